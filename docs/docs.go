@@ -4,7 +4,7 @@
 //
 //		Schemes: http
 //		Host: localhost:8080
-//		BasePath: /api/user
+//		BasePath: /api
 //		Version: 1.0.0
 //
 //		Consumes:
@@ -30,27 +30,124 @@ import (
 	"github.com/leonf08/gophermart.git/internal/models"
 )
 
-// swagger:route POST /register auth userSignUp
+// swagger:route POST /user/register auth userSignUp
 // Register a new user.
 // consumes:
 // - application/json
 // responses:
-//   200: noContentResponse
-//   400: errorResponse
+//   200: tokenResponse
+//   400: policyErrorResponse
 //   409: errorResponse
 //   500: errorResponse
 
-// swagger:route POST /login auth userLogIn
+// swagger:route POST /user/login auth userLogIn
 // Log in a user.
+// Repeated failed attempts are delayed and locked out, the Retry-After header tells when to retry.
 // consumes:
 // - application/json
 // responses:
+//   200: tokenResponse
+//   400: errorResponse
+//   401: errorResponse
+//   429: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /user/token/refresh auth refreshToken
+// Exchange a refresh token for a new token pair.
+// consumes:
+// - application/json
+// responses:
+//   200: tokenResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /.well-known/jwks.json auth getJWKS
+// Get the public keys used to verify access tokens.
+// The key set is served at the root of the host, outside the /api base path: GET /.well-known/jwks.json.
+// Clients may cache it for 5 minutes.
+// produces:
+// - application/json
+// responses:
+//   200: jwksResponse
+
+// swagger:route POST /user/logout auth logOut
+// Log out the current session.
+// security:
+//   api_key:
+// responses:
 //   200: noContentResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /user/password auth changePassword
+// Change the user password. All issued tokens are revoked.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   200: noContentResponse
+//   400: policyErrorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   429: errorResponse
+//   500: errorResponse
+
+// swagger:route DELETE /user auth deleteUser
+// Delete the user account. The user is anonymised, orders and withdrawals are kept.
+// The remaining balance is forfeited or the deletion is rejected, depending on the server configuration.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   200: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   409: errorResponse
+//   429: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /user/tokens auth issueMachineToken
+// Issue a machine token, a long-lived token with a subset of the user scopes for a service like an analytics job.
+// The scopes are orders:read, orders:write, balance:read, balance:write and webhooks.
+// The ttl is in seconds, the maximum lifetime if omitted. The token is returned only in this response.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   201: machineTokenResponse
 //   400: errorResponse
 //   401: errorResponse
+//   403: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/tokens auth getMachineTokens
+// Get machine tokens, newest first.
+// security:
+//   api_key:
+// responses:
+//   200: getMachineTokensResponse
+//   204: noContentResponse
+//   401: errorResponse
+//   403: errorResponse
 //   500: errorResponse
 
-// swagger:route POST /orders orders uploadOrder
+// swagger:route DELETE /user/tokens/{id} auth revokeMachineToken
+// Revoke a machine token.
+// security:
+//   api_key:
+// responses:
+//   204: noContentResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /user/orders orders uploadOrder
 // Upload an order.
 // consumes:
 // - text/plain
@@ -65,17 +162,59 @@ import (
 //   422: errorResponse
 //   500: errorResponse
 
-// swagger:route GET /orders orders getOrders
+// swagger:route POST /user/orders/batch orders uploadOrderBatch
+// Upload a batch of orders.
+// The batch is a JSON array of order numbers or a newline-delimited list, at most 1000 orders.
+// The orders are created in one transaction, the result of every order is
+// accepted, duplicate-own, conflict or invalid.
+// consumes:
+// - application/json
+// - text/plain
+// security:
+//   api_key:
+// responses:
+//   200: uploadOrderBatchResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/orders orders getOrders
 // Get orders.
 // security:
 //   api_key:
 // responses:
 //   200: getOrdersResponse
 //   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/orders/events orders getOrderEvents
+// Stream order status and balance changes as Server-Sent Events.
+// Every event has an id, a type, order or balance, and a JSON data with the Order or the UserAccount.
+// A reconnecting client gets the recent events after the one in the Last-Event-ID header.
+// produces:
+//   - text/event-stream
+// security:
+//   api_key:
+// responses:
+//   200: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/orders/{number} orders getOrder
+// Get an order with its status history.
+// security:
+//   api_key:
+// responses:
+//   200: getOrderResponse
+//   400: errorResponse
 //   401: errorResponse
+//   404: errorResponse
 //   500: errorResponse
 
-// swagger:route GET /balance balance getUserBalance
+// swagger:route GET /user/balance balance getUserBalance
 // Get user balance.
 // security:
 //   api_key:
@@ -84,7 +223,7 @@ import (
 //   401: errorResponse
 //   500: errorResponse
 
-// swagger:route POST /balance/withdraw balance withdraw
+// swagger:route POST /user/balance/withdraw balance withdraw
 // Withdraw money from the user balance.
 // consumes:
 // - application/json
@@ -95,17 +234,247 @@ import (
 //   400: errorResponse
 //   401: errorResponse
 //   402: errorResponse
+//   409: errorResponse
 //   422: errorResponse
 //   500: errorResponse
 
-// swagger:route GET /withdrawals balance getWithdrawals
+// swagger:route GET /user/withdrawals balance getWithdrawals
 // Get withdrawals.
 // security:
 //   api_key:
 // responses:
 //   200: getOrdersResponse
 //   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/ledger balance getLedger
+// Get the history of point movements.
+// security:
+//   api_key:
+// responses:
+//   200: getLedgerResponse
+//   204: noContentResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /user/webhooks webhooks createWebhook
+// Register a webhook.
+// The events are order.processed, order.invalid, withdrawal.created and withdrawal.reversed.
+// Every request is signed, the X-Gophermart-Signature header is t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">
+// keyed with the webhook secret. The secret is returned only in this response.
+// The url must not resolve to a loopback, private, link-local, multicast, shared (100.64.0.0/10) or unspecified address, redirects are not followed.
+// security:
+//   api_key:
+// responses:
+//   201: webhookResponse
+//   400: errorResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/webhooks webhooks getWebhooks
+// Get webhooks.
+// security:
+//   api_key:
+// responses:
+//   200: getWebhooksResponse
+//   204: noContentResponse
+//   401: errorResponse
+//   500: errorResponse
+
+// swagger:route DELETE /user/webhooks/{id} webhooks deleteWebhook
+// Delete a webhook with its delivery log.
+// security:
+//   api_key:
+// responses:
+//   204: noContentResponse
+//   401: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /user/webhooks/{id}/deliveries webhooks getWebhookDeliveries
+// Get the latest deliveries of a webhook, newest first.
+// security:
+//   api_key:
+// responses:
+//   200: getWebhookDeliveriesResponse
+//   204: noContentResponse
+//   401: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/users admin searchUsers
+// Search users by login. Requires the admin role and scope.
+// security:
+//   api_key:
+// responses:
+//   200: getAdminUsersResponse
+//   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/users/{id} admin getAdminUser
+// Get a user with the balance.
+// security:
+//   api_key:
+// responses:
+//   200: adminUserResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/users/{id}/orders admin getAdminUserOrders
+// Get orders of a user.
+// security:
+//   api_key:
+// responses:
+//   200: getOrdersResponse
+//   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/users/{id}/withdrawals admin getAdminUserWithdrawals
+// Get withdrawals of a user.
+// security:
+//   api_key:
+// responses:
+//   200: getWithdrawalsResponse
+//   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /admin/users/{id}/adjustments admin adjustBalance
+// Adjust the current balance of a user. A positive amount credits the user, a negative amount debits the user.
+// The reason is required and recorded in the audit log.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   200: getBalanceResponse
+//   400: errorResponse
+//   401: errorResponse
+//   402: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /admin/users/{id}/withdrawals/{order}/reversal admin reverseWithdrawal
+// Reverse a pending withdrawal of a user and credit the sum back.
+// The reason is required and recorded in the audit log.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   200: withdrawalResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   409: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/orders/{number} admin getAdminOrder
+// Get any order with its status history.
+// security:
+//   api_key:
+// responses:
+//   200: getOrderResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /admin/orders/{number}/recheck admin recheckOrder
+// Send an order to the accrual system again. The order must not be in a final status.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   202: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   409: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/audit admin getAuditLog
+// Get the audit log of administrator actions, newest first.
+// security:
+//   api_key:
+// responses:
+//   200: getAuditLogResponse
+//   204: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /admin/api-keys admin issueAPIKey
+// Issue an API key for a merchant backend.
+// The ttl is in seconds, the key does not expire if omitted. The key is returned only in this response.
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   201: apiKeyResponse
+//   400: errorResponse
+//   401: errorResponse
+//   403: errorResponse
+//   500: errorResponse
+
+// swagger:route GET /admin/api-keys admin getAPIKeys
+// Get API keys of merchants.
+// security:
+//   api_key:
+// responses:
+//   200: getAPIKeysResponse
+//   204: noContentResponse
 //   401: errorResponse
+//   403: errorResponse
+//   500: errorResponse
+
+// swagger:route DELETE /admin/api-keys/{id} admin revokeAPIKey
+// Revoke an API key.
+// security:
+//   api_key:
+// responses:
+//   204: noContentResponse
+//   401: errorResponse
+//   403: errorResponse
+//   404: errorResponse
+//   500: errorResponse
+
+// swagger:route POST /merchant/orders merchant uploadMerchantOrder
+// Upload an order on behalf of a user.
+// The request is authenticated with an API key sent as "Authorization: ApiKey <key>".
+// consumes:
+// - application/json
+// security:
+//   api_key:
+// responses:
+//   200: noContentResponse
+//   202: noContentResponse
+//   400: errorResponse
+//   401: errorResponse
+//   404: errorResponse
+//   409: errorResponse
+//   422: errorResponse
 //   500: errorResponse
 
 // swagger:parameters userSignUp userLogIn
@@ -114,14 +483,67 @@ type userSignUpRequest struct {
 	Body *models.User
 }
 
+// swagger:parameters refreshToken
+type refreshTokenRequest struct {
+	// in: body
+	Body *models.RefreshRequest
+}
+
+// swagger:parameters changePassword
+type changePasswordRequest struct {
+	// in: body
+	Body *models.PasswordChangeRequest
+}
+
+// swagger:parameters deleteUser
+type deleteUserRequest struct {
+	// in: body
+	Body *models.AccountDeletionRequest
+}
+
+// swagger:parameters issueMachineToken
+type issueMachineTokenRequest struct {
+	// in: body
+	Body *models.MachineTokenRequest
+}
+
+// swagger:parameters revokeMachineToken
+type machineTokenIDRequest struct {
+	// in: path
+	// required: true
+	ID string `json:"id"`
+}
+
 // swagger:parameters uploadOrder
 type uploadOrderRequest struct {
 	// in: body
 	Body string
 }
 
+// swagger:parameters uploadOrderBatch
+type uploadOrderBatchRequest struct {
+	// in: body
+	Body []string
+}
+
+// swagger:parameters createWebhook
+type createWebhookRequest struct {
+	// in: body
+	Body *models.WebhookRequest
+}
+
+// swagger:parameters deleteWebhook getWebhookDeliveries
+type webhookIDRequest struct {
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+}
+
 // swagger:parameters withdraw
 type withdrawRequest struct {
+	// Repeated requests with the same key return the original result.
+	// in: header
+	IdempotencyKey string `json:"Idempotency-Key"`
 	// in: body
 	Body struct {
 		Order string  `json:"order"`
@@ -129,17 +551,173 @@ type withdrawRequest struct {
 	}
 }
 
+// swagger:parameters getOrders getWithdrawals getAdminUserOrders getAdminUserWithdrawals
+type listRequest struct {
+	// Page size, at most 1000, 100 if only a cursor is given. Without both all items are returned.
+	// in: query
+	Limit int `json:"limit"`
+	// X-Next-Cursor of the previous page.
+	// in: query
+	Cursor string `json:"cursor"`
+	// Comma separated statuses to keep.
+	// in: query
+	Status string `json:"status"`
+	// Keep items from this RFC 3339 time, inclusive.
+	// in: query
+	From string `json:"from"`
+	// Keep items up to this RFC 3339 time, exclusive.
+	// in: query
+	To string `json:"to"`
+}
+
+// swagger:parameters getOrderEvents
+type getOrderEventsRequest struct {
+	// Id of the last received event to resume the stream after.
+	// in: header
+	LastEventID string `json:"Last-Event-ID"`
+}
+
+// swagger:parameters getOrder
+type getOrderRequest struct {
+	// in: path
+	// required: true
+	Number string `json:"number"`
+}
+
+// swagger:parameters searchUsers
+type searchUsersRequest struct {
+	// Part of the login to search for.
+	// in: query
+	Login string `json:"login"`
+	// Maximum number of users.
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// swagger:parameters getAdminUser getAdminUserOrders getAdminUserWithdrawals
+type adminUserIDRequest struct {
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+}
+
+// swagger:parameters adjustBalance
+type adjustBalanceRequest struct {
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+	// in: body
+	Body *models.BalanceAdjustment
+}
+
+// swagger:parameters reverseWithdrawal
+type reverseWithdrawalRequest struct {
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+	// in: path
+	// required: true
+	Order string `json:"order"`
+	// in: body
+	Body struct {
+		Reason string `json:"reason"`
+	}
+}
+
+// swagger:parameters getAdminOrder
+type getAdminOrderRequest struct {
+	// in: path
+	// required: true
+	Number string `json:"number"`
+}
+
+// swagger:parameters recheckOrder
+type recheckOrderRequest struct {
+	// in: path
+	// required: true
+	Number string `json:"number"`
+	// The body is optional.
+	// in: body
+	Body *models.RecheckRequest
+}
+
+// swagger:parameters getAuditLog
+type getAuditLogRequest struct {
+	// Keep only the actions on this user.
+	// in: query
+	UserID int64 `json:"user_id"`
+	// Maximum number of entries.
+	// in: query
+	Limit int `json:"limit"`
+}
+
+// swagger:parameters issueAPIKey
+type issueAPIKeyRequest struct {
+	// in: body
+	Body *models.APIKeyRequest
+}
+
+// swagger:parameters revokeAPIKey
+type apiKeyIDRequest struct {
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+}
+
+// swagger:parameters uploadMerchantOrder
+type uploadMerchantOrderRequest struct {
+	// in: body
+	Body *models.MerchantOrder
+}
+
 // noContentResponse is a response body when content is empty.
 // swagger:response noContentResponse
 type noContentResponse struct{}
 
+// tokenResponse is a response body for the userSignUp, userLogIn and refreshToken handlers when the input is valid.
+// swagger:response tokenResponse
+type tokenResponse struct {
+	// in: body
+	Body *models.TokenPair
+}
+
+// jwksResponse is a response body for the getJWKS handler.
+// swagger:response jwksResponse
+type jwksResponse struct {
+	// in: body
+	Body models.JWKS
+}
+
+// machineTokenResponse is a response body for the issueMachineToken handler when the input is valid.
+// swagger:response machineTokenResponse
+type machineTokenResponse struct {
+	// in: body
+	Body *models.MachineToken
+}
+
+// getMachineTokensResponse is a response body for the getMachineTokens handler when the input is valid.
+// swagger:response getMachineTokensResponse
+type getMachineTokensResponse struct {
+	// in: body
+	Body []models.MachineToken
+}
+
 // getOrdersResponse is a response body for the getOrders handler when the input is valid.
 // swagger:response getOrdersResponse
 type getOrdersResponse struct {
+	// Cursor of the next page, absent on the last page.
+	NextCursor string `json:"X-Next-Cursor"`
 	// in: body
 	Body []models.Order
 }
 
+// getOrderResponse is a response body for the getOrder handler when the input is valid.
+// swagger:response getOrderResponse
+type getOrderResponse struct {
+	// in: body
+	Body *models.OrderDetail
+}
+
 // getBalanceResponse is a response body for the getUserBalance handler when the input is valid.
 // swagger:response getBalanceResponse
 type getBalanceResponse struct {
@@ -150,10 +728,96 @@ type getBalanceResponse struct {
 // getWithdrawalsResponse is a response body for the getWithdrawals handler when the input is valid.
 // swagger:response getWithdrawalsResponse
 type getWithdrawalsResponse struct {
+	// Cursor of the next page, absent on the last page.
+	NextCursor string `json:"X-Next-Cursor"`
 	// in: body
 	Body []models.Withdrawal
 }
 
+// uploadOrderBatchResponse is a response body for the uploadOrderBatch handler when the input is valid.
+// swagger:response uploadOrderBatchResponse
+type uploadOrderBatchResponse struct {
+	// in: body
+	Body []models.OrderBatchResult
+}
+
+// withdrawalResponse is a response body for the reverseWithdrawal handler when the input is valid.
+// swagger:response withdrawalResponse
+type withdrawalResponse struct {
+	// in: body
+	Body *models.Withdrawal
+}
+
+// webhookResponse is a response body for the createWebhook handler when the input is valid.
+// swagger:response webhookResponse
+type webhookResponse struct {
+	// in: body
+	Body *models.Webhook
+}
+
+// getWebhooksResponse is a response body for the getWebhooks handler when the input is valid.
+// swagger:response getWebhooksResponse
+type getWebhooksResponse struct {
+	// in: body
+	Body []models.Webhook
+}
+
+// getWebhookDeliveriesResponse is a response body for the getWebhookDeliveries handler when the input is valid.
+// swagger:response getWebhookDeliveriesResponse
+type getWebhookDeliveriesResponse struct {
+	// in: body
+	Body []models.WebhookDelivery
+}
+
+// getLedgerResponse is a response body for the getLedger handler when the input is valid.
+// swagger:response getLedgerResponse
+type getLedgerResponse struct {
+	// in: body
+	Body []models.LedgerEntry
+}
+
+// getAdminUsersResponse is a response body for the searchUsers handler when the input is valid.
+// swagger:response getAdminUsersResponse
+type getAdminUsersResponse struct {
+	// in: body
+	Body []models.AdminUser
+}
+
+// adminUserResponse is a response body for the getAdminUser handler when the input is valid.
+// swagger:response adminUserResponse
+type adminUserResponse struct {
+	// in: body
+	Body *models.AdminUser
+}
+
+// getAuditLogResponse is a response body for the getAuditLog handler when the input is valid.
+// swagger:response getAuditLogResponse
+type getAuditLogResponse struct {
+	// in: body
+	Body []models.AuditEntry
+}
+
+// apiKeyResponse is a response body for the issueAPIKey handler when the input is valid.
+// swagger:response apiKeyResponse
+type apiKeyResponse struct {
+	// in: body
+	Body *models.APIKey
+}
+
+// getAPIKeysResponse is a response body for the getAPIKeys handler when the input is valid.
+// swagger:response getAPIKeysResponse
+type getAPIKeysResponse struct {
+	// in: body
+	Body []models.APIKey
+}
+
+// policyErrorResponse is a response body for the userSignUp handler when the credentials do not satisfy the policy.
+// swagger:response policyErrorResponse
+type policyErrorResponse struct {
+	// in: body
+	Body *models.PolicyErrorResponse
+}
+
 // errorResponse is a response body for the userSignUp handler when the input is invalid.
 // swagger:response errorResponse
 type errorResponse struct {
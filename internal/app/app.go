@@ -1,22 +1,53 @@
 package app
 
 import (
+	"context"
 	"github.com/leonf08/gophermart.git/internal/config"
 	"github.com/leonf08/gophermart.git/internal/controller/http"
 	"github.com/leonf08/gophermart.git/internal/controller/http/handlers"
 	"github.com/leonf08/gophermart.git/internal/database/postgres"
 	"github.com/leonf08/gophermart.git/internal/logger"
+	"github.com/leonf08/gophermart.git/internal/metrics"
+	"github.com/leonf08/gophermart.git/internal/models"
 	"github.com/leonf08/gophermart.git/internal/services"
 	"github.com/leonf08/gophermart.git/internal/services/repo"
+	"github.com/leonf08/gophermart.git/internal/tracing"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+)
+
+const (
+	// serviceName identifies the application in traces.
+	serviceName = "gophermart"
+	// tracingShutdownTimeout limits flushing of the remaining spans on shutdown.
+	tracingShutdownTimeout = 5 * time.Second
 )
 
 // Run runs the application.
 func Run(cfg *config.Config) {
 	log := logger.NewLogger()
 
+	// Tracing is set up first, so the database connection is traced too.
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Exporter:    cfg.TracingExporter,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Error("app - Run - tracing.Setup", "error", err)
+		return
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error("app - Run - shutdownTracing", "error", err)
+		}
+	}()
+
 	db, err := postgres.NewConnection(cfg.DatabaseAddress)
 	if err != nil {
 		log.Error("app - Run - postgres.NewConnection", "error", err)
@@ -24,29 +55,148 @@ func Run(cfg *config.Config) {
 	}
 	defer db.Close()
 
+	if err = metrics.RegisterDB(db.DB); err != nil {
+		log.Error("app - Run - metrics.RegisterDB", "error", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	repository := repo.NewRepository(db)
-	auth := services.NewAuthenticator(cfg.JWTSecret)
-	userService := services.NewUserManager(repository, auth)
-	orderService := services.NewOrderManager(repository, services.NewAccrual(cfg.AccrualAddress, repository, log))
+	mismatched, err := repository.ReconcileLedger(ctx)
+	if err != nil {
+		log.Error("app - Run - repository.ReconcileLedger", "error", err)
+	} else if len(mismatched) > 0 {
+		log.Error("app - Run - repository.ReconcileLedger", "error", "balances do not match the ledger", "users", mismatched)
+	}
+
+	if len(cfg.AdminLogins) > 0 {
+		granted, err := repository.SetUserRole(ctx, cfg.AdminLogins, models.RoleAdmin)
+		if err != nil {
+			log.Error("app - Run - repository.SetUserRole", "error", err)
+		} else if granted > 0 {
+			log.Info("app - Run - repository.SetUserRole", "admins", granted)
+		}
+	}
+
+	keys, err := services.NewKeySet(ctx, services.KeySetConfig{
+		Dir:              cfg.JWTKeysDir,
+		Algorithm:        cfg.JWTAlgorithm,
+		RotationInterval: cfg.JWTKeyRotationInterval,
+		GracePeriod:      cfg.JWTKeyGracePeriod,
+	}, log)
+	if err != nil {
+		log.Error("app - Run - services.NewKeySet", "error", err)
+		return
+	}
+	if cfg.JWTKeysDir == "" {
+		log.Info("app - Run - services.NewKeySet", "message", "JWT_KEYS_DIR is not set, signing keys are kept in memory only")
+	}
+
+	policy, err := services.NewCredentialsPolicy(services.CredentialsPolicyConfig{
+		LoginMinLength:    cfg.LoginMinLength,
+		LoginMaxLength:    cfg.LoginMaxLength,
+		LoginCharset:      cfg.LoginCharset,
+		PasswordMinLength: cfg.PasswordMinLength,
+		PasswordMaxLength: cfg.PasswordMaxLength,
+		DenylistFile:      cfg.PasswordDenylistFile,
+	})
+	if err != nil {
+		log.Error("app - Run - services.NewCredentialsPolicy", "error", err)
+		return
+	}
+
+	var attempts services.LoginAttemptRepo = repository
+	if cfg.LoginAttemptsStorage == "memory" {
+		attempts = repo.NewMemoryLoginAttempts()
+	}
+	guard := services.NewLoginGuard(attempts, services.LoginGuardConfig{
+		Window:           cfg.LoginFailureWindow,
+		DelayBase:        cfg.LoginDelayBase,
+		DelayMax:         cfg.LoginDelayMax,
+		LoginMaxFailures: cfg.LoginMaxFailures,
+		IPMaxFailures:    cfg.LoginIPMaxFailures,
+		LockoutDuration:  cfg.LoginLockoutDuration,
+	})
+
+	auth := services.NewAuthenticator(keys, cfg.AccessTokenTTL, cfg.BcryptCost)
+	events := services.NewEventHub(ctx, services.EventHubConfig{
+		Backlog:   cfg.EventsBacklog,
+		Retention: cfg.EventsRetention,
+	})
+	accrual := services.NewAccrual(ctx, services.AccrualConfig{
+		Address:         cfg.AccrualAddress,
+		Workers:         cfg.AccrualWorkers,
+		RateLimit:       cfg.AccrualRate,
+		PollInterval:    cfg.AccrualPollInterval,
+		MaxPollInterval: cfg.AccrualMaxPollInterval,
+		MaxAttempts:     cfg.AccrualMaxAttempts,
+	}, repository, events, log)
+	if err = metrics.RegisterAccrualQueue(accrual.QueueDepth); err != nil {
+		log.Error("app - Run - metrics.RegisterAccrualQueue", "error", err)
+	}
+	webhooks := services.NewWebhookDispatcher(ctx, services.WebhookDispatcherConfig{
+		PollInterval:     cfg.WebhookPollInterval,
+		RetryInterval:    cfg.WebhookRetryInterval,
+		MaxRetryInterval: cfg.WebhookMaxRetryInterval,
+		MaxAttempts:      cfg.WebhookMaxAttempts,
+		Timeout:          cfg.WebhookTimeout,
+		BatchSize:        cfg.WebhookBatchSize,
+	}, repository, log)
+	withdrawals := services.NewWithdrawalSettler(ctx, services.WithdrawalSettlerConfig{
+		ReversalWindow: cfg.WithdrawalReversalWindow,
+		PollInterval:   cfg.WithdrawalSettleInterval,
+	}, repository, log)
+	userService := services.NewUserManager(repository, auth, policy, guard, services.BalancePolicy(cfg.AccountBalancePolicy))
+	orderService := services.NewOrderManager(repository, accrual)
+	sessionService := services.NewSessionManager(repository, auth, cfg.RefreshTokenTTL)
+	webhookService := services.NewWebhookManager(repository)
+	tokenService := services.NewMachineTokenManager(repository, cfg.MachineTokenMaxTTL)
+	merchantService := services.NewMerchantManager(repository, orderService)
+	adminService := services.NewAdminManager(repository, accrual, events)
 
-	r := handlers.NewRouter(userService, orderService, auth, log)
+	r := handlers.NewRouter(userService, orderService, sessionService, webhookService, tokenService, merchantService, adminService, auth, events, log)
 
 	server := http.NewServer(r, cfg.ServerAddress)
 	log.Info("app - Run - server.ListenAndServe", "address", cfg.ServerAddress)
 
+	// The metrics are served apart from the public API, a nil channel never reports an error.
+	var metricsServer *http.Server
+	var metricsErr <-chan error
+	if cfg.MetricsAddress != "" {
+		metricsServer = http.NewServer(handlers.NewMetricsRouter(), cfg.MetricsAddress)
+		metricsErr = metricsServer.Err()
+		log.Info("app - Run - metricsServer.ListenAndServe", "address", cfg.MetricsAddress)
+	}
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
 	select {
 	case err := <-server.Err():
 		log.Error("app - Run - server.Err", "error", err)
+	case err := <-metricsErr:
+		log.Error("app - Run - metricsServer.Err", "error", err)
 	case sig := <-interrupt:
 		log.Info("app - Run - interrupt", "signal", sig.String())
 	}
 
 	log.Info("app - Run - shutdown")
+	// Event streams never end by themselves, they are finished before the server waits for them.
+	events.Close()
 	err = server.Shutdown()
 	if err != nil {
 		log.Error("app - Run - server.Shutdown", "error", err)
 	}
+
+	if metricsServer != nil {
+		if err = metricsServer.Shutdown(); err != nil {
+			log.Error("app - Run - metricsServer.Shutdown", "error", err)
+		}
+	}
+
+	cancel()
+	accrual.Wait()
+	webhooks.Wait()
+	withdrawals.Wait()
 }
@@ -3,16 +3,71 @@ package config
 import (
 	"flag"
 	"github.com/ilyakaznacheev/cleanenv"
+	"golang.org/x/crypto/bcrypt"
 	"os"
+	"time"
 )
 
-const key = "smAc+l7mbCyqh79FUhsn0O9rSbDOmxcTGqPKJuJJ7ow="
-
 type Config struct {
 	ServerAddress   string `env:"RUN_ADDRESS"`
 	DatabaseAddress string `env:"DATABASE_URI"`
 	AccrualAddress  string `env:"ACCRUAL_SYSTEM_ADDRESS"`
-	JWTSecret       string `env:"JWT_SECRET"`
+	AccrualWorkers  int    `env:"ACCRUAL_WORKERS"`
+	AccrualRate     int    `env:"ACCRUAL_RATE_LIMIT"`
+
+	AccrualPollInterval    time.Duration `env:"ACCRUAL_POLL_INTERVAL" env-default:"1s"`
+	AccrualMaxPollInterval time.Duration `env:"ACCRUAL_MAX_POLL_INTERVAL" env-default:"10m"`
+	AccrualMaxAttempts     int           `env:"ACCRUAL_MAX_ATTEMPTS" env-default:"100"`
+
+	AccessTokenTTL     time.Duration `env:"ACCESS_TOKEN_TTL" env-default:"15m"`
+	RefreshTokenTTL    time.Duration `env:"REFRESH_TOKEN_TTL" env-default:"720h"`
+	MachineTokenMaxTTL time.Duration `env:"MACHINE_TOKEN_MAX_TTL" env-default:"2160h"`
+
+	JWTKeysDir             string        `env:"JWT_KEYS_DIR"`
+	JWTAlgorithm           string        `env:"JWT_ALGORITHM" env-default:"EdDSA"`
+	JWTKeyRotationInterval time.Duration `env:"JWT_KEY_ROTATION_INTERVAL" env-default:"24h"`
+	JWTKeyGracePeriod      time.Duration `env:"JWT_KEY_GRACE_PERIOD" env-default:"1h"`
+
+	LoginMinLength       int    `env:"LOGIN_MIN_LENGTH" env-default:"3"`
+	LoginMaxLength       int    `env:"LOGIN_MAX_LENGTH" env-default:"64"`
+	LoginCharset         string `env:"LOGIN_CHARSET" env-default:"a-zA-Z0-9._@-"`
+	PasswordMinLength    int    `env:"PASSWORD_MIN_LENGTH" env-default:"8"`
+	PasswordMaxLength    int    `env:"PASSWORD_MAX_LENGTH" env-default:"72"`
+	PasswordDenylistFile string `env:"PASSWORD_DENYLIST_FILE"`
+	BcryptCost           int    `env:"BCRYPT_COST" env-default:"10"`
+
+	LoginAttemptsStorage string        `env:"LOGIN_ATTEMPTS_STORAGE" env-default:"postgres"`
+	LoginFailureWindow   time.Duration `env:"LOGIN_FAILURE_WINDOW" env-default:"15m"`
+	LoginDelayBase       time.Duration `env:"LOGIN_DELAY_BASE" env-default:"1s"`
+	LoginDelayMax        time.Duration `env:"LOGIN_DELAY_MAX" env-default:"30s"`
+	LoginMaxFailures     int           `env:"LOGIN_MAX_FAILURES" env-default:"5"`
+	LoginIPMaxFailures   int           `env:"LOGIN_IP_MAX_FAILURES" env-default:"50"`
+	LoginLockoutDuration time.Duration `env:"LOGIN_LOCKOUT_DURATION" env-default:"15m"`
+
+	AccountBalancePolicy string `env:"ACCOUNT_BALANCE_POLICY" env-default:"reject"`
+
+	// AdminLogins are the logins of the users granted the admin role on start.
+	AdminLogins []string `env:"ADMIN_LOGINS" env-separator:","`
+
+	EventsBacklog   int           `env:"EVENTS_BACKLOG" env-default:"100"`
+	EventsRetention time.Duration `env:"EVENTS_RETENTION" env-default:"1h"`
+
+	WebhookPollInterval     time.Duration `env:"WEBHOOK_POLL_INTERVAL" env-default:"1s"`
+	WebhookRetryInterval    time.Duration `env:"WEBHOOK_RETRY_INTERVAL" env-default:"10s"`
+	WebhookMaxRetryInterval time.Duration `env:"WEBHOOK_MAX_RETRY_INTERVAL" env-default:"1h"`
+	WebhookMaxAttempts      int           `env:"WEBHOOK_MAX_ATTEMPTS" env-default:"10"`
+	WebhookTimeout          time.Duration `env:"WEBHOOK_TIMEOUT" env-default:"10s"`
+	WebhookBatchSize        int           `env:"WEBHOOK_BATCH_SIZE" env-default:"100"`
+
+	WithdrawalReversalWindow time.Duration `env:"WITHDRAWAL_REVERSAL_WINDOW" env-default:"24h"`
+	WithdrawalSettleInterval time.Duration `env:"WITHDRAWAL_SETTLE_INTERVAL" env-default:"1m"`
+
+	// MetricsAddress is the address of the server of the /metrics endpoint, metrics are not served if empty.
+	// By default it listens on all interfaces, so Prometheus can scrape it from another host.
+	MetricsAddress string `env:"METRICS_ADDRESS" env-default:":9090"`
+
+	TracingExporter    string  `env:"TRACING_EXPORTER" env-default:"none"`
+	TracingSampleRatio float64 `env:"TRACING_SAMPLE_RATIO" env-default:"1"`
 }
 
 func MustLoadConfig() *Config {
@@ -20,13 +75,16 @@ func MustLoadConfig() *Config {
 	serverAddress := f.String("a", "localhost:8080", "host address")
 	dsn := f.String("d", "", "database uri")
 	accrualAddress := f.String("r", "", "accrual system address")
+	accrualWorkers := f.Int("w", 4, "number of accrual workers")
+	accrualRate := f.Int("l", 0, "accrual system requests per second limit, 0 means no limit")
 	_ = f.Parse(os.Args[1:])
 
 	cfg := &Config{
 		ServerAddress:   *serverAddress,
 		DatabaseAddress: *dsn,
 		AccrualAddress:  *accrualAddress,
-		JWTSecret:       key,
+		AccrualWorkers:  *accrualWorkers,
+		AccrualRate:     *accrualRate,
 	}
 
 	if err := cleanenv.ReadEnv(cfg); err != nil {
@@ -37,5 +95,53 @@ func MustLoadConfig() *Config {
 		panic("database address must be not empty")
 	}
 
+	if cfg.AccrualWorkers < 1 {
+		panic("number of accrual workers must be positive")
+	}
+
+	if cfg.JWTKeyGracePeriod < cfg.AccessTokenTTL {
+		panic("jwt key grace period must be not shorter than access token ttl")
+	}
+
+	if cfg.MachineTokenMaxTTL <= 0 {
+		panic("machine token max ttl must be positive")
+	}
+
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		panic("bcrypt cost is out of range")
+	}
+
+	if cfg.LoginAttemptsStorage != "memory" && cfg.LoginAttemptsStorage != "postgres" {
+		panic("login attempts storage must be memory or postgres")
+	}
+
+	if cfg.AccountBalancePolicy != "reject" && cfg.AccountBalancePolicy != "forfeit" {
+		panic("account balance policy must be reject or forfeit")
+	}
+
+	if cfg.EventsBacklog < 0 {
+		panic("events backlog must be not negative")
+	}
+
+	if cfg.WebhookPollInterval <= 0 || cfg.WebhookBatchSize < 1 {
+		panic("webhook poll interval and batch size must be positive")
+	}
+
+	if cfg.WithdrawalReversalWindow < 0 || cfg.WithdrawalSettleInterval <= 0 {
+		panic("withdrawal reversal window must be not negative and settle interval must be positive")
+	}
+
+	if cfg.MetricsAddress != "" && cfg.MetricsAddress == cfg.ServerAddress {
+		panic("metrics address must differ from the server address")
+	}
+
+	if cfg.TracingExporter != "none" && cfg.TracingExporter != "stdout" && cfg.TracingExporter != "otlp" {
+		panic("tracing exporter must be none, stdout or otlp")
+	}
+
+	if cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1 {
+		panic("tracing sample ratio must be between 0 and 1")
+	}
+
 	return cfg
 }
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/leonf08/gophermart.git/internal/controller/http/handlers/middleware"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func (h *handler) adminRoutes(r chi.Router) {
+	r.Use(middleware.Auth(h.sessions), middleware.RequireRole(models.RoleAdmin), middleware.RequireScope(models.ScopeAdmin))
+	r.Get("/users", h.searchUsers)
+	r.Get("/users/{id}", h.getAdminUser)
+	r.Get("/users/{id}/orders", h.getAdminUserOrders)
+	r.Get("/users/{id}/withdrawals", h.getAdminUserWithdrawals)
+	r.Post("/users/{id}/adjustments", h.adjustBalance)
+	r.Post("/users/{id}/withdrawals/{order}/reversal", h.reverseWithdrawal)
+	r.Get("/orders/{number}", h.getAdminOrder)
+	r.Post("/orders/{number}/recheck", h.recheckOrder)
+	r.Get("/audit", h.getAuditLog)
+	r.Post("/api-keys", h.issueAPIKey)
+	r.Get("/api-keys", h.getAPIKeys)
+	r.Delete("/api-keys/{id}", h.revokeAPIKey)
+}
+
+func (h *handler) searchUsers(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.admin.SearchUsers(r.Context(), r.URL.Query().Get("login"), limit)
+	if err != nil {
+		entry.Error(err.Error())
+		writeListError(w, err)
+		return
+	}
+
+	if len(users) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, users)
+}
+
+func (h *handler) getAdminUser(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	user, err := h.admin.GetUser(r.Context(), userID)
+	if err != nil {
+		entry.Error(err.Error())
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, user)
+}
+
+func (h *handler) getAdminUserOrders(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		logEntry(h.log, r).Error(err.Error())
+		http.Error(w, services.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.listOrders(w, r, userID)
+}
+
+func (h *handler) getAdminUserWithdrawals(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		logEntry(h.log, r).Error(err.Error())
+		http.Error(w, services.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	h.listWithdrawals(w, r, userID)
+}
+
+func (h *handler) adjustBalance(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	adminID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	adj := &models.BalanceAdjustment{}
+	if err = json.NewDecoder(r.Body).Decode(adj); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adj.AdminID = adminID
+	adj.UserID = userID
+	account, err := h.admin.AdjustBalance(r.Context(), adj)
+	if err != nil {
+		entry.Error(err.Error())
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, account)
+}
+
+func (h *handler) reverseWithdrawal(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	adminID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrUserNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	rev := &models.WithdrawalReversal{}
+	if err = json.NewDecoder(r.Body).Decode(rev); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rev.AdminID = adminID
+	rev.UserID = userID
+	rev.OrderNumber = chi.URLParam(r, "order")
+	withdrawal, err := h.admin.ReverseWithdrawal(r.Context(), rev)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidOrderNumber), errors.Is(err, services.ErrReversalReason):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrWithdrawalNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrWithdrawalNotReversible):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, withdrawal)
+}
+
+func (h *handler) getAdminOrder(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	order, err := h.admin.GetOrder(r.Context(), chi.URLParam(r, "number"))
+	if err != nil {
+		entry.Error(err.Error())
+		writeAdminError(w, err)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, order)
+}
+
+func (h *handler) recheckOrder(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	adminID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	// The request body with the reason is optional.
+	req := &models.RecheckRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.admin.RecheckOrder(r.Context(), adminID, chi.URLParam(r, "number"), req.Reason)
+	if err != nil {
+		entry.Error(err.Error())
+		writeAdminError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *handler) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var userID int64
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		if userID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			entry.Error(err.Error())
+			http.Error(w, "invalid user id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.admin.GetAuditLog(r.Context(), userID, limit)
+	if err != nil {
+		entry.Error(err.Error())
+		writeListError(w, err)
+		return
+	}
+
+	if len(entries) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, entries)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidOrderNumber),
+		errors.Is(err, services.ErrInvalidAdjustment),
+		errors.Is(err, services.ErrAdjustmentReason):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrOrderNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, services.ErrOrderFinal):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, services.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
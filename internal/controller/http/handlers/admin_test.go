@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_handler_adminRoutes(t *testing.T) {
+	sessions := mocks.NewSessions(t)
+	admin := mocks.NewAdmin(t)
+	merchants := mocks.NewMerchants(t)
+
+	h := &handler{
+		sessions:  sessions,
+		admin:     admin,
+		merchants: merchants,
+		log:       &mockLogger{},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/admin", h.adminRoutes)
+
+	sessions.
+		On("Authenticate", mock.Anything, "user").
+		Return(&models.CustomJWTClaims{UserID: 2}, nil)
+	sessions.
+		On("Authenticate", mock.Anything, "admin").
+		Return(&models.CustomJWTClaims{UserID: 1, Role: models.RoleAdmin}, nil)
+
+	admin.
+		On("SearchUsers", mock.Anything, "goph", 0).
+		Return([]*models.AdminUser{{UserID: 2, Login: "gopher", Role: models.RoleUser}}, nil)
+	admin.
+		On("AdjustBalance", mock.Anything, &models.BalanceAdjustment{AdminID: 1, UserID: 2, Amount: 10050, Reason: "compensation"}).
+		Return(&models.UserAccount{UserID: 2, Current: 60050}, nil)
+	admin.
+		On("AdjustBalance", mock.Anything, mock.MatchedBy(func(adj *models.BalanceAdjustment) bool { return adj.Amount == 0 })).
+		Return(nil, services.ErrInvalidAdjustment)
+	admin.
+		On("RecheckOrder", mock.Anything, int64(1), "79927398713", "stuck").
+		Return(nil)
+	admin.
+		On("RecheckOrder", mock.Anything, int64(1), "4561261212345467", "").
+		Return(services.ErrOrderFinal)
+	admin.
+		On("ReverseWithdrawal", mock.Anything, &models.WithdrawalReversal{
+			AdminID: 1, UserID: 2, OrderNumber: "79927398713", Reason: "order cancelled",
+		}).
+		Return(&models.Withdrawal{UserID: 2, OrderNumber: "79927398713", Sum: 10000,
+			Status: models.WithdrawalStatusReversed, ReversedBy: "admin:1", ReversalReason: "order cancelled"}, nil)
+	admin.
+		On("ReverseWithdrawal", mock.Anything, mock.MatchedBy(func(rev *models.WithdrawalReversal) bool { return rev.Reason == "" })).
+		Return(nil, services.ErrReversalReason)
+
+	merchants.
+		On("IssueAPIKey", mock.Anything, &models.APIKeyRequest{Merchant: "shop"}).
+		Return(&models.APIKey{KeyID: 1, Merchant: "shop", Prefix: "0a1b2c3d4e5f", Key: "gmk_0a1b2c3d4e5f.secret"}, nil)
+	merchants.
+		On("RevokeAPIKey", mock.Anything, int64(2)).
+		Return(services.ErrAPIKeyNotFound)
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		target string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "1. no token",
+			method: http.MethodGet,
+			target: "/api/admin/users",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "2. not an admin",
+			token:  "user",
+			method: http.MethodGet,
+			target: "/api/admin/users",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "3. search users",
+			token:  "admin",
+			method: http.MethodGet,
+			target: "/api/admin/users?login=goph",
+			status: http.StatusOK,
+			want:   `[{"id":2,"login":"gopher","role":"user","current":0,"withdrawn":0}]`,
+		},
+		{
+			name:   "4. adjust balance",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/users/2/adjustments",
+			body:   `{"amount": 100.5, "reason": "compensation"}`,
+			status: http.StatusOK,
+			want:   `{"current":600.5,"withdrawn":0}`,
+		},
+		{
+			name:   "5. adjust balance, zero amount",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/users/2/adjustments",
+			body:   `{"amount": 0, "reason": "nothing"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "6. adjust balance, unknown user",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/users/abc/adjustments",
+			body:   `{"amount": 1, "reason": "compensation"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "7. recheck order",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/orders/79927398713/recheck",
+			body:   `{"reason": "stuck"}`,
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "8. recheck order in a final status, no reason",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/orders/4561261212345467/recheck",
+			status: http.StatusConflict,
+		},
+		{
+			name:   "9. reverse withdrawal",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/users/2/withdrawals/79927398713/reversal",
+			body:   `{"reason": "order cancelled"}`,
+			status: http.StatusOK,
+			want: `{"order":"79927398713","sum":100,"status":"REVERSED","processed_at":"0001-01-01T00:00:00Z",` +
+				`"reversed_by":"admin:1","reversal_reason":"order cancelled"}`,
+		},
+		{
+			name:   "10. reverse withdrawal, no reason",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/users/2/withdrawals/79927398713/reversal",
+			body:   `{}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "11. reverse withdrawal, not an admin",
+			token:  "user",
+			method: http.MethodPost,
+			target: "/api/admin/users/2/withdrawals/79927398713/reversal",
+			body:   `{"reason": "order cancelled"}`,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "12. issue api key",
+			token:  "admin",
+			method: http.MethodPost,
+			target: "/api/admin/api-keys",
+			body:   `{"merchant": "shop"}`,
+			status: http.StatusCreated,
+			want:   `{"id":1,"merchant":"shop","prefix":"0a1b2c3d4e5f","key":"gmk_0a1b2c3d4e5f.secret","created_at":"0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:   "13. revoke unknown api key",
+			token:  "admin",
+			method: http.MethodDelete,
+			target: "/api/admin/api-keys/2",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "14. issue api key, not an admin",
+			token:  "user",
+			method: http.MethodPost,
+			target: "/api/admin/api-keys",
+			body:   `{"merchant": "shop"}`,
+			status: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.want != "" {
+				assert.JSONEq(t, tt.want, resp.Body.String())
+			}
+		})
+	}
+}
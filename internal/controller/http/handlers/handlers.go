@@ -3,40 +3,109 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/leonf08/gophermart.git/internal/controller/http/handlers/middleware"
 	"github.com/leonf08/gophermart.git/internal/models"
 	"github.com/leonf08/gophermart.git/internal/services"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
-	"sort"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// jwksMaxAge is how long clients may cache the JWKS, in seconds.
+// It is much shorter than the key grace period, so clients see new keys in time.
+const jwksMaxAge = "300"
+
+// eventsHeartbeat is how often a comment is sent to an idle event stream,
+// so proxies do not close the connection.
+const eventsHeartbeat = 15 * time.Second
+
+// headerNextCursor carries the cursor of the next page of a listing.
+// It is not set on the last page.
+const headerNextCursor = "X-Next-Cursor"
+
 type handler struct {
-	users  services.Users
-	orders services.Orders
-	log    services.Logger
+	users     services.Users
+	orders    services.Orders
+	sessions  services.Sessions
+	webhooks  services.Webhooks
+	tokens    services.MachineTokens
+	merchants services.Merchants
+	admin     services.Admin
+	auth      services.Authenticator
+	events    services.Events
+	log       services.Logger
 }
 
-func newHandler(r chi.Router, users services.Users, orders services.Orders, auth services.Authenticator, log services.Logger) {
-	h := &handler{
-		users:  users,
-		orders: orders,
-		log:    log,
+func newHandler(users services.Users, orders services.Orders, sessions services.Sessions, webhooks services.Webhooks, tokens services.MachineTokens, merchants services.Merchants, admin services.Admin, auth services.Authenticator, events services.Events, log services.Logger) *handler {
+	return &handler{
+		users:     users,
+		orders:    orders,
+		sessions:  sessions,
+		webhooks:  webhooks,
+		tokens:    tokens,
+		merchants: merchants,
+		admin:     admin,
+		auth:      auth,
+		events:    events,
+		log:       log,
 	}
+}
 
+func (h *handler) routes(r chi.Router) {
 	r.Post("/register", h.userSignUp)
 	r.Post("/login", h.userLogIn)
+	r.Post("/token/refresh", h.refreshToken)
 	r.Group(func(r chi.Router) {
-		r.Use(middleware.Auth(auth))
-		r.Post("/orders", h.uploadOrder)
-		r.Get("/orders", h.getOrders)
-		r.Get("/balance", h.getUserBalance)
-		r.Post("/balance/withdraw", h.withdraw)
-		r.Get("/withdrawals", h.getWithdrawals)
+		r.Use(middleware.Auth(h.sessions))
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeAccount))
+			r.Post("/logout", h.logOut)
+			r.Post("/password", h.changePassword)
+			r.Delete("/", h.deleteUser)
+			r.Post("/tokens", h.issueMachineToken)
+			r.Get("/tokens", h.getMachineTokens)
+			r.Delete("/tokens/{id}", h.revokeMachineToken)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeOrdersWrite))
+			r.Post("/orders", h.uploadOrder)
+			r.Post("/orders/batch", h.uploadOrderBatch)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeOrdersRead))
+			r.Get("/orders", h.getOrders)
+			r.Get("/orders/events", h.getOrderEvents)
+			r.Get("/orders/{number}", h.getOrder)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeBalanceRead))
+			r.Get("/balance", h.getUserBalance)
+			r.Get("/withdrawals", h.getWithdrawals)
+			r.Get("/ledger", h.getLedger)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeBalanceWrite))
+			r.Post("/balance/withdraw", h.withdraw)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireScope(models.ScopeWebhooks))
+			r.Post("/webhooks", h.createWebhook)
+			r.Get("/webhooks", h.getWebhooks)
+			r.Delete("/webhooks/{id}", h.deleteWebhook)
+			r.Get("/webhooks/{id}/deliveries", h.getWebhookDeliveries)
+		})
 	})
 }
 
@@ -54,6 +123,15 @@ func (h *handler) userSignUp(w http.ResponseWriter, r *http.Request) {
 	err = h.users.RegisterUser(r.Context(), user)
 	if err != nil {
 		entry.Error(err.Error())
+		var policyErr *services.CredentialsPolicyError
+		if errors.As(err, &policyErr) {
+			writeJSON(w, entry, http.StatusBadRequest, &models.PolicyErrorResponse{
+				Error:      services.ErrCredentialsPolicy.Error(),
+				Violations: policyErr.Violations,
+			})
+			return
+		}
+
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgerrcode.IsIntegrityConstraintViolation(pgErr.Code) {
 			http.Error(w, err.Error(), http.StatusConflict)
@@ -64,16 +142,14 @@ func (h *handler) userSignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.users.GetToken(user)
+	tokens, err := h.sessions.CreateSession(r.Context(), user)
 	if err != nil {
 		entry.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("Authorization", "Bearer "+token)
-	w.WriteHeader(http.StatusOK)
+	writeTokens(w, entry, tokens)
 }
 
 func (h *handler) userLogIn(w http.ResponseWriter, r *http.Request) {
@@ -87,23 +163,228 @@ func (h *handler) userLogIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.users.LoginUser(r.Context(), user)
+	err = h.users.LoginUser(r.Context(), user, clientIP(r))
+	if err != nil {
+		entry.Error(err.Error())
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			http.Error(w, services.ErrTooManyLoginAttempts.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrInvalidLoginFormat):
+			http.Error(w, services.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	tokens, err := h.sessions.CreateSession(r.Context(), user)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeTokens(w, entry, tokens)
+}
+
+func (h *handler) refreshToken(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	req := &models.RefreshRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.sessions.RefreshSession(r.Context(), req.RefreshToken)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidRefreshToken), errors.Is(err, services.ErrRefreshTokenReused):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeTokens(w, entry, tokens)
+}
+
+func (h *handler) logOut(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	claims := r.Context().Value(middleware.KeyClaims{}).(*models.CustomJWTClaims)
+
+	err := h.sessions.RevokeSession(r.Context(), claims)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	req := &models.PasswordChangeRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.users.ChangePassword(r.Context(), userID, req, clientIP(r))
+	if err != nil {
+		entry.Error(err.Error())
+		h.writeCredentialsError(w, entry, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	req := &models.AccountDeletionRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.users.DeleteUser(r.Context(), userID, req, clientIP(r))
 	if err != nil {
 		entry.Error(err.Error())
+		if errors.Is(err, services.ErrAccountHasBalance) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		h.writeCredentialsError(w, entry, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeCredentialsError writes the response for an error
+// of an operation confirmed with the user password.
+func (h *handler) writeCredentialsError(w http.ResponseWriter, entry services.Logger, err error) {
+	var (
+		throttled *services.LoginThrottledError
+		policyErr *services.CredentialsPolicyError
+	)
+	switch {
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		http.Error(w, services.ErrTooManyLoginAttempts.Error(), http.StatusTooManyRequests)
+	case errors.As(err, &policyErr):
+		writeJSON(w, entry, http.StatusBadRequest, &models.PolicyErrorResponse{
+			Error:      services.ErrCredentialsPolicy.Error(),
+			Violations: policyErr.Violations,
+		})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, services.ErrUserNotFound):
 		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) issueMachineToken(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	claims := r.Context().Value(middleware.KeyClaims{}).(*models.CustomJWTClaims)
+
+	req := &models.MachineTokenRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	token, err := h.users.GetToken(user)
+	token, err := h.tokens.IssueMachineToken(r.Context(), claims, req)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidTokenName),
+			errors.Is(err, services.ErrInvalidScopes),
+			errors.Is(err, services.ErrInvalidTokenTTL):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusCreated, token)
+}
+
+func (h *handler) getMachineTokens(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	tokens, err := h.tokens.GetMachineTokens(r.Context(), userID)
 	if err != nil {
 		entry.Error(err.Error())
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Header().Set("Authorization", "Bearer "+token)
+	if len(tokens) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, tokens)
+}
+
+func (h *handler) revokeMachineToken(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	err := h.tokens.RevokeMachineToken(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrMachineTokenNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) getJWKS(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+jwksMaxAge)
 	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(h.auth.JWKS()); err != nil {
+		entry.Error(err.Error())
+	}
 }
 
 func (h *handler) uploadOrder(w http.ResponseWriter, r *http.Request) {
@@ -140,15 +421,54 @@ func (h *handler) uploadOrder(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusAccepted)
 }
 
-func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
+func (h *handler) uploadOrderBatch(w http.ResponseWriter, r *http.Request) {
 	entry := logEntry(h.log, r)
 
 	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
 
-	orders, err := h.orders.GetOrdersForUser(r.Context(), userID)
+	orderNums, err := parseOrderBatch(r)
 	if err != nil {
 		entry.Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.orders.CreateOrderBatch(r.Context(), userID, orderNums)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrEmptyOrderBatch), errors.Is(err, services.ErrOrderBatchTooLarge):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, results)
+}
+
+func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	h.listOrders(w, r, userID)
+}
+
+// listOrders writes a page of orders of a user.
+func (h *handler) listOrders(w http.ResponseWriter, r *http.Request, userID int64) {
+	entry := logEntry(h.log, r)
+
+	q, err := parseListQuery(r, userID)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, next, err := h.orders.GetOrdersForUser(r.Context(), q)
+	if err != nil {
+		entry.Error(err.Error())
+		writeListError(w, err)
 		return
 	}
 
@@ -158,9 +478,9 @@ func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sort.SliceStable(orders, func(i, j int) bool {
-		return orders[i].UploadedAt.Before(orders[j].UploadedAt)
-	})
+	if next != nil {
+		w.Header().Set(headerNextCursor, next.Encode())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -171,6 +491,96 @@ func (h *handler) getOrders(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *handler) getOrder(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	order, err := h.orders.GetOrder(r.Context(), userID, chi.URLParam(r, "number"))
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidOrderNumber):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrOrderNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, order)
+}
+
+// getOrderEvents streams order status and balance changes as Server-Sent Events.
+// A reconnecting client sends the id of the last received event in the Last-Event-ID header
+// and gets the events it missed first.
+func (h *handler) getOrderEvents(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		entry.Error("streaming is not supported")
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var lastEventID uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			entry.Error(err.Error())
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		lastEventID = id
+	}
+
+	sub := h.events.Subscribe(userID, lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range sub.Replay {
+		if err := writeEvent(w, event); err != nil {
+			entry.Error(err.Error())
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+
+			if err := writeEvent(w, event); err != nil {
+				entry.Error(err.Error())
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
 func (h *handler) getUserBalance(w http.ResponseWriter, r *http.Request) {
 	entry := logEntry(h.log, r)
 
@@ -206,12 +616,27 @@ func (h *handler) withdraw(w http.ResponseWriter, r *http.Request) {
 	}
 
 	withdrawal.UserID = userID
+	withdrawal.IdempotencyKey = r.Header.Get("Idempotency-Key")
 	err = h.users.WithdrawFromAccount(r.Context(), withdrawal)
+	if errors.Is(err, services.ErrWithdrawalReplayed) {
+		// A retried request is answered as the first one was.
+		entry.Info(err.Error(), "order", withdrawal.OrderNumber)
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	if err != nil {
 		entry.Error(err.Error())
 		switch {
+		case errors.Is(err, services.ErrWithdrawalAlreadyExists), errors.Is(err, services.ErrIdempotencyKeyReused):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, services.ErrInvalidIdempotencyKey):
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, services.ErrInsufficientFunds):
 			http.Error(w, err.Error(), http.StatusPaymentRequired)
+		case errors.Is(err, services.ErrInvalidWithdrawalSum):
+			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, services.ErrInvalidOrderNumber):
 			http.Error(w, err.Error(), http.StatusBadRequest)
 		case errors.Is(err, services.ErrInvalidOrderNumberFormat):
@@ -226,14 +651,26 @@ func (h *handler) withdraw(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	h.listWithdrawals(w, r, userID)
+}
+
+// listWithdrawals writes a page of withdrawals of a user.
+func (h *handler) listWithdrawals(w http.ResponseWriter, r *http.Request, userID int64) {
 	entry := logEntry(h.log, r)
 
-	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+	q, err := parseListQuery(r, userID)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	withdrawals, err := h.users.GetWithdrawals(r.Context(), userID)
+	withdrawals, next, err := h.users.GetWithdrawals(r.Context(), q)
 	if err != nil {
 		entry.Error(err.Error())
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeListError(w, err)
 		return
 	}
 
@@ -243,9 +680,9 @@ func (h *handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sort.SliceStable(withdrawals, func(i, j int) bool {
-		return withdrawals[i].ProcessedAt.Before(withdrawals[j].ProcessedAt)
-	})
+	if next != nil {
+		w.Header().Set(headerNextCursor, next.Encode())
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -256,15 +693,302 @@ func (h *handler) getWithdrawals(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *handler) getLedger(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	entries, err := h.users.GetLedger(r.Context(), userID)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(entries) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(entries); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	req := &models.WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.webhooks.CreateWebhook(r.Context(), userID, req)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhookURL), errors.Is(err, services.ErrWebhookAddrForbidden),
+			errors.Is(err, services.ErrInvalidWebhookEvents):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusCreated, webhook)
+}
+
+func (h *handler) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	webhooks, err := h.webhooks.GetWebhooks(r.Context(), userID)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, webhooks)
+}
+
+func (h *handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrWebhookNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.webhooks.DeleteWebhook(r.Context(), userID, webhookID)
+	if err != nil {
+		entry.Error(err.Error())
+		writeWebhookError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	userID := r.Context().Value(middleware.KeyUserID{}).(int64)
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrWebhookNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	deliveries, err := h.webhooks.GetWebhookDeliveries(r.Context(), userID, webhookID)
+	if err != nil {
+		entry.Error(err.Error())
+		writeWebhookError(w, err)
+		return
+	}
+
+	if len(deliveries) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, deliveries)
+}
+
+// writeWebhookError maps an error of a webhook operation to a response.
+func writeWebhookError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrWebhookNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func writeTokens(w http.ResponseWriter, entry services.Logger, tokens *models.TokenPair) {
+	w.Header().Set("Authorization", tokens.TokenType+" "+tokens.AccessToken)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, entry, http.StatusOK, tokens)
+}
+
+func writeJSON(w http.ResponseWriter, entry services.Logger, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		entry.Error(err.Error())
+	}
+}
+
+// writeEvent writes an event in the Server-Sent Events format.
+func writeEvent(w io.Writer, event *models.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+
+	return err
+}
+
+// parseOrderBatch reads the order numbers of a batch upload, a JSON array of strings
+// if the request content type is JSON, otherwise a newline-delimited list.
+// Blank lines of a list are skipped.
+func parseOrderBatch(r *http.Request) ([]string, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var orderNums []string
+		if err := json.NewDecoder(r.Body).Decode(&orderNums); err != nil {
+			return nil, err
+		}
+
+		return orderNums, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var orderNums []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			orderNums = append(orderNums, line)
+		}
+	}
+
+	return orderNums, nil
+}
+
+// parseListQuery reads the listing parameters of a request:
+// limit, cursor, status (comma separated or repeated) and from/to RFC 3339 times.
+func parseListQuery(r *http.Request, userID int64) (*models.ListQuery, error) {
+	params := r.URL.Query()
+	q := &models.ListQuery{UserID: userID}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+	q.Limit = limit
+
+	if v := params.Get("cursor"); v != "" {
+		cursor, err := models.DecodeCursor(v)
+		if err != nil {
+			return nil, err
+		}
+		q.Cursor = cursor
+	}
+
+	for _, v := range params["status"] {
+		for _, status := range strings.Split(v, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				q.Statuses = append(q.Statuses, strings.ToUpper(status))
+			}
+		}
+	}
+
+	if q.From, err = parseTimeParam(params, "from"); err != nil {
+		return nil, err
+	}
+
+	if q.To, err = parseTimeParam(params, "to"); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// parseLimit reads the optional limit query parameter.
+func parseLimit(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, services.ErrInvalidListLimit
+	}
+
+	return limit, nil
+}
+
+// parseTimeParam reads an optional RFC 3339 time query parameter.
+func parseTimeParam(params url.Values, name string) (*time.Time, error) {
+	v := params.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s time, RFC 3339 expected", name)
+	}
+
+	return &t, nil
+}
+
+// writeListError maps an error of a listing to a response.
+func writeListError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidListLimit),
+		errors.Is(err, services.ErrInvalidStatusFilter),
+		errors.Is(err, services.ErrInvalidDateRange):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// clientIP returns the address of the client the request came from.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
 func logEntry(l services.Logger, r *http.Request) services.Logger {
 	log, ok := l.(*slog.Logger)
 	if !ok {
 		return l
 	}
 
-	return log.With(
+	log = log.With(
 		slog.String("component", "handler"),
 		slog.String("method", r.Method),
 		slog.String("url", r.URL.Path),
+		slog.String("request_id", chiMiddleware.GetReqID(r.Context())),
 	)
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		log = log.With(slog.String("trace_id", sc.TraceID().String()))
+	}
+
+	return log
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/leonf08/gophermart.git/internal/controller/http/handlers/middleware"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -22,6 +24,8 @@ type mockLogger struct{}
 
 func (m *mockLogger) Info(msg string, args ...any) {}
 
+func (m *mockLogger) Warn(msg string, args ...any) {}
+
 func (m *mockLogger) Error(msg string, args ...any) {}
 
 func Test_handler_getOrders(t *testing.T) {
@@ -35,14 +39,18 @@ func Test_handler_getOrders(t *testing.T) {
 		log:    log,
 	}
 
+	next := &models.Cursor{Time: time.Date(2023, 12, 8, 12, 0, 0, 0, time.UTC), Key: "123456789"}
+
 	type want struct {
 		contentType string
 		status      int
+		nextCursor  string
 	}
 
 	tests := []struct {
 		name   string
 		userID int64
+		query  string
 		want   want
 	}{
 		{
@@ -68,20 +76,173 @@ func Test_handler_getOrders(t *testing.T) {
 				status:      http.StatusInternalServerError,
 			},
 		},
+		{
+			name:   "4. get orders, next page",
+			userID: 3,
+			query:  "?limit=1&status=new,processing&from=2023-12-01T00:00:00Z",
+			want: want{
+				contentType: "application/json",
+				status:      http.StatusOK,
+				nextCursor:  next.Encode(),
+			},
+		},
+		{
+			name:   "5. get orders, invalid limit",
+			userID: 1,
+			query:  "?limit=ten",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "6. get orders, invalid cursor",
+			userID: 1,
+			query:  "?cursor=!",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "7. get orders, invalid date",
+			userID: 1,
+			query:  "?to=yesterday",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "8. get orders, invalid status filter",
+			userID: 4,
+			query:  "?status=unknown",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
 	}
 
 	orders.
 		On("GetOrdersForUser", mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, userID int64) ([]*models.Order, error) {
-			if userID == 1 {
+		Return(func(ctx context.Context, q *models.ListQuery) ([]*models.Order, *models.Cursor, error) {
+			switch q.UserID {
+			case 1:
 				return []*models.Order{
 					{
 						Number:     "123456789",
 						UploadedAt: time.Now(),
 					},
+				}, nil, nil
+			case 2:
+				return []*models.Order{}, nil, nil
+			case 3:
+				if q.Limit != 1 || len(q.Statuses) != 2 || q.Statuses[0] != models.OrderStatusNew || q.From == nil {
+					return nil, nil, errors.New("unexpected query")
+				}
+
+				return []*models.Order{
+					{
+						Number:     "123456789",
+						UploadedAt: next.Time,
+					},
+				}, next, nil
+			case 4:
+				return nil, nil, services.ErrInvalidStatusFilter
+			}
+
+			return nil, nil, errors.New("internal server error")
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+tt.query, nil)
+			resp := httptest.NewRecorder()
+			h.getOrders(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, tt.userID)))
+
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.status, resp.Code)
+			assert.Equal(t, tt.want.nextCursor, resp.Header().Get(headerNextCursor))
+		})
+	}
+}
+
+func Test_handler_getOrder(t *testing.T) {
+	orders := mocks.NewOrders(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		orders: orders,
+		log:    log,
+	}
+
+	type want struct {
+		contentType string
+		status      int
+	}
+
+	tests := []struct {
+		name   string
+		number string
+		want   want
+	}{
+		{
+			name:   "1. get order success",
+			number: "12345678903",
+			want: want{
+				contentType: "application/json",
+				status:      http.StatusOK,
+			},
+		},
+		{
+			name:   "2. get order, invalid number",
+			number: "12345abc",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name:   "3. get order, not found",
+			number: "79927398713",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusNotFound,
+			},
+		},
+		{
+			name:   "4. get order, internal server error",
+			number: "4561261212345467",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusInternalServerError,
+			},
+		},
+	}
+
+	orders.
+		On("GetOrder", mock.Anything, int64(1), mock.Anything).
+		Return(func(ctx context.Context, userID int64, orderNum string) (*models.OrderDetail, error) {
+			switch orderNum {
+			case "12345678903":
+				return &models.OrderDetail{
+					Order: models.Order{
+						Number:     orderNum,
+						Status:     models.OrderStatusNew,
+						UploadedAt: time.Now(),
+					},
+					History: []*models.OrderStatusChange{
+						{
+							Status:    models.OrderStatusNew,
+							ChangedAt: time.Now(),
+						},
+					},
 				}, nil
-			} else if userID == 2 {
-				return []*models.Order{}, nil
+			case "12345abc":
+				return nil, services.ErrInvalidOrderNumber
+			case "79927398713":
+				return nil, services.ErrOrderNotFound
 			}
 
 			return nil, errors.New("internal server error")
@@ -89,14 +250,86 @@ func Test_handler_getOrders(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("number", tt.number)
+
+			req := httptest.NewRequest(http.MethodGet, "/orders/"+tt.number, nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, middleware.KeyUserID{}, int64(1))
 			resp := httptest.NewRecorder()
-			h.getOrders(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, tt.userID)))
+			h.getOrder(resp, req.WithContext(ctx))
 
-			orders.AssertExpectations(t)
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_getOrderEvents(t *testing.T) {
+	events := services.NewEventHub(context.Background(), services.EventHubConfig{Backlog: 10})
+	log := &mockLogger{}
+
+	h := &handler{
+		events: events,
+		log:    log,
+	}
+
+	events.Publish(1, models.EventOrder, &models.Order{Number: "12345678903", Status: models.OrderStatusProcessing})
+	events.Publish(1, models.EventBalance, &models.UserAccount{Current: 50000})
+	events.Publish(2, models.EventOrder, &models.Order{Number: "79927398713", Status: models.OrderStatusNew})
+
+	first := events.Subscribe(1, 1).Replay[0]
+
+	type want struct {
+		contentType string
+		status      int
+		body        []string
+	}
+
+	tests := []struct {
+		name        string
+		lastEventID string
+		want        want
+	}{
+		{
+			name:        "1. resume event stream",
+			lastEventID: strconv.FormatUint(first.ID, 10),
+			want: want{
+				contentType: "text/event-stream",
+				status:      http.StatusOK,
+				body: []string{
+					"event: balance\n",
+					`data: {"current":500,"withdrawn":0}`,
+				},
+			},
+		},
+		{
+			name:        "2. invalid last event id",
+			lastEventID: "last",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The client is gone right after the replay.
+			ctx, cancel := context.WithCancel(context.WithValue(context.Background(), middleware.KeyUserID{}, int64(1)))
+			cancel()
+
+			req := httptest.NewRequest(http.MethodGet, "/orders/events", nil)
+			req.Header.Set("Last-Event-ID", tt.lastEventID)
+			resp := httptest.NewRecorder()
+			h.getOrderEvents(resp, req.WithContext(ctx))
 
 			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
 			assert.Equal(t, tt.want.status, resp.Code)
+			for _, line := range tt.want.body {
+				assert.Contains(t, resp.Body.String(), line)
+			}
+			assert.NotContains(t, resp.Body.String(), "79927398713")
 		})
 	}
 }
@@ -214,20 +447,20 @@ func Test_handler_getWithdrawals(t *testing.T) {
 
 	users.
 		On("GetWithdrawals", mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-			if userID == 1 {
+		Return(func(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, *models.Cursor, error) {
+			if q.UserID == 1 {
 				return []*models.Withdrawal{
 					{
 						OrderNumber: "123456789",
 						Sum:         100,
 						ProcessedAt: time.Now(),
 					},
-				}, nil
-			} else if userID == 2 {
-				return []*models.Withdrawal{}, nil
+				}, nil, nil
+			} else if q.UserID == 2 {
+				return []*models.Withdrawal{}, nil, nil
 			}
 
-			return nil, errors.New("internal server error")
+			return nil, nil, errors.New("internal server error")
 		})
 
 	for _, tt := range tests {
@@ -339,17 +572,100 @@ func Test_handler_uploadOrder(t *testing.T) {
 	}
 }
 
-func Test_handler_userLogIn(t *testing.T) {
-	users := mocks.NewUsers(t)
+func Test_handler_uploadOrderBatch(t *testing.T) {
 	orders := mocks.NewOrders(t)
 	log := &mockLogger{}
 
 	h := &handler{
-		users:  users,
 		orders: orders,
 		log:    log,
 	}
 
+	type want struct {
+		status int
+		body   string
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        want
+	}{
+		{
+			name:        "1. upload JSON batch success",
+			contentType: "application/json",
+			body:        `["79927398713", "1"]`,
+			want: want{
+				status: http.StatusOK,
+				body:   `[{"number":"79927398713","result":"accepted"},{"number":"1","result":"invalid"}]`,
+			},
+		},
+		{
+			name:        "2. upload newline-delimited batch success",
+			contentType: "text/plain",
+			body:        "79927398713\r\n\n1\n",
+			want: want{
+				status: http.StatusOK,
+				body:   `[{"number":"79927398713","result":"accepted"},{"number":"1","result":"invalid"}]`,
+			},
+		},
+		{
+			name:        "3. upload batch fail, malformed JSON",
+			contentType: "application/json",
+			body:        `{"order": "79927398713"}`,
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name:        "4. upload batch fail, empty batch",
+			contentType: "text/plain",
+			body:        "\n",
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+	}
+
+	orders.
+		On("CreateOrderBatch", mock.Anything, int64(1), []string{"79927398713", "1"}).
+		Return([]*models.OrderBatchResult{
+			{Number: "79927398713", Result: models.OrderBatchAccepted},
+			{Number: "1", Result: models.OrderBatchInvalid},
+		}, nil)
+	orders.
+		On("CreateOrderBatch", mock.Anything, int64(1), []string(nil)).
+		Return(nil, services.ErrEmptyOrderBatch)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			resp := httptest.NewRecorder()
+			h.uploadOrderBatch(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, int64(1))))
+
+			assert.Equal(t, tt.want.status, resp.Code)
+			if tt.want.body != "" {
+				assert.JSONEq(t, tt.want.body, resp.Body.String())
+			}
+		})
+	}
+}
+
+func Test_handler_userLogIn(t *testing.T) {
+	users := mocks.NewUsers(t)
+	orders := mocks.NewOrders(t)
+	sessions := mocks.NewSessions(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		users:    users,
+		orders:   orders,
+		sessions: sessions,
+		log:      log,
+	}
+
 	type want struct {
 		status int
 	}
@@ -381,7 +697,14 @@ func Test_handler_userLogIn(t *testing.T) {
 			},
 		},
 		{
-			name: "4. login fail, internal server error",
+			name: "4. login fail, too many attempts",
+			body: `{"login":"locked","password":"user"}`,
+			want: want{
+				status: http.StatusTooManyRequests,
+			},
+		},
+		{
+			name: "5. login fail, internal server error",
 			body: `{"login":"admin","password":"admin"}`,
 			want: want{
 				status: http.StatusInternalServerError,
@@ -390,23 +713,26 @@ func Test_handler_userLogIn(t *testing.T) {
 	}
 
 	users.
-		On("LoginUser", mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, user *models.User) error {
+		On("LoginUser", mock.Anything, mock.Anything, "192.0.2.1").
+		Return(func(ctx context.Context, user *models.User, clientIP string) error {
 			if user.Password == "test" {
-				return errors.New("unauthorized")
+				return services.ErrInvalidCredentials
+			}
+			if user.Login == "locked" {
+				return &services.LoginThrottledError{RetryAfter: 1500 * time.Millisecond}
 			}
 
 			return nil
 		})
 
-	users.
-		On("GetToken", mock.Anything).
-		Return(func(user *models.User) (string, error) {
+	sessions.
+		On("CreateSession", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, user *models.User) (*models.TokenPair, error) {
 			if user.Login == "admin" {
-				return "", errors.New("internal server error")
+				return nil, errors.New("internal server error")
 			}
 
-			return "token", nil
+			return &models.TokenPair{AccessToken: "token", RefreshToken: "refresh", TokenType: "Bearer"}, nil
 		})
 
 	for _, tt := range tests {
@@ -418,6 +744,16 @@ func Test_handler_userLogIn(t *testing.T) {
 			users.AssertExpectations(t)
 
 			assert.Equal(t, tt.want.status, resp.Code)
+			if tt.want.status == http.StatusTooManyRequests {
+				assert.Equal(t, "2", resp.Header().Get("Retry-After"))
+			}
+			if tt.want.status == http.StatusUnauthorized {
+				assert.Equal(t, "invalid credentials\n", resp.Body.String())
+			}
+			if tt.want.status == http.StatusOK {
+				assert.Equal(t, "Bearer token", resp.Header().Get("Authorization"))
+				assert.JSONEq(t, `{"access_token":"token","refresh_token":"refresh","token_type":"Bearer","expires_in":0}`, resp.Body.String())
+			}
 		})
 	}
 }
@@ -425,12 +761,14 @@ func Test_handler_userLogIn(t *testing.T) {
 func Test_handler_userSignUp(t *testing.T) {
 	users := mocks.NewUsers(t)
 	orders := mocks.NewOrders(t)
+	sessions := mocks.NewSessions(t)
 	log := &mockLogger{}
 
 	h := &handler{
-		users:  users,
-		orders: orders,
-		log:    log,
+		users:    users,
+		orders:   orders,
+		sessions: sessions,
+		log:      log,
 	}
 
 	type want struct {
@@ -471,7 +809,14 @@ func Test_handler_userSignUp(t *testing.T) {
 			},
 		},
 		{
-			name: "5. sign up fail, get token internal server error",
+			name: "5. sign up fail, credentials policy",
+			body: `{"login":"te","password":"te"}`,
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "6. sign up fail, get token internal server error",
 			body: `{"login":"login","password":"password"}`,
 			want: want{
 				status: http.StatusInternalServerError,
@@ -482,7 +827,11 @@ func Test_handler_userSignUp(t *testing.T) {
 	users.
 		On("RegisterUser", mock.Anything, mock.Anything).
 		Return(func(ctx context.Context, user *models.User) error {
-			if user.Login == "user" {
+			if user.Login == "te" {
+				return &services.CredentialsPolicyError{Violations: []models.PolicyViolation{
+					{Field: "login", Rule: services.RuleMinLength, Message: "login must be at least 3 characters long"},
+				}}
+			} else if user.Login == "user" {
 				return &pgconn.PgError{Code: pgerrcode.UniqueViolation}
 			} else if user.Login == "admin" {
 				return errors.New("internal server error")
@@ -490,14 +839,14 @@ func Test_handler_userSignUp(t *testing.T) {
 			return nil
 		})
 
-	users.
-		On("GetToken", mock.Anything).
-		Return(func(user *models.User) (string, error) {
+	sessions.
+		On("CreateSession", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, user *models.User) (*models.TokenPair, error) {
 			if user.Login == "login" {
-				return "", errors.New("internal server error")
+				return nil, errors.New("internal server error")
 			}
 
-			return "token", nil
+			return &models.TokenPair{AccessToken: "token", RefreshToken: "refresh", TokenType: "Bearer"}, nil
 		})
 
 	for _, tt := range tests {
@@ -509,6 +858,13 @@ func Test_handler_userSignUp(t *testing.T) {
 			users.AssertExpectations(t)
 
 			assert.Equal(t, tt.want.status, resp.Code)
+			if tt.want.status == http.StatusOK {
+				assert.Equal(t, "Bearer token", resp.Header().Get("Authorization"))
+				assert.JSONEq(t, `{"access_token":"token","refresh_token":"refresh","token_type":"Bearer","expires_in":0}`, resp.Body.String())
+			}
+			if tt.name == "5. sign up fail, credentials policy" {
+				assert.JSONEq(t, `{"error":"credentials do not satisfy the policy","violations":[{"field":"login","rule":"min_length","message":"login must be at least 3 characters long"}]}`, resp.Body.String())
+			}
 		})
 	}
 }
@@ -525,14 +881,16 @@ func Test_handler_withdraw(t *testing.T) {
 	}
 
 	type want struct {
-		status int
+		status   int
+		replayed string
 	}
 
 	tests := []struct {
-		name   string
-		userID int64
-		body   string
-		want   want
+		name           string
+		userID         int64
+		body           string
+		idempotencyKey string
+		want           want
 	}{
 		{
 			name:   "1. withdraw success",
@@ -581,12 +939,45 @@ func Test_handler_withdraw(t *testing.T) {
 				status: http.StatusBadRequest,
 			},
 		},
+		{
+			name:           "7. withdraw replayed by idempotency key",
+			userID:         1,
+			body:           `{"order":"123456789","sum":100}`,
+			idempotencyKey: "replayed",
+			want: want{
+				status:   http.StatusOK,
+				replayed: "true",
+			},
+		},
+		{
+			name:           "8. withdraw fail, idempotency key reused",
+			userID:         1,
+			body:           `{"order":"123456789","sum":100}`,
+			idempotencyKey: "reused",
+			want: want{
+				status: http.StatusConflict,
+			},
+		},
+		{
+			name:   "9. withdraw fail, withdrawal already exists",
+			userID: 3,
+			body:   `{"order":"123456789","sum":100}`,
+			want: want{
+				status: http.StatusConflict,
+			},
+		},
 	}
 
 	users.
 		On("WithdrawFromAccount", mock.Anything, mock.Anything).
 		Return(func(ctx context.Context, withdrawal *models.Withdrawal) error {
-			if withdrawal.Sum == 1000 {
+			if withdrawal.IdempotencyKey == "replayed" {
+				return services.ErrWithdrawalReplayed
+			} else if withdrawal.IdempotencyKey == "reused" {
+				return services.ErrIdempotencyKeyReused
+			} else if withdrawal.UserID == 3 {
+				return services.ErrWithdrawalAlreadyExists
+			} else if withdrawal.Sum == 1000*100 {
 				return services.ErrInsufficientFunds
 			} else if withdrawal.OrderNumber == "12345678dfg" {
 				return services.ErrInvalidOrderNumber
@@ -602,12 +993,657 @@ func Test_handler_withdraw(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
 			resp := httptest.NewRecorder()
 			h.withdraw(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, tt.userID)))
 
 			users.AssertExpectations(t)
 
 			assert.Equal(t, tt.want.status, resp.Code)
+			assert.Equal(t, tt.want.replayed, resp.Header().Get("Idempotent-Replayed"))
+		})
+	}
+}
+
+func Test_handler_getLedger(t *testing.T) {
+	users := mocks.NewUsers(t)
+	orders := mocks.NewOrders(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		users:  users,
+		orders: orders,
+		log:    log,
+	}
+
+	type want struct {
+		contentType string
+		status      int
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   want
+	}{
+		{
+			name:   "1. get ledger success",
+			userID: 1,
+			want: want{
+				contentType: "application/json",
+				status:      http.StatusOK,
+			},
+		},
+		{
+			name:   "2. get ledger, no content",
+			userID: 2,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusNoContent,
+			},
+		},
+		{
+			name: "3. get ledger, internal server error",
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusInternalServerError,
+			},
+		},
+	}
+
+	users.
+		On("GetLedger", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+			if userID == 1 {
+				return []*models.LedgerEntry{
+					{
+						TransactionID: 1,
+						Account:       models.LedgerAccountCurrent,
+						Kind:          models.LedgerKindAccrual,
+						Amount:        100,
+						OrderNumber:   "123456789",
+						CreatedAt:     time.Now(),
+					},
+				}, nil
+			} else if userID == 2 {
+				return []*models.LedgerEntry{}, nil
+			}
+
+			return nil, errors.New("internal server error")
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			resp := httptest.NewRecorder()
+			h.getLedger(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, tt.userID)))
+
+			users.AssertExpectations(t)
+
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_refreshToken(t *testing.T) {
+	sessions := mocks.NewSessions(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		sessions: sessions,
+		log:      log,
+	}
+
+	type want struct {
+		status int
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want want
+	}{
+		{
+			name: "1. refresh success",
+			body: `{"refresh_token":"refresh"}`,
+			want: want{
+				status: http.StatusOK,
+			},
+		},
+		{
+			name: "2. refresh fail, bad request",
+			body: `{"refresh_token":`,
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "3. refresh fail, invalid token",
+			body: `{"refresh_token":"invalid"}`,
+			want: want{
+				status: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "4. refresh fail, token reused",
+			body: `{"refresh_token":"reused"}`,
+			want: want{
+				status: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "5. refresh fail, internal server error",
+			body: `{"refresh_token":"error"}`,
+			want: want{
+				status: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	sessions.
+		On("RefreshSession", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, token string) (*models.TokenPair, error) {
+			switch token {
+			case "invalid":
+				return nil, services.ErrInvalidRefreshToken
+			case "reused":
+				return nil, services.ErrRefreshTokenReused
+			case "error":
+				return nil, errors.New("internal server error")
+			}
+
+			return &models.TokenPair{AccessToken: "token", RefreshToken: "new", TokenType: "Bearer"}, nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			resp := httptest.NewRecorder()
+			h.refreshToken(resp, req)
+
+			assert.Equal(t, tt.want.status, resp.Code)
+			if tt.want.status == http.StatusOK {
+				assert.Equal(t, "Bearer token", resp.Header().Get("Authorization"))
+			}
+		})
+	}
+}
+
+func Test_handler_logOut(t *testing.T) {
+	sessions := mocks.NewSessions(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		sessions: sessions,
+		log:      log,
+	}
+
+	tests := []struct {
+		name   string
+		claims *models.CustomJWTClaims
+		status int
+	}{
+		{
+			name:   "1. logout success",
+			claims: &models.CustomJWTClaims{UserID: 1, SessionID: "session"},
+			status: http.StatusOK,
+		},
+		{
+			name:   "2. logout fail, internal server error",
+			claims: &models.CustomJWTClaims{UserID: 2, SessionID: "session"},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	sessions.
+		On("RevokeSession", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, claims *models.CustomJWTClaims) error {
+			if claims.UserID == 2 {
+				return errors.New("internal server error")
+			}
+
+			return nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			ctx := context.WithValue(req.Context(), middleware.KeyClaims{}, tt.claims)
+			resp := httptest.NewRecorder()
+			h.logOut(resp, req.WithContext(ctx))
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_getJWKS(t *testing.T) {
+	auth := mocks.NewAuthenticator(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		auth: auth,
+		log:  log,
+	}
+
+	auth.
+		On("JWKS").
+		Return(models.JWKS{Keys: []models.JWK{{Kty: "OKP", Use: "sig", Alg: "EdDSA", Kid: "kid", Crv: "Ed25519", X: "x"}}})
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	resp := httptest.NewRecorder()
+	h.getJWKS(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"keys":[{"kty":"OKP","use":"sig","alg":"EdDSA","kid":"kid","crv":"Ed25519","x":"x"}]}`, resp.Body.String())
+}
+
+func Test_handler_changePassword(t *testing.T) {
+	users := mocks.NewUsers(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		users: users,
+		log:   log,
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{
+			name:   "1. change password success",
+			body:   `{"old_password":"old password","new_password":"new password"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "2. change password fail, bad request",
+			body:   `{"old_password":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "3. change password fail, wrong old password",
+			body:   `{"old_password":"wrong","new_password":"new password"}`,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "4. change password fail, credentials policy",
+			body:   `{"old_password":"old password","new_password":"short"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "5. change password fail, too many attempts",
+			body:   `{"old_password":"locked","new_password":"new password"}`,
+			status: http.StatusTooManyRequests,
+		},
+	}
+
+	users.
+		On("ChangePassword", mock.Anything, int64(1), mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, userID int64, req *models.PasswordChangeRequest, clientIP string) error {
+			switch {
+			case req.OldPassword == "wrong":
+				return services.ErrInvalidCredentials
+			case req.OldPassword == "locked":
+				return &services.LoginThrottledError{RetryAfter: time.Second}
+			case req.NewPassword == "short":
+				return &services.CredentialsPolicyError{Violations: []models.PolicyViolation{
+					{Field: "password", Rule: services.RuleMinLength, Message: "password must be at least 8 characters long"},
+				}}
+			}
+
+			return nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			ctx := context.WithValue(req.Context(), middleware.KeyUserID{}, int64(1))
+			resp := httptest.NewRecorder()
+			h.changePassword(resp, req.WithContext(ctx))
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_deleteUser(t *testing.T) {
+	users := mocks.NewUsers(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		users: users,
+		log:   log,
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{
+			name:   "1. delete user success",
+			body:   `{"password":"password"}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "2. delete user fail, bad request",
+			body:   `{"password":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "3. delete user fail, wrong password",
+			body:   `{"password":"wrong"}`,
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "4. delete user fail, remaining balance",
+			body:   `{"password":"balance"}`,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "5. delete user fail, internal server error",
+			body:   `{"password":"error"}`,
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	users.
+		On("DeleteUser", mock.Anything, int64(1), mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, userID int64, req *models.AccountDeletionRequest, clientIP string) error {
+			switch req.Password {
+			case "wrong":
+				return services.ErrInvalidCredentials
+			case "balance":
+				return services.ErrAccountHasBalance
+			case "error":
+				return errors.New("internal server error")
+			}
+
+			return nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodDelete, "/", strings.NewReader(tt.body))
+			ctx := context.WithValue(req.Context(), middleware.KeyUserID{}, int64(1))
+			resp := httptest.NewRecorder()
+			h.deleteUser(resp, req.WithContext(ctx))
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_createWebhook(t *testing.T) {
+	webhooks := mocks.NewWebhooks(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		webhooks: webhooks,
+		log:      log,
+	}
+
+	type want struct {
+		contentType string
+		status      int
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want want
+	}{
+		{
+			name: "1. create webhook success",
+			body: `{"url":"https://shop.example.com/hooks","events":["order.processed"]}`,
+			want: want{
+				contentType: "application/json",
+				status:      http.StatusCreated,
+			},
+		},
+		{
+			name: "2. create webhook, invalid url",
+			body: `{"url":"shop","events":["order.processed"]}`,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name: "3. create webhook, malformed body",
+			body: `{"url":`,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name: "4. create webhook, internal address",
+			body: `{"url":"http://169.254.169.254/latest","events":["order.processed"]}`,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+	}
+
+	webhooks.
+		On("CreateWebhook", mock.Anything, int64(1), mock.Anything).
+		Return(func(ctx context.Context, userID int64, req *models.WebhookRequest) (*models.Webhook, error) {
+			switch req.URL {
+			case "shop":
+				return nil, services.ErrInvalidWebhookURL
+			case "http://169.254.169.254/latest":
+				return nil, services.ErrWebhookAddrForbidden
+			}
+
+			return &models.Webhook{WebhookID: 1, URL: req.URL, Secret: "secret", Events: req.Events}, nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tt.body))
+			resp := httptest.NewRecorder()
+			h.createWebhook(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyUserID{}, int64(1))))
+
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_deleteWebhook(t *testing.T) {
+	webhooks := mocks.NewWebhooks(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		webhooks: webhooks,
+		log:      log,
+	}
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{
+			name:   "1. delete webhook success",
+			id:     "1",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "2. delete webhook, not found",
+			id:     "2",
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "3. delete webhook, invalid id",
+			id:     "first",
+			status: http.StatusNotFound,
+		},
+	}
+
+	webhooks.On("DeleteWebhook", mock.Anything, int64(1), int64(1)).Return(nil)
+	webhooks.On("DeleteWebhook", mock.Anything, int64(1), int64(2)).Return(services.ErrWebhookNotFound)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.id)
+
+			req := httptest.NewRequest(http.MethodDelete, "/webhooks/"+tt.id, nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, middleware.KeyUserID{}, int64(1))
+			resp := httptest.NewRecorder()
+			h.deleteWebhook(resp, req.WithContext(ctx))
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_routesScopes(t *testing.T) {
+	sessions := mocks.NewSessions(t)
+	users := mocks.NewUsers(t)
+
+	h := &handler{
+		sessions: sessions,
+		users:    users,
+		log:      &mockLogger{},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/user", h.routes)
+
+	sessions.
+		On("Authenticate", mock.Anything, "gmt_balance").
+		Return(&models.CustomJWTClaims{UserID: 1, Scope: models.ScopeBalanceRead}, nil)
+	sessions.
+		On("Authenticate", mock.Anything, "user").
+		Return(&models.CustomJWTClaims{UserID: 1}, nil)
+
+	users.
+		On("GetUserAccount", mock.Anything, int64(1)).
+		Return(&models.UserAccount{UserID: 1, Current: 10050}, nil)
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		target string
+		status int
+	}{
+		{
+			name:   "1. machine token within its scope",
+			token:  "gmt_balance",
+			method: http.MethodGet,
+			target: "/api/user/balance",
+			status: http.StatusOK,
+		},
+		{
+			name:   "2. machine token outside its scope",
+			token:  "gmt_balance",
+			method: http.MethodPost,
+			target: "/api/user/orders",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "3. machine token can not issue tokens",
+			token:  "gmt_balance",
+			method: http.MethodPost,
+			target: "/api/user/tokens",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "4. access token without scope claim has all user scopes",
+			token:  "user",
+			method: http.MethodGet,
+			target: "/api/user/balance",
+			status: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+		})
+	}
+}
+
+func Test_handler_issueMachineToken(t *testing.T) {
+	tokens := mocks.NewMachineTokens(t)
+	log := &mockLogger{}
+
+	h := &handler{
+		tokens: tokens,
+		log:    log,
+	}
+
+	type want struct {
+		contentType string
+		status      int
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want want
+	}{
+		{
+			name: "1. issue machine token success",
+			body: `{"name":"analytics","scopes":["orders:read","balance:read"],"ttl":3600}`,
+			want: want{
+				contentType: "application/json",
+				status:      http.StatusCreated,
+			},
+		},
+		{
+			name: "2. issue machine token, invalid scopes",
+			body: `{"name":"analytics","scopes":["account"]}`,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+		{
+			name: "3. issue machine token, malformed body",
+			body: `{"name":`,
+			want: want{
+				contentType: "text/plain; charset=utf-8",
+				status:      http.StatusBadRequest,
+			},
+		},
+	}
+
+	claims := &models.CustomJWTClaims{UserID: 1}
+	tokens.
+		On("IssueMachineToken", mock.Anything, claims, mock.Anything).
+		Return(func(ctx context.Context, claims *models.CustomJWTClaims, req *models.MachineTokenRequest) (*models.MachineToken, error) {
+			if req.Scopes[0] == models.ScopeAccount {
+				return nil, services.ErrInvalidScopes
+			}
+
+			return &models.MachineToken{TokenID: "id", Name: req.Name, Scopes: req.Scopes, Token: "gmt_token"}, nil
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(tt.body))
+			resp := httptest.NewRecorder()
+			h.issueMachineToken(resp, req.WithContext(context.WithValue(req.Context(), middleware.KeyClaims{}, claims)))
+
+			assert.Equal(t, tt.want.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, tt.want.status, resp.Code)
 		})
 	}
 }
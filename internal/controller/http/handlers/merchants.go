@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/leonf08/gophermart.git/internal/controller/http/handlers/middleware"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"net/http"
+	"strconv"
+)
+
+func (h *handler) merchantRoutes(r chi.Router) {
+	r.Use(middleware.APIKey(h.merchants))
+	r.Post("/orders", h.uploadMerchantOrder)
+}
+
+func (h *handler) uploadMerchantOrder(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	key := r.Context().Value(middleware.KeyAPIKey{}).(*models.APIKey)
+
+	req := &models.MerchantOrder{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := h.merchants.UploadOrder(r.Context(), req)
+	if errors.Is(err, services.ErrOrderAlreadyExistsForUser) {
+		// A repeated upload of the same order for the same user succeeds.
+		entry.Info(err.Error(), "merchant", key.Merchant, "login", req.Login, "order", req.Number)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err != nil {
+		entry.Error(err.Error(), "merchant", key.Merchant, "login", req.Login)
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, services.ErrInvalidOrderNumber):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, services.ErrInvalidOrderNumberFormat):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, services.ErrOrderAlreadyExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	entry.Info("order uploaded by merchant", "merchant", key.Merchant, "login", req.Login, "order", req.Number)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *handler) issueAPIKey(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	req := &models.APIKeyRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.merchants.IssueAPIKey(r.Context(), req)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrInvalidMerchant), errors.Is(err, services.ErrInvalidAPIKeyTTL):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, entry, http.StatusCreated, key)
+}
+
+func (h *handler) getAPIKeys(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	keys, err := h.merchants.GetAPIKeys(r.Context())
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(keys) == 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, entry, http.StatusOK, keys)
+}
+
+func (h *handler) revokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	entry := logEntry(h.log, r)
+
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		entry.Error(err.Error())
+		http.Error(w, services.ErrAPIKeyNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	err = h.merchants.RevokeAPIKey(r.Context(), keyID)
+	if err != nil {
+		entry.Error(err.Error())
+		switch {
+		case errors.Is(err, services.ErrAPIKeyNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
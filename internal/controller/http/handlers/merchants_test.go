@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_handler_merchantRoutes(t *testing.T) {
+	merchants := mocks.NewMerchants(t)
+
+	h := &handler{
+		merchants: merchants,
+		log:       &mockLogger{},
+	}
+
+	r := chi.NewRouter()
+	r.Route("/api/merchant", h.merchantRoutes)
+
+	merchants.
+		On("AuthenticateAPIKey", mock.Anything, "gmk_0a1b2c3d4e5f.secret").
+		Return(&models.APIKey{KeyID: 1, Merchant: "shop", Prefix: "0a1b2c3d4e5f"}, nil)
+	merchants.
+		On("AuthenticateAPIKey", mock.Anything, "gmk_0a1b2c3d4e5f.revoked").
+		Return(nil, services.ErrAPIKeyRevoked)
+
+	merchants.
+		On("UploadOrder", mock.Anything, &models.MerchantOrder{Login: "gopher", Number: "79927398713"}).
+		Return(nil)
+	merchants.
+		On("UploadOrder", mock.Anything, &models.MerchantOrder{Login: "nobody", Number: "79927398713"}).
+		Return(services.ErrUserNotFound)
+	merchants.
+		On("UploadOrder", mock.Anything, &models.MerchantOrder{Login: "gopher", Number: "79927398710"}).
+		Return(services.ErrInvalidOrderNumberFormat)
+	merchants.
+		On("UploadOrder", mock.Anything, &models.MerchantOrder{Login: "gopher", Number: "12345678903"}).
+		Return(services.ErrOrderAlreadyExistsForUser)
+
+	tests := []struct {
+		name   string
+		auth   string
+		body   string
+		status int
+	}{
+		{
+			name:   "1. upload order success",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.secret",
+			body:   `{"login":"gopher","order":"79927398713"}`,
+			status: http.StatusAccepted,
+		},
+		{
+			name:   "2. upload order, unknown user",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.secret",
+			body:   `{"login":"nobody","order":"79927398713"}`,
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "3. upload order, invalid order number",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.secret",
+			body:   `{"login":"gopher","order":"79927398710"}`,
+			status: http.StatusUnprocessableEntity,
+		},
+		{
+			name:   "4. upload order, malformed body",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.secret",
+			body:   `{"login":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "5. revoked key",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.revoked",
+			body:   `{"login":"gopher","order":"79927398713"}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "6. bearer token instead of api key",
+			auth:   "Bearer token",
+			body:   `{"login":"gopher","order":"79927398713"}`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "7. upload order, already uploaded for the user",
+			auth:   "ApiKey gmk_0a1b2c3d4e5f.secret",
+			body:   `{"login":"gopher","order":"12345678903"}`,
+			status: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/merchant/orders", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.auth)
+			resp := httptest.NewRecorder()
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.status, resp.Code)
+			if tt.status == http.StatusOK {
+				assert.Empty(t, resp.Body.String())
+			}
+		})
+	}
+}
@@ -2,14 +2,22 @@ package middleware
 
 import (
 	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
 	"github.com/leonf08/gophermart.git/internal/services"
 	"net/http"
 	"strings"
 )
 
-type KeyUserID struct{}
+type (
+	KeyUserID struct{}
+	KeyClaims struct{}
+	KeyAPIKey struct{}
+)
+
+// apiKeyScheme is the authorization scheme of merchant API keys.
+const apiKeyScheme = "ApiKey"
 
-func Auth(auth services.Authenticator) func(next http.Handler) http.Handler {
+func Auth(sessions services.Sessions) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			token := strings.Split(r.Header.Get("Authorization"), " ")
@@ -18,14 +26,69 @@ func Auth(auth services.Authenticator) func(next http.Handler) http.Handler {
 				return
 			}
 
-			claims, err := auth.ValidateTokenAndExtractClaims(token[1])
+			claims, err := sessions.Authenticate(r.Context(), token[1])
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusUnauthorized)
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), KeyUserID{}, claims.UserID)
+			ctx = context.WithValue(ctx, KeyClaims{}, claims)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// APIKey authenticates merchant backends with an API key sent as "Authorization: ApiKey <key>",
+// an alternative to Bearer access tokens. The authenticated key is put into the request context.
+func APIKey(merchants services.Merchants) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, key, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, apiKeyScheme) {
+				http.Error(w, "invalid api key format", http.StatusUnauthorized)
+				return
+			}
+
+			apiKey, err := merchants.AuthenticateAPIKey(r.Context(), key)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), KeyAPIKey{}, apiKey)))
+		})
+	}
+}
+
+// RequireRole lets through only the requests authenticated with a token of the given role.
+// It must be used after Auth.
+func RequireRole(role string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(KeyClaims{}).(*models.CustomJWTClaims)
+			if !ok || claims.Role != role {
+				http.Error(w, "insufficient role", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope lets through only the requests authenticated with a token which has the given scope.
+// It must be used after Auth.
+func RequireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(KeyClaims{}).(*models.CustomJWTClaims)
+			if !ok || !claims.HasScope(scope) {
+				http.Error(w, services.ErrInsufficientScope.Error(), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
 	"log/slog"
 	"net/http"
 	"time"
@@ -18,6 +19,9 @@ func Logging(l *slog.Logger) func(handler http.Handler) http.Handler {
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
 			)
+			if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+				entry = entry.With(slog.String("trace_id", sc.TraceID().String()))
+			}
 
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/leonf08/gophermart.git/internal/metrics"
+	"net/http"
+	"time"
+)
+
+// notFoundRoute is the route label of requests which matched no route.
+const notFoundRoute = "not_found"
+
+// Metrics records the count and the latency of requests per chi route pattern.
+func Metrics(next http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		t := time.Now()
+		next.ServeHTTP(ww, r)
+
+		// The pattern is complete only after the request went through all subrouters.
+		route := notFoundRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		// A handler which writes nothing responds with 200 OK.
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.ObserveHTTPRequest(r.Method, route, status, time.Since(t))
+	}
+
+	return http.HandlerFunc(fn)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/leonf08/gophermart.git/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Route("/api/test", func(r chi.Router) {
+		r.Get("/orders/{number}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+	})
+	r.Handle("/metrics", metrics.Handler())
+
+	for _, target := range []string{"/api/test/orders/1", "/api/test/orders/2", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := resp.Body.String()
+	assert.Contains(t, body, `gophermart_http_requests_total{method="GET",route="/api/test/orders/{number}",status="202"} 2`)
+	assert.Contains(t, body, `gophermart_http_requests_total{method="GET",route="not_found",status="404"} 1`)
+	assert.Contains(t, body, `gophermart_http_request_duration_seconds_count{method="GET",route="/api/test/orders/{number}"} 2`)
+}
@@ -6,29 +6,43 @@ import (
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/leonf08/gophermart.git/internal/controller/http/handlers/middleware"
+	"github.com/leonf08/gophermart.git/internal/metrics"
 	"github.com/leonf08/gophermart.git/internal/services"
 	"log/slog"
 )
 
-func NewRouter(users services.Users, orders services.Orders, auth services.Authenticator, log *slog.Logger) *chi.Mux {
+func NewRouter(users services.Users, orders services.Orders, sessions services.Sessions, webhooks services.Webhooks, tokens services.MachineTokens, merchants services.Merchants, admin services.Admin, auth services.Authenticator, events services.Events, log *slog.Logger) *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(
 		cors.Handler(cors.Options{
 			AllowedOrigins:   []string{"*"},
-			AllowedMethods:   []string{"POST", "GET"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+			AllowedMethods:   []string{"POST", "GET", "DELETE"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "Last-Event-ID"},
 			AllowCredentials: true,
-			ExposedHeaders:   []string{"Authorization"},
+			ExposedHeaders:   []string{"Authorization", "Idempotent-Replayed", "X-Next-Cursor"},
 			MaxAge:           300,
 		}),
 		chiMiddleware.Compress(flate.BestCompression),
 		chiMiddleware.RequestID,
+		middleware.Tracing,
 		middleware.Logging(log),
+		middleware.Metrics,
 	)
 
-	r.Route("/api/user/", func(r chi.Router) {
-		newHandler(r, users, orders, auth, log)
-	})
+	h := newHandler(users, orders, sessions, webhooks, tokens, merchants, admin, auth, events, log)
+	r.Get("/.well-known/jwks.json", h.getJWKS)
+	r.Route("/api/user", h.routes)
+	r.Route("/api/admin", h.adminRoutes)
+	r.Route("/api/merchant", h.merchantRoutes)
+
+	return r
+}
+
+// NewMetricsRouter returns the router of the metrics server.
+// It is served on a separate address, so the metrics are not exposed with the public API.
+func NewMetricsRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.Handle("/metrics", metrics.Handler())
 
 	return r
 }
@@ -0,0 +1,8 @@
+begin transaction;
+
+alter table orders
+    drop column if exists accrual_attempts,
+    drop column if exists accrual_next_at,
+    drop column if exists accrual_last_error;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,8 @@
+begin transaction;
+
+alter table orders
+    add column if not exists accrual_attempts integer not null default 0,
+    add column if not exists accrual_next_at timestamp not null default now(),
+    add column if not exists accrual_last_error text not null default '';
+
+commit;
\ No newline at end of file
@@ -0,0 +1,6 @@
+begin transaction;
+
+alter table orders
+    drop column if exists accrual_dead_at;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,6 @@
+begin transaction;
+
+alter table orders
+    add column if not exists accrual_dead_at timestamp;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,6 @@
+begin transaction;
+
+alter table users
+    drop constraint if exists users_current_non_negative;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,6 @@
+begin transaction;
+
+alter table users
+    add constraint users_current_non_negative check (current >= 0);
+
+commit;
\ No newline at end of file
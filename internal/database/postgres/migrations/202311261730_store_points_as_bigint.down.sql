@@ -0,0 +1,13 @@
+begin transaction;
+
+alter table users
+    alter column current type integer,
+    alter column withdrawn type integer;
+
+alter table orders
+    alter column accrual type integer;
+
+alter table withdrawals
+    alter column sum type integer;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,13 @@
+begin transaction;
+
+alter table users
+    alter column current type bigint,
+    alter column withdrawn type bigint;
+
+alter table orders
+    alter column accrual type bigint;
+
+alter table withdrawals
+    alter column sum type bigint;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,11 @@
+begin transaction;
+
+drop table if exists ledger_entries;
+
+drop sequence if exists ledger_transaction_seq;
+
+drop type if exists ledger_account;
+
+drop type if exists ledger_entry_kind;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,37 @@
+begin transaction;
+
+create type ledger_entry_kind as enum ('ACCRUAL', 'WITHDRAWAL', 'REVERSAL', 'ADJUSTMENT');
+
+create type ledger_account as enum ('CURRENT', 'WITHDRAWN', 'PROGRAM', 'ADJUSTMENT');
+
+create sequence if not exists ledger_transaction_seq;
+
+create table if not exists ledger_entries (
+    entry_id bigserial primary key,
+    transaction_id bigint not null,
+    user_id bigint not null references users(user_id),
+    account ledger_account not null,
+    kind ledger_entry_kind not null,
+    amount bigint not null,
+    order_number varchar(255),
+    created_at timestamp not null
+);
+
+create index if not exists ledger_entries_user_id_idx on ledger_entries (user_id, entry_id);
+
+create index if not exists ledger_entries_transaction_id_idx on ledger_entries (transaction_id);
+
+-- Opening balances of existing users.
+with opening as (
+    select user_id, current, withdrawn, nextval('ledger_transaction_seq') as transaction_id
+    from users
+    where current <> 0 or withdrawn <> 0
+)
+insert into ledger_entries (transaction_id, user_id, account, kind, amount, created_at)
+select transaction_id, user_id, 'CURRENT'::ledger_account, 'ADJUSTMENT'::ledger_entry_kind, current, now() from opening
+union all
+select transaction_id, user_id, 'WITHDRAWN'::ledger_account, 'ADJUSTMENT'::ledger_entry_kind, withdrawn, now() from opening
+union all
+select transaction_id, user_id, 'ADJUSTMENT'::ledger_account, 'ADJUSTMENT'::ledger_entry_kind, -(current + withdrawn), now() from opening;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,15 @@
+begin transaction;
+
+drop index if exists withdrawals_user_id_idempotency_key_idx;
+
+drop index if exists withdrawals_user_id_order_number_idx;
+
+alter table withdrawals
+    drop column if exists idempotency_key;
+
+insert into withdrawals (user_id, order_number, sum, updated_at)
+select user_id, order_number, sum, updated_at from withdrawals_duplicates;
+
+drop table if exists withdrawals_duplicates;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,33 @@
+begin transaction;
+
+-- Earlier withdrawals could repeat an order. The first withdrawal of an order is kept,
+-- the repeated ones are moved to an archive, so the points spent on them stay accounted for.
+create table if not exists withdrawals_duplicates (
+    user_id bigint not null references users(user_id),
+    order_number varchar(255) not null,
+    sum bigint not null,
+    updated_at timestamp not null
+);
+
+with ranked as (
+    select ctid, row_number() over (partition by user_id, order_number order by updated_at, ctid) as n
+    from withdrawals
+), archived as (
+    delete from withdrawals w
+    using ranked r
+    where w.ctid = r.ctid and r.n > 1
+    returning w.user_id, w.order_number, w.sum, w.updated_at
+)
+insert into withdrawals_duplicates (user_id, order_number, sum, updated_at)
+select user_id, order_number, sum, updated_at from archived;
+
+alter table withdrawals
+    add column if not exists idempotency_key varchar(255);
+
+create unique index if not exists withdrawals_user_id_order_number_idx
+    on withdrawals (user_id, order_number);
+
+create unique index if not exists withdrawals_user_id_idempotency_key_idx
+    on withdrawals (user_id, idempotency_key) where idempotency_key is not null;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,7 @@
+begin transaction;
+
+drop table if exists revoked_tokens;
+
+drop table if exists sessions;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,22 @@
+begin transaction;
+
+create table if not exists sessions (
+    session_id varchar(64) primary key,
+    user_id bigint not null references users(user_id),
+    refresh_token_hash varchar(64) not null unique,
+    previous_token_hash varchar(64),
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    revoked_at timestamp
+);
+
+create index if not exists sessions_user_id_idx on sessions (user_id);
+
+create index if not exists sessions_previous_token_hash_idx on sessions (previous_token_hash);
+
+create table if not exists revoked_tokens (
+    jti varchar(64) primary key,
+    expires_at timestamp not null
+);
+
+commit;
\ No newline at end of file
@@ -0,0 +1,5 @@
+begin transaction;
+
+drop table if exists login_attempts;
+
+commit;
\ No newline at end of file
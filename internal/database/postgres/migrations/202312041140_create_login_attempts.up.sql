@@ -0,0 +1,12 @@
+begin transaction;
+
+create table if not exists login_attempts (
+    key varchar(320) primary key,
+    failures integer not null,
+    last_failure_at timestamp not null,
+    locked_until timestamp
+);
+
+create index if not exists login_attempts_last_failure_at_idx on login_attempts (last_failure_at);
+
+commit;
\ No newline at end of file
@@ -0,0 +1,6 @@
+begin transaction;
+
+-- Enum values can not be dropped, FORFEITURE is left in ledger_entry_kind.
+alter table users drop column if exists deleted_at;
+
+commit;
\ No newline at end of file
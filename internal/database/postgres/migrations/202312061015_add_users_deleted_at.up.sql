@@ -0,0 +1,7 @@
+begin transaction;
+
+alter table users add column if not exists deleted_at timestamp;
+
+alter type ledger_entry_kind add value if not exists 'FORFEITURE';
+
+commit;
\ No newline at end of file
@@ -0,0 +1,7 @@
+begin transaction;
+
+drop index if exists withdrawals_user_id_updated_at_idx;
+
+drop index if exists orders_user_id_created_at_idx;
+
+commit;
\ No newline at end of file
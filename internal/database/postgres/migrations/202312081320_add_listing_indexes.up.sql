@@ -0,0 +1,9 @@
+begin transaction;
+
+create index if not exists orders_user_id_created_at_idx
+    on orders (user_id, created_at, number);
+
+create index if not exists withdrawals_user_id_updated_at_idx
+    on withdrawals (user_id, updated_at, order_number);
+
+commit;
\ No newline at end of file
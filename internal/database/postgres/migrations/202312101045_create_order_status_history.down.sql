@@ -0,0 +1,5 @@
+begin transaction;
+
+drop table if exists order_status_history;
+
+commit;
\ No newline at end of file
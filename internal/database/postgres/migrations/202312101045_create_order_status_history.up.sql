@@ -0,0 +1,22 @@
+begin transaction;
+
+create table if not exists order_status_history (
+    history_id bigserial primary key,
+    order_number varchar(255) not null references orders(number) on delete cascade,
+    status order_status not null,
+    accrual bigint not null default 0,
+    accrual_response text,
+    created_at timestamp not null
+);
+
+create index if not exists order_status_history_order_number_idx
+    on order_status_history (order_number, history_id);
+
+-- History of existing orders: the upload and the current status.
+insert into order_status_history (order_number, status, created_at)
+select number, 'NEW'::order_status, created_at from orders;
+
+insert into order_status_history (order_number, status, accrual, created_at)
+select number, status, accrual, localtimestamp from orders where status <> 'NEW';
+
+commit;
\ No newline at end of file
@@ -0,0 +1,11 @@
+begin transaction;
+
+drop table if exists webhook_deliveries;
+
+drop type if exists webhook_delivery_status;
+
+drop table if exists webhook_outbox;
+
+drop table if exists webhooks;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,47 @@
+begin transaction;
+
+create table if not exists webhooks (
+    webhook_id bigserial primary key,
+    user_id bigint not null references users(user_id),
+    url varchar(2048) not null,
+    secret varchar(255) not null,
+    events varchar(1024) not null,
+    created_at timestamp not null
+);
+
+create index if not exists webhooks_user_id_idx on webhooks (user_id);
+
+create table if not exists webhook_outbox (
+    outbox_id bigserial primary key,
+    user_id bigint not null references users(user_id),
+    event_type varchar(64) not null,
+    payload text not null,
+    created_at timestamp not null,
+    dispatched_at timestamp
+);
+
+create index if not exists webhook_outbox_pending_idx
+    on webhook_outbox (outbox_id) where dispatched_at is null;
+
+create type webhook_delivery_status as enum ('PENDING', 'DELIVERED', 'FAILED');
+
+create table if not exists webhook_deliveries (
+    delivery_id bigserial primary key,
+    outbox_id bigint not null references webhook_outbox(outbox_id),
+    webhook_id bigint not null references webhooks(webhook_id) on delete cascade,
+    status webhook_delivery_status not null,
+    attempts integer not null default 0,
+    next_attempt_at timestamp not null,
+    response_status integer,
+    last_error text,
+    created_at timestamp not null,
+    delivered_at timestamp
+);
+
+create index if not exists webhook_deliveries_pending_idx
+    on webhook_deliveries (next_attempt_at) where status = 'PENDING';
+
+create index if not exists webhook_deliveries_webhook_id_idx
+    on webhook_deliveries (webhook_id, delivery_id);
+
+commit;
\ No newline at end of file
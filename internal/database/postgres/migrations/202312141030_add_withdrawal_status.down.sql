@@ -0,0 +1,13 @@
+begin transaction;
+
+drop index if exists withdrawals_pending_idx;
+
+alter table withdrawals
+    drop column if exists reversal_reason,
+    drop column if exists reversed_by,
+    drop column if exists reversed_at,
+    drop column if exists status;
+
+drop type if exists withdrawal_status;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,17 @@
+begin transaction;
+
+create type withdrawal_status as enum ('PENDING', 'COMPLETED', 'REVERSED');
+
+-- Existing withdrawals can no longer be reversed.
+alter table withdrawals
+    add column if not exists status withdrawal_status not null default 'COMPLETED',
+    add column if not exists reversed_at timestamp,
+    add column if not exists reversed_by varchar(255) not null default '',
+    add column if not exists reversal_reason text not null default '';
+
+alter table withdrawals
+    alter column status set default 'PENDING';
+
+create index if not exists withdrawals_pending_idx on withdrawals (updated_at) where status = 'PENDING';
+
+commit;
\ No newline at end of file
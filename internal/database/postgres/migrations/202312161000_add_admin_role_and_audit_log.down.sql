@@ -0,0 +1,7 @@
+begin transaction;
+
+drop table if exists admin_audit_log;
+
+alter table users drop column if exists role;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,18 @@
+begin transaction;
+
+alter table users add column if not exists role varchar(32) not null default 'user';
+
+create table if not exists admin_audit_log (
+    audit_id bigserial primary key,
+    admin_id bigint not null references users(user_id),
+    action varchar(64) not null,
+    user_id bigint references users(user_id),
+    order_number varchar(255),
+    amount bigint,
+    reason text not null default '',
+    created_at timestamp not null
+);
+
+create index if not exists admin_audit_log_user_id_idx on admin_audit_log (user_id, audit_id);
+
+commit;
\ No newline at end of file
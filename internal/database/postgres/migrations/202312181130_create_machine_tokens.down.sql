@@ -0,0 +1,5 @@
+begin transaction;
+
+drop table if exists machine_tokens;
+
+commit;
\ No newline at end of file
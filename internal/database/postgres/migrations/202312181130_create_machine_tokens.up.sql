@@ -0,0 +1,16 @@
+begin transaction;
+
+create table if not exists machine_tokens (
+    token_id varchar(64) primary key,
+    user_id bigint not null references users(user_id),
+    token_hash varchar(64) not null unique,
+    name varchar(255) not null,
+    scope varchar(1024) not null,
+    created_at timestamp not null,
+    expires_at timestamp not null,
+    revoked_at timestamp
+);
+
+create index if not exists machine_tokens_user_id_idx on machine_tokens (user_id, created_at);
+
+commit;
\ No newline at end of file
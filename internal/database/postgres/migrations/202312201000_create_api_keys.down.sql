@@ -0,0 +1,5 @@
+begin transaction;
+
+drop table if exists api_keys;
+
+commit;
\ No newline at end of file
@@ -0,0 +1,13 @@
+begin transaction;
+
+create table if not exists api_keys (
+    key_id bigserial primary key,
+    merchant varchar(255) not null,
+    prefix varchar(16) not null unique,
+    secret_hash varchar(64) not null,
+    created_at timestamp not null,
+    expires_at timestamp,
+    revoked_at timestamp
+);
+
+commit;
\ No newline at end of file
@@ -2,24 +2,33 @@ package postgres
 
 import (
 	"errors"
+	"github.com/XSAM/otelsql"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
 )
 
 const sourceURL = "file://internal/database/postgres/migrations"
 
 // NewConnection opens a connection to the database.
+// Every query is traced in a span of the context it is run with.
 // If connection fails, returns error.
 // If connection succeeds, returns nil.
 func NewConnection(dsn string) (*sqlx.DB, error) {
-	db, err := sqlx.Connect("pgx", dsn)
+	sqlDB, err := otelsql.Open("pgx", dsn, otelsql.WithAttributes(semconv.DBSystemPostgreSQL))
 	if err != nil {
 		return nil, err
 	}
 
+	db := sqlx.NewDb(sqlDB, "pgx")
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// openTestSchema connects to the database given by TEST_DATABASE_URI in a new schema,
+// which is dropped when the test ends. The test is skipped if TEST_DATABASE_URI is not set.
+func openTestSchema(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URI")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URI is not set")
+	}
+
+	admin, err := sqlx.Open("pgx", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	u, err := url.Parse(dsn)
+	require.NoError(t, err)
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+
+	db, err := sqlx.Open("pgx", u.String())
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestMigrations_withdrawalsIdempotency(t *testing.T) {
+	db := openTestSchema(t)
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	require.NoError(t, err)
+	m, err := migrate.NewWithDatabaseInstance("file://migrations", "postgres", driver)
+	require.NoError(t, err)
+
+	// The last version that allowed repeated withdrawals of an order.
+	require.NoError(t, m.Migrate(202311281420))
+
+	var userID int64
+	err = db.Get(&userID, `INSERT INTO users (login, password, current, withdrawn) VALUES ('gopher', 'hash', 0, 600)
+		RETURNING user_id`)
+	require.NoError(t, err)
+
+	at := time.Date(2023, 11, 29, 12, 0, 0, 0, time.UTC)
+	_, err = db.Exec(`INSERT INTO withdrawals (user_id, order_number, sum, updated_at) VALUES
+		($1, '79927398713', 200, $2), ($1, '79927398713', 100, $3), ($1, '4561261212345467', 300, $2)`,
+		userID, at.Add(time.Hour), at)
+	require.NoError(t, err)
+
+	require.NoError(t, m.Migrate(202311301105))
+
+	var sums []int64
+	require.NoError(t, db.Select(&sums, `SELECT sum FROM withdrawals ORDER BY order_number DESC`))
+	assert.Equal(t, []int64{100, 300}, sums, "the first withdrawal of an order is kept")
+
+	var archived []int64
+	require.NoError(t, db.Select(&archived, `SELECT sum FROM withdrawals_duplicates`))
+	assert.Equal(t, []int64{200}, archived, "the repeated withdrawal is archived")
+
+	_, err = db.Exec(`INSERT INTO withdrawals (user_id, order_number, sum, updated_at) VALUES ($1, '79927398713', 1, $2)`,
+		userID, at)
+	assert.Error(t, err, "an order can not be withdrawn twice")
+
+	require.NoError(t, m.Migrate(202311281420))
+
+	var count int
+	require.NoError(t, db.Get(&count, `SELECT count(*) FROM withdrawals`))
+	assert.Equal(t, 3, count, "the archived withdrawal is restored")
+}
@@ -0,0 +1,112 @@
+// Package metrics collects Prometheus metrics of the HTTP server, the accrual polling and the database
+// and exposes them on the /metrics endpoint of a separate metrics server.
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// namespace prefixes the names of all metrics.
+const namespace = "gophermart"
+
+// AccrualStatusError is the status label of accrual requests which got no response.
+const AccrualStatusError = "error"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served HTTP requests by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	accrualResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "responses_total",
+		Help:      "Number of accrual system responses by status, error if there was no response.",
+	}, []string{"status"})
+
+	accrualRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of requests to the accrual system.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	accrualPauses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "rate_limit_pauses_total",
+		Help:      "Number of polling pauses after 429 Too Many Requests responses of the accrual system.",
+	})
+
+	orderStatusChanges = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "orders",
+		Name:      "status_transitions_total",
+		Help:      "Number of order status transitions by the new status.",
+	}, []string{"status"})
+)
+
+// Handler returns the handler of the /metrics endpoint.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveHTTPRequest records a served HTTP request.
+// The route is the chi route pattern, so requests to one route with different parameters are counted together.
+func ObserveHTTPRequest(method, route string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveAccrualResponse records a request to the accrual system.
+// The status is the response status code or AccrualStatusError.
+func ObserveAccrualResponse(status string, d time.Duration) {
+	accrualResponses.WithLabelValues(status).Inc()
+	accrualRequestDuration.Observe(d.Seconds())
+}
+
+// AccrualPaused records a pause of the accrual polling after a 429 Too Many Requests response.
+func AccrualPaused() {
+	accrualPauses.Inc()
+}
+
+// OrderStatusChanged records a transition of an order to the new status.
+func OrderStatusChanged(status string) {
+	orderStatusChanges.WithLabelValues(status).Inc()
+}
+
+// RegisterAccrualQueue exposes the number of orders waiting in the accrual polling queue.
+func RegisterAccrualQueue(depth func() int) error {
+	return prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "queue_depth",
+		Help:      "Number of orders waiting in the accrual polling queue.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterDB exposes the connection pool stats of the database.
+func RegisterDB(db *sql.DB) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, namespace))
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Admin audit log actions.
+const (
+	AuditActionBalanceAdjustment = "balance.adjust"
+	AuditActionOrderRecheck      = "order.recheck"
+	AuditActionWithdrawalReverse = "withdrawal.reverse"
+)
+
+type (
+	// AdminUser is a user as seen by an administrator.
+	AdminUser struct {
+		UserID    int64      `json:"id" db:"user_id"`
+		Login     string     `json:"login" db:"login"`
+		Role      string     `json:"role" db:"role"`
+		Current   Points     `json:"current" db:"current"`
+		Withdrawn Points     `json:"withdrawn" db:"withdrawn"`
+		DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	}
+
+	// BalanceAdjustment is a manual change of the current balance of a user made by an administrator.
+	// A positive amount credits the user, a negative amount debits the user.
+	BalanceAdjustment struct {
+		AdminID int64     `json:"-"`
+		UserID  int64     `json:"-"`
+		Amount  Points    `json:"amount"`
+		Reason  string    `json:"reason"`
+		At      time.Time `json:"-"`
+	}
+
+	// RecheckRequest is a request body to recheck an order in the accrual system.
+	RecheckRequest struct {
+		Reason string `json:"reason"`
+	}
+
+	// AuditEntry is a record of an action of an administrator.
+	AuditEntry struct {
+		AuditID     int64     `json:"id" db:"audit_id"`
+		AdminID     int64     `json:"admin_id" db:"admin_id"`
+		Action      string    `json:"action" db:"action"`
+		UserID      *int64    `json:"user_id,omitempty" db:"user_id"`
+		OrderNumber string    `json:"order,omitempty" db:"order_number"`
+		Amount      Points    `json:"amount,omitempty" db:"amount"`
+		Reason      string    `json:"reason,omitempty" db:"reason"`
+		CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	}
+)
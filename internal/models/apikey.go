@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type (
+	// APIKey is a key of a merchant backend to submit orders on behalf of users.
+	// The key is sent as <prefix>.<secret>: the prefix is stored to look the key up,
+	// only the hash of the secret is stored. The key itself is shown only once, when it is issued.
+	APIKey struct {
+		KeyID      int64      `json:"id" db:"key_id"`
+		Merchant   string     `json:"merchant" db:"merchant"`
+		Prefix     string     `json:"prefix" db:"prefix"`
+		SecretHash string     `json:"-" db:"secret_hash"`
+		Key        string     `json:"key,omitempty" db:"-"`
+		CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+		ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+		RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	}
+
+	// APIKeyRequest is a request body to issue an API key.
+	// TTL is the lifetime of the key in seconds, the key does not expire if zero.
+	APIKeyRequest struct {
+		Merchant string `json:"merchant"`
+		TTL      int64  `json:"ttl"`
+	}
+
+	// MerchantOrder is a request body of a merchant to upload an order of a user.
+	MerchantOrder struct {
+		Login  string `json:"login"`
+		Number string `json:"order"`
+	}
+)
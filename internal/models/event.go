@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Event types of the user event stream.
+const (
+	// EventOrder carries an Order whose status changed.
+	EventOrder = "order"
+	// EventBalance carries the UserAccount after points were accrued.
+	EventBalance = "balance"
+)
+
+// Event is a change pushed to the event stream of a user.
+// Event ids grow monotonically, so a client can resume the stream
+// after the last event it received.
+type Event struct {
+	ID        uint64
+	UserID    int64
+	Type      string
+	Data      any
+	CreatedAt time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+type (
+	// JWKS is a JSON Web Key Set with the public keys used to verify tokens.
+	JWKS struct {
+		Keys []JWK `json:"keys"`
+	}
+
+	// JWK is a public JSON Web Key, RFC 7517.
+	// N and E are set for RSA keys, Crv and X for Ed25519 keys.
+	JWK struct {
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		N   string `json:"n,omitempty"`
+		E   string `json:"e,omitempty"`
+		Crv string `json:"crv,omitempty"`
+		X   string `json:"x,omitempty"`
+	}
+)
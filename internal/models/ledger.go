@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	LedgerKindAccrual    = "ACCRUAL"
+	LedgerKindWithdrawal = "WITHDRAWAL"
+	LedgerKindReversal   = "REVERSAL"
+	LedgerKindAdjustment = "ADJUSTMENT"
+	LedgerKindForfeiture = "FORFEITURE"
+)
+
+// Every user has a set of ledger accounts. Points are always moved
+// between two accounts, so amounts of every ledger transaction sum up to zero.
+const (
+	// LedgerAccountCurrent holds points available to the user.
+	LedgerAccountCurrent = "CURRENT"
+	// LedgerAccountWithdrawn holds points spent by the user.
+	LedgerAccountWithdrawn = "WITHDRAWN"
+	// LedgerAccountProgram is the source of points accrued by the loyalty program.
+	LedgerAccountProgram = "PROGRAM"
+	// LedgerAccountAdjustment is the source of manual adjustments and opening balances.
+	LedgerAccountAdjustment = "ADJUSTMENT"
+)
+
+type LedgerEntry struct {
+	EntryID       int64     `json:"-" db:"entry_id"`
+	TransactionID int64     `json:"transaction_id" db:"transaction_id"`
+	UserID        int64     `json:"-" db:"user_id"`
+	Account       string    `json:"account" db:"account"`
+	Kind          string    `json:"kind" db:"kind"`
+	Amount        Points    `json:"amount" db:"amount"`
+	OrderNumber   string    `json:"order,omitempty" db:"order_number"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
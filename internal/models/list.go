@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorSeparator separates the time and the key in an encoded cursor.
+const cursorSeparator = "|"
+
+type (
+	// ListQuery selects a page of a user's orders or withdrawals.
+	// Items are ordered by time, oldest first, and the page starts
+	// right after the Cursor position when it is set.
+	ListQuery struct {
+		UserID int64
+		Limit  int
+		Cursor *Cursor
+		// Statuses keeps only the items in one of the statuses, all items if empty.
+		Statuses []string
+		// From and To keep only the items within [From, To), either bound is optional.
+		From *time.Time
+		To   *time.Time
+	}
+
+	// Cursor is a position in a listing, the time and the key of the last item on a page.
+	Cursor struct {
+		Time time.Time
+		Key  string
+	}
+)
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c *Cursor) Encode() string {
+	raw := c.Time.UTC().Format(time.RFC3339Nano) + cursorSeparator + c.Key
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor returned by Cursor.Encode.
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	ts, key, ok := strings.Cut(string(raw), cursorSeparator)
+	if !ok || key == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{Time: t, Key: key}, nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCursor_Encode(t *testing.T) {
+	c := &Cursor{Time: time.Date(2023, 12, 8, 12, 30, 0, 123456000, time.UTC), Key: "12345678903"}
+
+	decoded, err := DecodeCursor(c.Encode())
+	require.NoError(t, err)
+	assert.True(t, c.Time.Equal(decoded.Time))
+	assert.Equal(t, c.Key, decoded.Key)
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!"},
+		{name: "no separator", cursor: "MjAyMy0xMi0wOFQxMjozMDowMFo"},
+		{name: "no key", cursor: "MjAyMy0xMi0wOFQxMjozMDowMFp8"},
+		{name: "invalid time", cursor: "eWVzdGVyZGF5fDE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeCursor(tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
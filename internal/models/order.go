@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 const (
 	OrderStatusNew        = "NEW"
@@ -10,25 +13,122 @@ const (
 	OrderStatusProcessed  = "PROCESSED"
 )
 
+// A withdrawal is pending until the reversal window is over, then it is completed.
+// A pending withdrawal may be reversed, returning the points to the user.
+const (
+	WithdrawalStatusPending   = "PENDING"
+	WithdrawalStatusCompleted = "COMPLETED"
+	WithdrawalStatusReversed  = "REVERSED"
+)
+
+// Results of an order in a batch upload.
+const (
+	OrderBatchAccepted     = "accepted"
+	OrderBatchDuplicateOwn = "duplicate-own"
+	OrderBatchConflict     = "conflict"
+	OrderBatchInvalid      = "invalid"
+)
+
 type (
 	Order struct {
 		UserID     int64     `json:"-" db:"user_id"`
 		Number     string    `json:"number" db:"number"`
 		Status     string    `json:"status" db:"status"`
-		Accrual    float64   `json:"accrual,omitempty" db:"accrual"`
+		Accrual    Points    `json:"accrual,omitempty" db:"accrual"`
 		UploadedAt time.Time `json:"uploaded_at" db:"created_at"`
 	}
 
+	// OrderStatusChange is an entry of the status history of an order.
+	// AccrualResponse is the accrual system response which caused the change,
+	// it is empty for the upload of the order.
+	OrderStatusChange struct {
+		OrderNumber     string    `json:"-" db:"order_number"`
+		Status          string    `json:"status" db:"status"`
+		Accrual         Points    `json:"accrual,omitempty" db:"accrual"`
+		AccrualResponse RawJSON   `json:"accrual_response,omitempty" db:"accrual_response"`
+		ChangedAt       time.Time `json:"changed_at" db:"created_at"`
+	}
+
+	// OrderDetail is an order with its status history, oldest change first.
+	OrderDetail struct {
+		Order
+		History []*OrderStatusChange `json:"history"`
+	}
+
+	// OrderBatchResult is the result of an order in a batch upload.
+	OrderBatchResult struct {
+		Number string `json:"number"`
+		Result string `json:"result"`
+	}
+
 	Withdrawal struct {
-		UserID      int64     `json:"-" db:"user_id"`
-		OrderNumber string    `json:"order" db:"order_number"`
-		Sum         float64   `json:"sum" db:"sum"`
-		ProcessedAt time.Time `json:"processed_at,omitempty" db:"updated_at"`
+		UserID         int64      `json:"-" db:"user_id"`
+		OrderNumber    string     `json:"order" db:"order_number"`
+		Sum            Points     `json:"sum" db:"sum"`
+		Status         string     `json:"status" db:"status"`
+		ProcessedAt    time.Time  `json:"processed_at,omitempty" db:"updated_at"`
+		ReversedAt     *time.Time `json:"reversed_at,omitempty" db:"reversed_at"`
+		ReversedBy     string     `json:"reversed_by,omitempty" db:"reversed_by"`
+		ReversalReason string     `json:"reversal_reason,omitempty" db:"reversal_reason"`
+		IdempotencyKey string     `json:"-" db:"idempotency_key"`
 	}
 
+	// WithdrawalReversal is a request to reverse a pending withdrawal of a user
+	// because the order it paid for was cancelled. Only administrators may reverse
+	// withdrawals, ReversedBy records which one did it.
+	WithdrawalReversal struct {
+		UserID      int64     `json:"-"`
+		OrderNumber string    `json:"order"`
+		Reason      string    `json:"reason"`
+		AdminID     int64     `json:"-"`
+		ReversedBy  string    `json:"-"`
+		At          time.Time `json:"-"`
+	}
+
+	AccrualTask struct {
+		OrderNumber   string     `db:"number"`
+		Attempts      int        `db:"accrual_attempts"`
+		NextAttemptAt time.Time  `db:"accrual_next_at"`
+		LastError     string     `db:"accrual_last_error"`
+		DeadAt        *time.Time `db:"accrual_dead_at"`
+	}
+
+	// AccrualResponse is the status of an order in the accrual system.
+	// AccrualTruncated reports that the accrual had more than two decimal places
+	// and was truncated to minor units.
 	AccrualResponse struct {
-		OrderNumber string  `json:"order"`
-		Status      string  `json:"status"`
-		Accrual     float64 `json:"accrual,omitempty"`
+		OrderNumber      string `json:"order"`
+		Status           string `json:"status"`
+		Accrual          Points `json:"accrual,omitempty"`
+		AccrualTruncated bool   `json:"-"`
 	}
 )
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Unlike points sent by users, the accrual is not rejected for its precision,
+// otherwise the order would fail on every poll.
+func (r *AccrualResponse) UnmarshalJSON(data []byte) error {
+	type response AccrualResponse
+	aux := struct {
+		*response
+		Accrual json.RawMessage `json:"accrual,omitempty"`
+	}{response: (*response)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	s, ok := jsonNumber(aux.Accrual)
+	if len(aux.Accrual) == 0 || !ok {
+		return nil
+	}
+
+	accrual, truncated, err := ParsePointsTruncated(s)
+	if err != nil {
+		return err
+	}
+
+	r.Accrual, r.AccrualTruncated = accrual, truncated
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pointsScale is the number of minor units in one point.
+const pointsScale = 100
+
+var (
+	// pointsPattern matches a decimal number of points with at most two decimal places.
+	// Fractions and exponents are rejected before parsing, big.Rat would accept both.
+	pointsPattern = regexp.MustCompile(`^-?\d+(\.\d{1,2})?$`)
+	// truncatedPointsPattern matches a decimal number of points with any number of decimal places.
+	truncatedPointsPattern = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+
+	ErrPointsFormat    = errors.New("invalid points format")
+	ErrPointsPrecision = errors.New("points must have at most two decimal places")
+)
+
+// Points is an amount of loyalty points stored exactly in minor units,
+// one hundredth of a point.
+// It is marshalled to JSON as a decimal number, e.g. 729.98,
+// and stored in the database as an integer number of minor units.
+type Points int64
+
+// ParsePoints parses a decimal number of points, e.g. "729.98" or "-50" for a debit.
+// Exponent notation and fractions are not accepted.
+// If the value has more than two decimal places, ErrPointsPrecision is returned.
+func ParsePoints(s string) (Points, error) {
+	s = strings.TrimSpace(s)
+	if !pointsPattern.MatchString(s) {
+		if truncatedPointsPattern.MatchString(s) {
+			return 0, ErrPointsPrecision
+		}
+		return 0, ErrPointsFormat
+	}
+
+	r, err := parseMinorUnits(s)
+	if err != nil {
+		return 0, err
+	}
+
+	return pointsFromInt(r.Num())
+}
+
+// ParsePointsTruncated parses a decimal number of points like ParsePoints,
+// but decimal places beyond minor units are truncated instead of rejected.
+// It reports whether the value was truncated.
+func ParsePointsTruncated(s string) (Points, bool, error) {
+	s = strings.TrimSpace(s)
+	if !truncatedPointsPattern.MatchString(s) {
+		return 0, false, ErrPointsFormat
+	}
+
+	r, err := parseMinorUnits(s)
+	if err != nil {
+		return 0, false, err
+	}
+
+	n := new(big.Int).Quo(r.Num(), r.Denom())
+	p, err := pointsFromInt(n)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return p, !r.IsInt(), nil
+}
+
+// parseMinorUnits parses a decimal number of points and scales it to minor units.
+// The number must be already checked against one of the points patterns.
+func parseMinorUnits(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, ErrPointsFormat
+	}
+
+	return r.Mul(r, big.NewRat(pointsScale, 1)), nil
+}
+
+func pointsFromInt(n *big.Int) (Points, error) {
+	if !n.IsInt64() {
+		return 0, ErrPointsFormat
+	}
+
+	return Points(n.Int64()), nil
+}
+
+// String returns points as a decimal number without trailing zeros, e.g. 500, 500.5, 729.98.
+func (p Points) String() string {
+	sign := ""
+	n := int64(p)
+	if n < 0 {
+		sign, n = "-", -n
+	}
+
+	whole, frac := n/pointsScale, n%pointsScale
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+
+	return strings.TrimRight(fmt.Sprintf("%s%d.%02d", sign, whole, frac), "0")
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Points) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+// Both numbers and strings holding a number are accepted.
+func (p *Points) UnmarshalJSON(data []byte) error {
+	s, ok := jsonNumber(data)
+	if !ok {
+		return nil
+	}
+
+	v, err := ParsePoints(s)
+	if err != nil {
+		return err
+	}
+
+	*p = v
+
+	return nil
+}
+
+// jsonNumber returns a JSON number or a string holding a number as text.
+// It returns false for null.
+func jsonNumber(data []byte) (string, bool) {
+	s := string(data)
+	if s == "null" {
+		return "", false
+	}
+
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	return s, true
+}
+
+// Scan implements sql.Scanner.
+func (p *Points) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*p = 0
+	case int64:
+		*p = Points(v)
+	case []byte:
+		return p.scanString(string(v))
+	case string:
+		return p.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Points", src)
+	}
+
+	return nil
+}
+
+func (p *Points) scanString(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*p = Points(n)
+
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (p Points) Value() (driver.Value, error) {
+	return int64(p), nil
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParsePoints(t *testing.T) {
+	type want struct {
+		points Points
+		err    error
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  want
+	}{
+		{
+			name:  "integer",
+			value: "500",
+			want:  want{points: 50000},
+		},
+		{
+			name:  "one decimal place",
+			value: "500.5",
+			want:  want{points: 50050},
+		},
+		{
+			name:  "two decimal places",
+			value: "729.98",
+			want:  want{points: 72998},
+		},
+		{
+			name:  "exponent",
+			value: "1.5e2",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "huge exponent",
+			value: "1e500000",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "fraction",
+			value: "1/4",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "negative",
+			value: "-0.01",
+			want:  want{points: -1},
+		},
+		{
+			name:  "plus sign",
+			value: "+5",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "double sign",
+			value: "--5",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "no integer part",
+			value: ".5",
+			want:  want{err: ErrPointsFormat},
+		},
+		{
+			name:  "too many decimal places",
+			value: "0.001",
+			want:  want{err: ErrPointsPrecision},
+		},
+		{
+			name:  "not a number",
+			value: "abc",
+			want:  want{err: ErrPointsFormat},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePoints(tt.value)
+			assert.Equal(t, tt.want.err, err)
+			assert.Equal(t, tt.want.points, got)
+		})
+	}
+}
+
+func TestParsePointsTruncated(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		wantPoints    Points
+		wantTruncated bool
+	}{
+		{name: "two decimal places", value: "729.98", wantPoints: 72998},
+		{name: "three decimal places", value: "500.129", wantPoints: 50012, wantTruncated: true},
+		{name: "negative", value: "-0.019", wantPoints: -1, wantTruncated: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated, err := ParsePointsTruncated(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPoints, got)
+			assert.Equal(t, tt.wantTruncated, truncated)
+		})
+	}
+
+	for _, value := range []string{"abc", "1/4", "1e500000", "+5"} {
+		_, _, err := ParsePointsTruncated(value)
+		assert.Equal(t, ErrPointsFormat, err, value)
+	}
+}
+
+func TestAccrualResponse_UnmarshalJSON(t *testing.T) {
+	resp := &AccrualResponse{}
+	err := json.Unmarshal([]byte(`{"order":"2030","status":"PROCESSED","accrual":500.123}`), resp)
+	require.NoError(t, err)
+	assert.Equal(t, &AccrualResponse{
+		OrderNumber:      "2030",
+		Status:           OrderStatusProcessed,
+		Accrual:          50012,
+		AccrualTruncated: true,
+	}, resp)
+
+	resp = &AccrualResponse{}
+	err = json.Unmarshal([]byte(`{"order":"2030","status":"REGISTERED"}`), resp)
+	require.NoError(t, err)
+	assert.Equal(t, &AccrualResponse{OrderNumber: "2030", Status: OrderStatusRegistered}, resp)
+
+	err = json.Unmarshal([]byte(`{"order":"2030","status":"PROCESSED","accrual":"abc"}`), resp)
+	assert.Equal(t, ErrPointsFormat, err)
+
+	// Points sent by users are still parsed strictly.
+	w := &Withdrawal{}
+	err = json.Unmarshal([]byte(`{"order":"2030","sum":500.123}`), w)
+	assert.Equal(t, ErrPointsPrecision, err)
+}
+
+func TestPoints_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		points Points
+		want   string
+	}{
+		{name: "zero", points: 0, want: "0"},
+		{name: "integer", points: 50000, want: "500"},
+		{name: "one decimal place", points: 50050, want: "500.5"},
+		{name: "two decimal places", points: 72998, want: "729.98"},
+		{name: "leading zero in fraction", points: 5, want: "0.05"},
+		{name: "negative", points: -150, want: "-1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.points.String())
+		})
+	}
+}
+
+func TestPoints_JSON(t *testing.T) {
+	account := &UserAccount{}
+	err := json.Unmarshal([]byte(`{"current":729.98,"withdrawn":"42"}`), account)
+	require.NoError(t, err)
+	assert.Equal(t, Points(72998), account.Current)
+	assert.Equal(t, Points(4200), account.Withdrawn)
+
+	w := &Withdrawal{}
+	err = json.Unmarshal([]byte(`{"order":"2377225624","sum":"1/4"}`), w)
+	assert.ErrorIs(t, err, ErrPointsFormat)
+
+	data, err := json.Marshal(account)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"current":729.98,"withdrawn":42}`, string(data))
+
+	order := &Order{Number: "2030", Status: OrderStatusNew}
+	data, err = json.Marshal(order)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "accrual")
+}
+
+func TestPoints_Scan(t *testing.T) {
+	var p Points
+	require.NoError(t, p.Scan(int64(72998)))
+	assert.Equal(t, Points(72998), p)
+
+	require.NoError(t, p.Scan([]byte("100")))
+	assert.Equal(t, Points(100), p)
+
+	require.NoError(t, p.Scan(nil))
+	assert.Equal(t, Points(0), p)
+
+	assert.Error(t, p.Scan(1.5))
+
+	v, err := Points(72998).Value()
+	require.NoError(t, err)
+	assert.Equal(t, int64(72998), v)
+}
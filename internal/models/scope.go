@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Token scopes. Every route group of the API requires one of them.
+const (
+	// ScopeAccount allows to manage the account: log out, change the password,
+	// delete the account and issue machine tokens.
+	ScopeAccount      = "account"
+	ScopeOrdersRead   = "orders:read"
+	ScopeOrdersWrite  = "orders:write"
+	ScopeBalanceRead  = "balance:read"
+	ScopeBalanceWrite = "balance:write"
+	ScopeWebhooks     = "webhooks"
+	ScopeAdmin        = "admin"
+)
+
+var (
+	// userScopes are the scopes of a plain user.
+	userScopes = []string{ScopeAccount, ScopeOrdersRead, ScopeOrdersWrite, ScopeBalanceRead, ScopeBalanceWrite, ScopeWebhooks}
+
+	// roleScopes are the scopes granted to the users of a role.
+	roleScopes = map[string][]string{
+		RoleUser:  userScopes,
+		RoleAdmin: append(slices.Clone(userScopes), ScopeAdmin),
+	}
+)
+
+// RoleScopes returns the scopes granted to the users of a role.
+// An empty role is a plain user, an unknown role has no scopes.
+func RoleScopes(role string) []string {
+	if role == "" {
+		role = RoleUser
+	}
+
+	return slices.Clone(roleScopes[role])
+}
+
+// ScopeList is a list of scopes stored and sent as a space-separated string,
+// as the scope claim of a token.
+type ScopeList []string
+
+// ParseScopeList splits a space-separated list of scopes.
+func ParseScopeList(s string) ScopeList {
+	return strings.Fields(s)
+}
+
+// String returns the scopes separated by spaces.
+func (l ScopeList) String() string {
+	return strings.Join(l, " ")
+}
+
+// Scan implements the sql.Scanner interface.
+func (l *ScopeList) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+	case []byte:
+		*l = ParseScopeList(string(v))
+	case string:
+		*l = ParseScopeList(v)
+	default:
+		return fmt.Errorf("cannot scan %T into ScopeList", src)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (l ScopeList) Value() (driver.Value, error) {
+	return l.String(), nil
+}
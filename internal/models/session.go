@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+type (
+	// Session is a login session of a user.
+	// Access tokens issued for the session carry its ID in the sid claim.
+	Session struct {
+		SessionID         string     `db:"session_id"`
+		UserID            int64      `db:"user_id"`
+		RefreshTokenHash  string     `db:"refresh_token_hash"`
+		PreviousTokenHash string     `db:"previous_token_hash"`
+		CreatedAt         time.Time  `db:"created_at"`
+		ExpiresAt         time.Time  `db:"expires_at"`
+		RevokedAt         *time.Time `db:"revoked_at"`
+		// Role is the current role of the session user.
+		Role string `db:"role"`
+	}
+
+	// TokenPair is a pair of tokens issued for a session.
+	TokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+
+	RefreshRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+)
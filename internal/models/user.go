@@ -1,22 +1,109 @@
 package models
 
-import "github.com/golang-jwt/jwt/v5"
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"slices"
+	"time"
+)
+
+// User roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type (
 	User struct {
 		UserID   int64  `json:"-" db:"user_id"`
 		Login    string `json:"login" db:"login"`
 		Password string `json:"password,omitempty" db:"password"`
+		Role     string `json:"-" db:"role"`
+	}
+
+	// PasswordChangeRequest is a request body to change the user password.
+	PasswordChangeRequest struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+
+	// AccountDeletionRequest is a request body to delete the user account.
+	AccountDeletionRequest struct {
+		Password string `json:"password"`
 	}
 
 	UserAccount struct {
-		UserID    int64   `json:"-" db:"user_id"`
-		Current   float64 `json:"current" db:"current"`
-		Withdrawn float64 `json:"withdrawn" db:"withdrawn"`
+		UserID    int64  `json:"-" db:"user_id"`
+		Current   Points `json:"current" db:"current"`
+		Withdrawn Points `json:"withdrawn" db:"withdrawn"`
 	}
 
 	CustomJWTClaims struct {
 		jwt.RegisteredClaims
-		UserID int64 `json:"user_id"`
+		UserID    int64  `json:"user_id"`
+		SessionID string `json:"sid,omitempty"`
+		// Role is the role of the user when the token was issued, a plain user if empty.
+		Role string `json:"role,omitempty"`
+		// Scope is a space-separated list of the scopes of the token.
+		// Tokens issued without it have all the scopes of the role.
+		Scope string `json:"scope,omitempty"`
+	}
+
+	// MachineToken is a long-lived access token of a user for a service, e.g. an analytics job,
+	// limited to the given scopes. The token itself is shown only once, when it is issued,
+	// only its hash is stored.
+	MachineToken struct {
+		TokenID   string     `json:"id" db:"token_id"`
+		UserID    int64      `json:"-" db:"user_id"`
+		TokenHash string     `json:"-" db:"token_hash"`
+		Name      string     `json:"name" db:"name"`
+		Scopes    ScopeList  `json:"scopes" db:"scope"`
+		Token     string     `json:"token,omitempty" db:"-"`
+		CreatedAt time.Time  `json:"created_at" db:"created_at"`
+		ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+		RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	}
+
+	// MachineTokenRequest is a request body to issue a machine token.
+	// TTL is the lifetime of the token in seconds, the maximum lifetime if zero.
+	MachineTokenRequest struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+		TTL    int64    `json:"ttl"`
+	}
+
+	// LoginAttempts is the state of recent failed log in attempts
+	// for a login or a client address.
+	LoginAttempts struct {
+		Key           string     `db:"key"`
+		Failures      int        `db:"failures"`
+		LastFailureAt time.Time  `db:"last_failure_at"`
+		LockedUntil   *time.Time `db:"locked_until"`
+	}
+
+	// PolicyViolation is a credentials policy rule a user failed.
+	PolicyViolation struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	// PolicyErrorResponse is a response body listing the failed credentials policy rules.
+	PolicyErrorResponse struct {
+		Error      string            `json:"error"`
+		Violations []PolicyViolation `json:"violations"`
 	}
 )
+
+// Scopes returns the scopes of the token.
+func (c *CustomJWTClaims) Scopes() []string {
+	if c.Scope == "" {
+		return RoleScopes(c.Role)
+	}
+
+	return ParseScopeList(c.Scope)
+}
+
+// HasScope reports whether the token has the scope.
+func (c *CustomJWTClaims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
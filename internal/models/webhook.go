@@ -0,0 +1,100 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Webhook event types.
+const (
+	WebhookEventOrderProcessed     = "order.processed"
+	WebhookEventOrderInvalid       = "order.invalid"
+	WebhookEventWithdrawal         = "withdrawal.created"
+	WebhookEventWithdrawalReversed = "withdrawal.reversed"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+type (
+	// Webhook is an endpoint of a user notified about the events it is subscribed to.
+	// The secret signs the payloads, it is shown only once, when the webhook is created.
+	Webhook struct {
+		WebhookID int64      `json:"id" db:"webhook_id"`
+		UserID    int64      `json:"-" db:"user_id"`
+		URL       string     `json:"url" db:"url"`
+		Secret    string     `json:"secret,omitempty" db:"secret"`
+		Events    EventTypes `json:"events" db:"events"`
+		CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	}
+
+	WebhookRequest struct {
+		URL    string   `json:"url"`
+		Events []string `json:"events"`
+	}
+
+	// WebhookDelivery is a delivery of an event to a webhook.
+	// The deliveries of a webhook form its delivery log.
+	WebhookDelivery struct {
+		DeliveryID     int64      `json:"id" db:"delivery_id"`
+		WebhookID      int64      `json:"webhook_id" db:"webhook_id"`
+		EventID        int64      `json:"event_id" db:"outbox_id"`
+		EventType      string     `json:"event" db:"event_type"`
+		Status         string     `json:"status" db:"status"`
+		Attempts       int        `json:"attempts" db:"attempts"`
+		ResponseStatus int        `json:"response_status,omitempty" db:"response_status"`
+		LastError      string     `json:"last_error,omitempty" db:"last_error"`
+		CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+		NextAttemptAt  time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+		DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+
+		// The endpoint and the event, loaded for sending.
+		URL     string    `json:"-" db:"url"`
+		Secret  string    `json:"-" db:"secret"`
+		Payload RawJSON   `json:"-" db:"payload"`
+		EventAt time.Time `json:"-" db:"event_at"`
+	}
+
+	// WebhookEnvelope is the body of a webhook request.
+	WebhookEnvelope struct {
+		ID        int64     `json:"id"`
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"created_at"`
+		Data      RawJSON   `json:"data"`
+	}
+)
+
+// EventTypes is a list of webhook event types stored in the database as comma separated text.
+type EventTypes []string
+
+// Scan implements sql.Scanner.
+func (e *EventTypes) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into EventTypes", src)
+	}
+
+	*e = nil
+	if s != "" {
+		*e = strings.Split(s, ",")
+	}
+
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (e EventTypes) Value() (driver.Value, error) {
+	return strings.Join(e, ","), nil
+}
@@ -4,106 +4,367 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/leonf08/gophermart.git/internal/metrics"
 	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"io"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
+const (
+	// accrualRequestTimeout limits a single request to the accrual system.
+	accrualRequestTimeout = 10 * time.Second
+
+	// accrualBackoffBase and accrualBackoffMax bound the pause after
+	// 429 Too Many Requests responses without a valid Retry-After header.
+	accrualBackoffBase = time.Second
+	accrualBackoffMax  = time.Minute
+)
+
+// AccrualConfig holds settings of the accrual service.
+type AccrualConfig struct {
+	// Address is the accrual system address.
+	Address string
+	// Workers is the number of orders polled concurrently.
+	Workers int
+	// RateLimit is the maximum number of requests per second
+	// sent to the accrual system by all workers, 0 means no limit.
+	RateLimit int
+	// PollInterval is a delay before an unresolved order is polled again.
+	// It doubles with every poll of an order still in progress
+	// and with every failed attempt up to MaxPollInterval.
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// MaxAttempts is the number of failed attempts, e.g. transport errors
+	// or 5xx responses, after which an order is moved to the dead-letter state,
+	// 0 means no limit. Polls of an order still in progress are not counted.
+	MaxAttempts int
+}
+
+// accrualTask is a queued order: its polling state and the span context
+// of the order upload, polling spans are linked to it. The span context is not stored,
+// the orders restored on start are not linked.
+// polls is the number of polls which found the order still in progress,
+// it is not stored either, so the back-off of a restored order starts over.
+type accrualTask struct {
+	*models.AccrualTask
+	upload trace.SpanContext
+	polls  int
+}
+
 // AccrualService is a service for working with the accrual system.
+// Polling state of every order is stored in the repository,
+// so pending orders survive restarts of the service.
 type AccrualService struct {
-	address  string
+	cfg      AccrualConfig
 	repo     AccrualRepo
+	events   Events
 	log      Logger
-	orderNum chan string
+	client   *http.Client
+	limiter  *rate.Limiter
+	throttle *throttle
+	queue    *delayQueue
+	wg       sync.WaitGroup
 }
 
-// NewAccrual creates a new accrual service.
-func NewAccrual(address string, repo AccrualRepo, log Logger) *AccrualService {
+// NewAccrual creates a new accrual service and starts its workers.
+// All orders which are not in a final status are reloaded from the repository.
+// Order status and balance changes are published to the events.
+// The workers are stopped when the given context is done.
+func NewAccrual(ctx context.Context, cfg AccrualConfig, repo AccrualRepo, events Events, log Logger) *AccrualService {
+	limit := rate.Inf
+	if cfg.RateLimit > 0 {
+		limit = rate.Limit(cfg.RateLimit)
+	}
+
 	a := &AccrualService{
-		address:  address,
+		cfg:      cfg,
 		repo:     repo,
+		events:   events,
 		log:      log,
-		orderNum: make(chan string, 10),
+		client:   &http.Client{Timeout: accrualRequestTimeout},
+		limiter:  rate.NewLimiter(limit, 1),
+		throttle: newThrottle(accrualBackoffBase, accrualBackoffMax),
+		queue:    newDelayQueue(),
 	}
 
-	go a.run(context.Background())
+	go a.restore(ctx)
+
+	a.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go a.run(ctx)
+	}
 
 	return a
 }
 
 // SendOrderAccrual sends an order number to the accrual system.
-func (a *AccrualService) SendOrderAccrual(orderNum string) {
-	a.orderNum <- orderNum
+// The spans of polling the order are linked to the span of the given context.
+func (a *AccrualService) SendOrderAccrual(ctx context.Context, orderNum string) {
+	a.queue.Push(&accrualTask{
+		AccrualTask: &models.AccrualTask{
+			OrderNumber:   orderNum,
+			NextAttemptAt: time.Now(),
+		},
+		upload: trace.SpanContextFromContext(ctx),
+	})
 }
 
-// Run starts the accrual service.
+// RecheckOrder polls the accrual system for an order right away.
+// Its polling state is reset, so an order in the dead-letter state is polled again.
+func (a *AccrualService) RecheckOrder(ctx context.Context, orderNum string) error {
+	task := &models.AccrualTask{
+		OrderNumber:   orderNum,
+		NextAttemptAt: time.Now(),
+	}
+
+	if err := a.repo.UpdateAccrualTask(ctx, task); err != nil {
+		return err
+	}
+
+	a.queue.Push(&accrualTask{AccrualTask: task, upload: trace.SpanContextFromContext(ctx)})
+
+	return nil
+}
+
+// QueueDepth returns the number of orders waiting to be polled.
+func (a *AccrualService) QueueDepth() int {
+	return a.queue.Len()
+}
+
+// Wait blocks until all workers are stopped.
+func (a *AccrualService) Wait() {
+	a.wg.Wait()
+}
+
+// restore loads pending orders from the repository and schedules them.
+func (a *AccrualService) restore(ctx context.Context) {
+	tasks, err := a.repo.GetPendingAccrualTasks(ctx)
+	if err != nil {
+		a.log.Error("accrual - restore - a.repo.GetPendingAccrualTasks", "error", err)
+		return
+	}
+
+	a.log.Info("accrual - restore", "pending", len(tasks))
+	for _, task := range tasks {
+		a.queue.Push(&accrualTask{AccrualTask: task})
+	}
+}
+
+// run is a worker which polls the accrual system for queued orders.
 func (a *AccrualService) run(ctx context.Context) {
+	defer a.wg.Done()
+
 	for {
-		orderNum := <-a.orderNum
-		if err := a.process(ctx, orderNum); err != nil {
-			a.log.Error("accrual - run - a.process", "error", err)
+		task, err := a.queue.Pop(ctx)
+		if err != nil {
+			return
+		}
+
+		if err = a.throttle.Wait(ctx); err != nil {
+			return
+		}
+
+		if err = a.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		if err = a.process(ctx, task); err != nil {
+			// Interrupted orders stay pending in the repository
+			// and are restored on the next start.
+			if ctx.Err() != nil {
+				return
+			}
+
+			a.log.Error("accrual - run - a.process", "order", task.OrderNumber, "error", err)
+			a.requeue(ctx, task, err)
 		}
 	}
 }
 
-func (a *AccrualService) process(ctx context.Context, orderNum string) error {
-	url := fmt.Sprintf("%s/api/orders/%s", a.address, orderNum)
-	resp, err := http.Get(url)
+// requeue saves polling state of an unresolved order and schedules its next attempt
+// with exponential back-off. A nil cause means the order is still in progress
+// in the accrual system, it is polled again without using up the attempts.
+// After MaxAttempts failed attempts the order is moved to the dead-letter state
+// and is not polled anymore.
+func (a *AccrualService) requeue(ctx context.Context, task *accrualTask, cause error) {
+	now := time.Now()
+
+	task.LastError = ""
+	if cause != nil {
+		task.Attempts++
+		task.LastError = cause.Error()
+		task.NextAttemptAt = now.Add(expBackoff(a.cfg.PollInterval, a.cfg.MaxPollInterval, task.Attempts))
+	} else {
+		task.polls++
+		task.NextAttemptAt = now.Add(expBackoff(a.cfg.PollInterval, a.cfg.MaxPollInterval, task.polls))
+	}
+
+	dead := cause != nil && a.cfg.MaxAttempts > 0 && task.Attempts >= a.cfg.MaxAttempts
+	if dead {
+		task.DeadAt = &now
+	}
+
+	if err := a.repo.UpdateAccrualTask(ctx, task.AccrualTask); err != nil {
+		a.log.Error("accrual - requeue - a.repo.UpdateAccrualTask", "order", task.OrderNumber, "error", err)
+	}
+
+	if dead {
+		a.log.Error("accrual - requeue - dead letter", "order", task.OrderNumber, "attempts", task.Attempts, "error", task.LastError)
+		return
+	}
+
+	a.queue.Push(task)
+}
+
+// process polls the accrual system for an order and applies its status.
+// Every poll is a new trace linked to the span of the order upload.
+func (a *AccrualService) process(ctx context.Context, task *accrualTask) (err error) {
+	orderNum := task.OrderNumber
+	ctx, span := tracing.Start(ctx, "AccrualService.process", trace.WithNewRoot(),
+		trace.WithLinks(trace.Link{SpanContext: task.upload}),
+		trace.WithAttributes(tracing.OrderNumber(orderNum), attribute.Int("accrual.attempts", task.Attempts)))
+	defer func() { tracing.End(span, err) }()
+
+	resp, body, err := a.fetch(ctx, orderNum)
 	if err != nil {
 		return err
 	}
 
-	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		a.throttle.Reset()
+	}
 
 	switch resp.StatusCode {
 	case http.StatusOK:
 		accrualResp := &models.AccrualResponse{}
-		if err = json.NewDecoder(resp.Body).Decode(accrualResp); err != nil {
+		if err = json.Unmarshal(body, accrualResp); err != nil {
 			return err
 		}
 
 		switch accrualResp.Status {
-		case models.OrderStatusInvalid:
-			if err = a.repo.UpdateOrder(ctx, &models.Order{
-				Number: orderNum,
-				Status: models.OrderStatusInvalid,
-			}); err != nil {
-				return err
-			}
-		case models.OrderStatusProcessed:
-			order, err := a.repo.GetOrderByNumber(ctx, orderNum)
-			if err != nil {
-				return err
-			}
-
-			order.Status, order.Accrual = models.OrderStatusProcessed, accrualResp.Accrual*100
-			if err = a.repo.UpdateOrder(ctx, order); err != nil {
-				return err
-			}
-		case models.OrderStatusProcessing:
-			if err = a.repo.UpdateOrder(ctx, &models.Order{
-				Number: orderNum,
-				Status: models.OrderStatusProcessing,
-			}); err != nil {
-				return err
-			}
+		case models.OrderStatusRegistered, models.OrderStatusProcessing,
+			models.OrderStatusInvalid, models.OrderStatusProcessed:
+		default:
+			return fmt.Errorf("unexpected accrual system order status %q", accrualResp.Status)
+		}
 
-			a.SendOrderAccrual(orderNum)
-		case models.OrderStatusRegistered:
-			a.SendOrderAccrual(orderNum)
+		if accrualResp.AccrualTruncated {
+			a.log.Warn("accrual - process - accrual truncated to minor units",
+				"order", orderNum, "accrual", accrualResp.Accrual, "response", string(body))
 		}
-	case http.StatusTooManyRequests:
-		pause, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+
+		// The status history is appended within the order update,
+		// so a failed update is retried with the change still pending.
+		var changed bool
+		changed, err = a.repo.UpdateOrderStatus(ctx, &models.OrderStatusChange{
+			OrderNumber:     orderNum,
+			Status:          accrualResp.Status,
+			Accrual:         accrualResp.Accrual,
+			AccrualResponse: body,
+			ChangedAt:       time.Now(),
+		})
 		if err != nil {
 			return err
 		}
 
-		time.Sleep(time.Duration(pause) * time.Second)
-		a.SendOrderAccrual(orderNum)
+		switch accrualResp.Status {
+		case models.OrderStatusProcessing:
+			a.requeue(ctx, task, nil)
+		case models.OrderStatusRegistered:
+			// Registration is only recorded in the status history,
+			// it does not change the status of the order for the user.
+			a.requeue(ctx, task, nil)
+			return nil
+		}
+
+		if changed {
+			metrics.OrderStatusChanged(accrualResp.Status)
+			a.notify(ctx, orderNum)
+		}
+	case http.StatusNoContent:
+		// The order is not registered in the accrual system yet.
+		a.requeue(ctx, task, nil)
+	case http.StatusTooManyRequests:
+		until := a.throttle.Pause(resp.Header.Get("Retry-After"))
+		metrics.AccrualPaused()
+		a.log.Info("accrual - process - too many requests", "order", orderNum, "paused_until", until)
+
+		// Rejected requests are not counted as attempts,
+		// the order is polled again as soon as the pause is over.
+		a.queue.Push(task)
 	case http.StatusInternalServerError:
 		return fmt.Errorf("accrual system internal error")
+	default:
+		return fmt.Errorf("unexpected accrual system response status %d", resp.StatusCode)
 	}
 
 	return nil
 }
+
+// fetch requests the status of an order from the accrual system in a client span
+// and propagates the trace context to it. The response body is read and closed.
+func (a *AccrualService) fetch(ctx context.Context, orderNum string) (_ *http.Response, _ []byte, err error) {
+	ctx, span := tracing.Start(ctx, "GET /api/orders/{number}", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPMethod(http.MethodGet), tracing.OrderNumber(orderNum)))
+	defer func() { tracing.End(span, err) }()
+
+	url := fmt.Sprintf("%s/api/orders/%s", a.cfg.Address, orderNum)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	t := time.Now()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		metrics.ObserveAccrualResponse(metrics.AccrualStatusError, time.Since(t))
+		return nil, nil, err
+	}
+
+	defer resp.Body.Close()
+	metrics.ObserveAccrualResponse(strconv.Itoa(resp.StatusCode), time.Since(t))
+	span.SetAttributes(semconv.HTTPStatusCode(resp.StatusCode))
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// notify publishes the new status of an order and,
+// if points were accrued for it, the new balance of its user.
+func (a *AccrualService) notify(ctx context.Context, orderNum string) {
+	order, err := a.repo.GetOrderByNumber(ctx, orderNum)
+	if err != nil {
+		a.log.Error("accrual - notify - a.repo.GetOrderByNumber", "order", orderNum, "error", err)
+		return
+	}
+
+	a.events.Publish(order.UserID, models.EventOrder, order)
+
+	if order.Status != models.OrderStatusProcessed || order.Accrual == 0 {
+		return
+	}
+
+	account, err := a.repo.GetUserAccount(ctx, order.UserID)
+	if err != nil {
+		a.log.Error("accrual - notify - a.repo.GetUserAccount", "order", orderNum, "error", err)
+		return
+	}
+
+	a.events.Publish(order.UserID, models.EventBalance, account)
+}
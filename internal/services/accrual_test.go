@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAccrual_restore(t *testing.T) {
+	next := time.Now().Add(time.Hour)
+	tests := []struct {
+		name      string
+		tasks     []*models.AccrualTask
+		err       error
+		wantDepth int
+	}{
+		{
+			name: "pending orders are queued",
+			tasks: []*models.AccrualTask{
+				{OrderNumber: "79927398713", Attempts: 2, NextAttemptAt: next},
+				{OrderNumber: "12345678903", NextAttemptAt: next.Add(time.Minute)},
+			},
+			wantDepth: 2,
+		},
+		{
+			name:      "no pending orders",
+			wantDepth: 0,
+		},
+		{
+			name:      "repository error",
+			err:       errors.New("connection refused"),
+			wantDepth: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restored := make(chan struct{})
+			repo := mocks.NewAccrualRepo(t)
+			repo.On("GetPendingAccrualTasks", mock.Anything).
+				Run(func(mock.Arguments) { close(restored) }).
+				Return(tt.tasks, tt.err).Once()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// No workers are started, restored orders stay in the queue.
+			a := NewAccrual(ctx, AccrualConfig{}, repo, nil, &nopLogger{})
+			<-restored
+
+			require.Eventually(t, func() bool { return a.QueueDepth() == tt.wantDepth }, time.Second, 10*time.Millisecond)
+			for _, task := range tt.tasks {
+				assert.Same(t, task, a.queue.index[task.OrderNumber].task.AccrualTask)
+			}
+		})
+	}
+}
+
+func TestAccrualService_fetch(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		assert.Equal(t, "/api/orders/79927398713", r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order":"79927398713","status":"PROCESSED","accrual":500}`))
+	}))
+	defer server.Close()
+
+	a := &AccrualService{
+		cfg:    AccrualConfig{Address: server.URL},
+		client: server.Client(),
+	}
+
+	// The remote span stands for the trace the order is polled in.
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+
+	resp, body, err := a.fetch(ctx, "79927398713")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"order":"79927398713","status":"PROCESSED","accrual":500}`, string(body))
+	assert.Contains(t, traceparent, "4bf92f3577b34da6a3ce929d0e0e4736")
+}
+
+func TestAccrualService_process(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"order":"79927398713","status":"PROCESSED","accrual":500}`))
+	}))
+	defer server.Close()
+
+	repo := mocks.NewAccrualRepo(t)
+	repo.On("UpdateOrderStatus", mock.Anything, mock.MatchedBy(func(c *models.OrderStatusChange) bool {
+		return c.OrderNumber == "79927398713" && c.Status == models.OrderStatusProcessed && c.Accrual == 50000
+	})).Return(false, errors.New("connection reset")).Once()
+	repo.On("UpdateOrderStatus", mock.Anything, mock.Anything).Return(true, nil).Once()
+	repo.On("GetOrderByNumber", mock.Anything, "79927398713").
+		Return(&models.Order{UserID: 1, Number: "79927398713", Status: models.OrderStatusProcessed, Accrual: 50000}, nil).Once()
+	repo.On("GetUserAccount", mock.Anything, int64(1)).
+		Return(&models.UserAccount{Current: 50000}, nil).Once()
+
+	events := &recordingEvents{}
+	a := &AccrualService{
+		cfg:      AccrualConfig{Address: server.URL},
+		repo:     repo,
+		events:   events,
+		log:      &nopLogger{},
+		client:   server.Client(),
+		throttle: newThrottle(accrualBackoffBase, accrualBackoffMax),
+		queue:    newDelayQueue(),
+	}
+	task := &accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "79927398713"}}
+
+	// The failed update is retried and the change is still published.
+	require.Error(t, a.process(context.Background(), task))
+	assert.Empty(t, events.published)
+
+	require.NoError(t, a.process(context.Background(), task))
+	if assert.Len(t, events.published, 2) {
+		assert.Equal(t, models.EventOrder, events.published[0].Type)
+		assert.Equal(t, models.EventBalance, events.published[1].Type)
+	}
+}
+
+func TestAccrualService_requeue(t *testing.T) {
+	repo := mocks.NewAccrualRepo(t)
+	repo.On("UpdateAccrualTask", mock.Anything, mock.Anything).Return(nil)
+
+	a := &AccrualService{
+		cfg:   AccrualConfig{PollInterval: time.Second, MaxPollInterval: time.Minute, MaxAttempts: 2},
+		repo:  repo,
+		log:   &nopLogger{},
+		queue: newDelayQueue(),
+	}
+	task := &accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "79927398713"}}
+
+	// Polls of an order still in progress back off without using up the attempts.
+	for i := 0; i < 5; i++ {
+		a.requeue(context.Background(), task, nil)
+	}
+	assert.Equal(t, 0, task.Attempts)
+	assert.Nil(t, task.DeadAt)
+	assert.WithinDuration(t, time.Now().Add(16*time.Second), task.NextAttemptAt, time.Second)
+	assert.Equal(t, 1, a.queue.Len())
+
+	a.requeue(context.Background(), task, errors.New("accrual system internal error"))
+	assert.Equal(t, 1, task.Attempts)
+	assert.Equal(t, "accrual system internal error", task.LastError)
+	assert.Nil(t, task.DeadAt)
+
+	// The order is moved to the dead-letter state after MaxAttempts failed attempts.
+	a.requeue(context.Background(), task, errors.New("connection refused"))
+	assert.Equal(t, 2, task.Attempts)
+	assert.NotNil(t, task.DeadAt)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/utils"
+	"strings"
+	"time"
+)
+
+// AdminManager is a service for the operator tasks of administrators.
+// Every change made by an administrator is recorded in the audit log.
+type AdminManager struct {
+	repo    AdminRepo
+	accrual Accrual
+	events  Events
+}
+
+// NewAdminManager creates a new admin manager.
+func NewAdminManager(repo AdminRepo, accrual Accrual, events Events) *AdminManager {
+	return &AdminManager{
+		repo:    repo,
+		accrual: accrual,
+		events:  events,
+	}
+}
+
+// SearchUsers returns the users whose login contains the given substring, ordered by login.
+// An empty login matches all users. The limit is DefaultListLimit if zero.
+func (a *AdminManager) SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error) {
+	limit, err := checkLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.repo.SearchUsers(ctx, login, limit)
+}
+
+// GetUser returns a user with the balance, deleted users included.
+// If the user does not exist, ErrUserNotFound is returned.
+func (a *AdminManager) GetUser(ctx context.Context, userID int64) (*models.AdminUser, error) {
+	return a.repo.GetAdminUser(ctx, userID)
+}
+
+// GetOrder returns an order of any user with its status history.
+// If the order does not exist, ErrOrderNotFound is returned.
+func (a *AdminManager) GetOrder(ctx context.Context, orderNum string) (*models.OrderDetail, error) {
+	if !utils.IsNumber(orderNum) {
+		return nil, ErrInvalidOrderNumber
+	}
+
+	order, err := a.repo.GetOrderByNumber(ctx, orderNum)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := a.repo.GetOrderStatusHistory(ctx, orderNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OrderDetail{
+		Order:   *order,
+		History: history,
+	}, nil
+}
+
+// RecheckOrder makes the accrual service poll the accrual system for an order right away,
+// even if the order was moved to the dead-letter state, and records it in the audit log.
+// If the order does not exist, ErrOrderNotFound is returned.
+// If the order is already processed or invalid, ErrOrderFinal is returned.
+func (a *AdminManager) RecheckOrder(ctx context.Context, adminID int64, orderNum, reason string) error {
+	if !utils.IsNumber(orderNum) {
+		return ErrInvalidOrderNumber
+	}
+
+	order, err := a.repo.GetOrderByNumber(ctx, orderNum)
+	if err != nil {
+		return err
+	}
+
+	if order.Status == models.OrderStatusProcessed || order.Status == models.OrderStatusInvalid {
+		return ErrOrderFinal
+	}
+
+	if err = a.accrual.RecheckOrder(ctx, orderNum); err != nil {
+		return err
+	}
+
+	userID := order.UserID
+	return a.repo.AddAuditEntry(ctx, &models.AuditEntry{
+		AdminID:     adminID,
+		Action:      models.AuditActionOrderRecheck,
+		UserID:      &userID,
+		OrderNumber: orderNum,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// AdjustBalance credits or debits the current balance of a user.
+// The adjustment is posted to the ledger and recorded in the audit log,
+// so it must have a reason. The new balance is published to the user.
+// If a debit exceeds the current balance, ErrInsufficientFunds is returned.
+func (a *AdminManager) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error) {
+	if adj.Amount == 0 {
+		return nil, ErrInvalidAdjustment
+	}
+
+	if adj.Reason == "" {
+		return nil, ErrAdjustmentReason
+	}
+
+	adj.At = time.Now()
+	account, err := a.repo.AdjustBalance(ctx, adj)
+	if err != nil {
+		return nil, err
+	}
+
+	a.events.Publish(adj.UserID, models.EventBalance, account)
+
+	return account, nil
+}
+
+// ReverseWithdrawal reverses a pending withdrawal of a user, e.g. when the order
+// it paid for was cancelled, and records it in the audit log, so it must have a reason.
+// If the withdrawal does not exist, ErrWithdrawalNotFound is returned.
+// If the withdrawal is already completed or reversed, ErrWithdrawalNotReversible is returned.
+func (a *AdminManager) ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error) {
+	if !utils.IsNumber(rev.OrderNumber) {
+		return nil, ErrInvalidOrderNumber
+	}
+
+	if strings.TrimSpace(rev.Reason) == "" {
+		return nil, ErrReversalReason
+	}
+
+	rev.ReversedBy = fmt.Sprintf("admin:%d", rev.AdminID)
+	rev.At = time.Now()
+
+	return a.repo.ReverseWithdrawal(ctx, rev)
+}
+
+// GetAuditLog returns the latest entries of the audit log, newest first.
+// If userID is not zero, only the entries about the user are returned.
+// The limit is DefaultListLimit if zero.
+func (a *AdminManager) GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error) {
+	limit, err := checkLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.repo.GetAuditLog(ctx, userID, limit)
+}
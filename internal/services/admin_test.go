@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// recordingEvents records the published events.
+type recordingEvents struct {
+	published []*models.Event
+}
+
+func (e *recordingEvents) Publish(userID int64, eventType string, data any) {
+	e.published = append(e.published, &models.Event{UserID: userID, Type: eventType, Data: data})
+}
+
+func (e *recordingEvents) Subscribe(_ int64, _ uint64) *Subscription {
+	return nil
+}
+
+// recheckAccrual records the rechecked orders.
+type recheckAccrual struct {
+	mockAccrual
+	rechecked []string
+}
+
+func (a *recheckAccrual) RecheckOrder(_ context.Context, orderNum string) error {
+	a.rechecked = append(a.rechecked, orderNum)
+	return nil
+}
+
+func TestAdminManager_RecheckOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		orderNum string
+		status   string
+		wantErr  error
+	}{
+		{
+			name:     "recheck dead-lettered order",
+			orderNum: "79927398713",
+			status:   models.OrderStatusProcessing,
+		},
+		{
+			name:     "invalid order number",
+			orderNum: "7992abc",
+			wantErr:  ErrInvalidOrderNumber,
+		},
+		{
+			name:     "order not found",
+			orderNum: "12345678903",
+			wantErr:  ErrOrderNotFound,
+		},
+		{
+			name:     "order in a final status",
+			orderNum: "4561261212345467",
+			status:   models.OrderStatusProcessed,
+			wantErr:  ErrOrderFinal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewAdminRepo(t)
+			accrual := &recheckAccrual{}
+
+			switch {
+			case tt.wantErr == ErrOrderNotFound:
+				repo.On("GetOrderByNumber", mock.Anything, tt.orderNum).Return(nil, ErrOrderNotFound)
+			case tt.status != "":
+				repo.
+					On("GetOrderByNumber", mock.Anything, tt.orderNum).
+					Return(&models.Order{UserID: 2, Number: tt.orderNum, Status: tt.status}, nil)
+			}
+
+			if tt.wantErr == nil {
+				repo.
+					On("AddAuditEntry", mock.Anything, mock.MatchedBy(func(e *models.AuditEntry) bool {
+						return e.AdminID == 1 && e.Action == models.AuditActionOrderRecheck &&
+							*e.UserID == 2 && e.OrderNumber == tt.orderNum && e.Reason == "stuck"
+					})).
+					Return(nil).
+					Once()
+			}
+
+			a := NewAdminManager(repo, accrual, &recordingEvents{})
+			err := a.RecheckOrder(context.Background(), 1, tt.orderNum, "stuck")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, accrual.rechecked)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.orderNum}, accrual.rechecked)
+		})
+	}
+}
+
+func TestAdminManager_AdjustBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		adj     *models.BalanceAdjustment
+		wantErr error
+	}{
+		{
+			name: "credit",
+			adj:  &models.BalanceAdjustment{AdminID: 1, UserID: 2, Amount: 10000, Reason: "compensation"},
+		},
+		{
+			name: "debit",
+			adj:  &models.BalanceAdjustment{AdminID: 1, UserID: 2, Amount: -5000, Reason: "duplicate accrual"},
+		},
+		{
+			name:    "zero amount",
+			adj:     &models.BalanceAdjustment{AdminID: 1, UserID: 2, Reason: "nothing"},
+			wantErr: ErrInvalidAdjustment,
+		},
+		{
+			name:    "no reason",
+			adj:     &models.BalanceAdjustment{AdminID: 1, UserID: 2, Amount: 10000},
+			wantErr: ErrAdjustmentReason,
+		},
+		{
+			name:    "debit over the balance",
+			adj:     &models.BalanceAdjustment{AdminID: 1, UserID: 2, Amount: -1000000, Reason: "fraud"},
+			wantErr: ErrInsufficientFunds,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewAdminRepo(t)
+			events := &recordingEvents{}
+
+			account := &models.UserAccount{UserID: 2, Current: 50000 + tt.adj.Amount}
+			switch {
+			case tt.wantErr == ErrInsufficientFunds:
+				repo.On("AdjustBalance", mock.Anything, tt.adj).Return(nil, ErrInsufficientFunds)
+			case tt.wantErr == nil:
+				repo.On("AdjustBalance", mock.Anything, tt.adj).Return(account, nil)
+			}
+
+			a := NewAdminManager(repo, &mockAccrual{}, events)
+			got, err := a.AdjustBalance(context.Background(), tt.adj)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, events.published)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, account, got)
+			assert.False(t, tt.adj.At.IsZero())
+			if assert.Len(t, events.published, 1) {
+				assert.Equal(t, models.EventBalance, events.published[0].Type)
+				assert.Equal(t, int64(2), events.published[0].UserID)
+			}
+		})
+	}
+}
+
+func TestAdminManager_ReverseWithdrawal(t *testing.T) {
+	tests := []struct {
+		name    string
+		rev     *models.WithdrawalReversal
+		repoErr error
+		wantErr error
+	}{
+		{
+			name: "reversed",
+			rev:  &models.WithdrawalReversal{AdminID: 1, UserID: 2, OrderNumber: "79927398713", Reason: "order cancelled"},
+		},
+		{
+			name:    "no reason",
+			rev:     &models.WithdrawalReversal{AdminID: 1, UserID: 2, OrderNumber: "79927398713", Reason: " "},
+			wantErr: ErrReversalReason,
+		},
+		{
+			name:    "invalid order number",
+			rev:     &models.WithdrawalReversal{AdminID: 1, UserID: 2, OrderNumber: "7992abc", Reason: "order cancelled"},
+			wantErr: ErrInvalidOrderNumber,
+		},
+		{
+			name:    "completed withdrawal",
+			rev:     &models.WithdrawalReversal{AdminID: 1, UserID: 2, OrderNumber: "79927398713", Reason: "order cancelled"},
+			repoErr: ErrWithdrawalNotReversible,
+			wantErr: ErrWithdrawalNotReversible,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewAdminRepo(t)
+
+			withdrawal := &models.Withdrawal{UserID: 2, OrderNumber: "79927398713", Status: models.WithdrawalStatusReversed}
+			if tt.wantErr == nil || tt.repoErr != nil {
+				repo.
+					On("ReverseWithdrawal", mock.Anything, tt.rev).
+					Return(func(_ context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error) {
+						if tt.repoErr != nil {
+							return nil, tt.repoErr
+						}
+
+						withdrawal.ReversedBy = rev.ReversedBy
+						withdrawal.ReversalReason = rev.Reason
+
+						return withdrawal, nil
+					})
+			}
+
+			a := NewAdminManager(repo, &mockAccrual{}, &recordingEvents{})
+			got, err := a.ReverseWithdrawal(context.Background(), tt.rev)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "admin:1", got.ReversedBy)
+			assert.Equal(t, "order cancelled", got.ReversalReason)
+			assert.False(t, tt.rev.At.IsZero())
+		})
+	}
+}
+
+func TestAdminManager_SearchUsers(t *testing.T) {
+	repo := mocks.NewAdminRepo(t)
+	repo.
+		On("SearchUsers", mock.Anything, "goph", DefaultListLimit).
+		Return([]*models.AdminUser{{UserID: 1, Login: "gopher", Role: models.RoleUser}}, nil).
+		Once()
+
+	a := NewAdminManager(repo, &mockAccrual{}, &recordingEvents{})
+
+	users, err := a.SearchUsers(context.Background(), "goph", 0)
+	require.NoError(t, err)
+	assert.Len(t, users, 1)
+
+	_, err = a.SearchUsers(context.Background(), "goph", MaxListLimit+1)
+	assert.ErrorIs(t, err, ErrInvalidListLimit)
+}
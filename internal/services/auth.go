@@ -8,56 +8,89 @@ import (
 )
 
 type AuthenticatorService struct {
-	key string
+	keys *KeySet
+	ttl  time.Duration
+	cost int
 }
 
-func NewAuthenticator(key string) *AuthenticatorService {
+func NewAuthenticator(keys *KeySet, ttl time.Duration, cost int) *AuthenticatorService {
 	return &AuthenticatorService{
-		key: key,
+		keys: keys,
+		ttl:  ttl,
+		cost: cost,
 	}
 }
 
-// GenerateToken generates a JWT token for a given user
-// and returns it as a string.
-// The token is signed with HMAC-SHA256 algorithm.
+// GenerateToken generates a JWT access token for a given user and session
+// and returns it as a string together with its expiration time.
+// The token is signed with the active key of the key set,
+// the key id is put into the kid header.
 // The token contains the following claims:
 // - issuer: "gophermart"
-// - expiration time: 24 hours
+// - jti: unique token id, used to revoke the token
+// - expiration time: token ttl provided to the AuthenticatorService
 // - issued at: current time
-// - login: user login
-// The token is signed with the key provided to the AuthenticatorService.
+// - user_id: user id
+// - sid: session id
+// - role: user role, omitted for plain users
+// - scope: all scopes of the user role
 // If the token generation fails, an error is returned.
-// If the token generation succeeds, the token is returned.
-func (a *AuthenticatorService) GenerateToken(user *models.User) (string, error) {
+func (a *AuthenticatorService) GenerateToken(user *models.User, sessionID string) (string, time.Time, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", time.Time{}, ErrGenerateToken
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(a.ttl)
 	claims := &models.CustomJWTClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			Issuer:    "gophermart",
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
-		UserID: user.UserID,
+		UserID:    user.UserID,
+		SessionID: sessionID,
+		Scope:     models.ScopeList(models.RoleScopes(user.Role)).String(),
+	}
+	if user.Role != models.RoleUser {
+		claims.Role = user.Role
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(a.key))
+	key := a.keys.signing()
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	signedToken, err := token.SignedString(key.private)
 	if err != nil {
-		return "", ErrGenerateToken
+		return "", time.Time{}, ErrGenerateToken
 	}
 
-	return signedToken, nil
+	return signedToken, expiresAt, nil
 }
 
 // ValidateTokenAndExtractClaims validates a JWT token
 // and returns the claims if the token is valid.
-// The token is signed with HMAC-SHA256 algorithm.
+// The token must be signed by a key of the key set identified by the kid header,
+// with the algorithm of that key.
 // If the token validation fails, an error is returned.
 // If the token validation succeeds, the claims are returned.
 func (a *AuthenticatorService) ValidateTokenAndExtractClaims(token string) (*models.CustomJWTClaims, error) {
 	claims := &models.CustomJWTClaims{}
 
 	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(a.key), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+		kid, _ := token.Header["kid"].(string)
+		key, ok := a.keys.lookup(kid)
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		return key.private.Public(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -66,8 +99,15 @@ func (a *AuthenticatorService) ValidateTokenAndExtractClaims(token string) (*mod
 	return claims, nil
 }
 
+// JWKS returns the public keys which can be used to verify the tokens.
+func (a *AuthenticatorService) JWKS() models.JWKS {
+	return a.keys.JWKS()
+}
+
+// GenerateHashFromPassword hashes the user password with bcrypt
+// using the cost provided to the AuthenticatorService.
 func (a *AuthenticatorService) GenerateHashFromPassword(user *models.User) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), a.cost)
 	if err != nil {
 		return "", ErrGenerateHashFromPassword
 	}
@@ -9,6 +9,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestAuthenticatorService_CheckPasswordHash(t *testing.T) {
@@ -94,26 +95,42 @@ func TestAuthenticatorService_GenerateHashFromPassword(t *testing.T) {
 }
 
 func TestAuthenticatorService_GenerateToken(t *testing.T) {
-	type fields struct {
-		key string
-	}
 	type args struct {
 		user *models.User
 	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr assert.ErrorAssertionFunc
+		name      string
+		algorithm string
+		args      args
+		wantErr   assert.ErrorAssertionFunc
 	}{
 		{
-			name: "generate token, no error",
-			fields: fields{
-				key: "key",
+			name:      "generate token, RS256",
+			algorithm: "RS256",
+			args: args{
+				user: &models.User{
+					UserID: 1,
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "generate token, EdDSA",
+			algorithm: "EdDSA",
+			args: args{
+				user: &models.User{
+					UserID: 1,
+				},
 			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:      "generate token, admin role",
+			algorithm: "EdDSA",
 			args: args{
 				user: &models.User{
 					UserID: 1,
+					Role:   models.RoleAdmin,
 				},
 			},
 			wantErr: assert.NoError,
@@ -121,72 +138,87 @@ func TestAuthenticatorService_GenerateToken(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &AuthenticatorService{
-				key: tt.fields.key,
-			}
-			_, err := a.GenerateToken(tt.args.user)
+			keys := newTestKeySet(t, KeySetConfig{Algorithm: tt.algorithm, GracePeriod: time.Hour})
+			a := NewAuthenticator(keys, time.Minute, bcrypt.MinCost)
+
+			token, expiresAt, err := a.GenerateToken(tt.args.user, "session")
 			if !tt.wantErr(t, err, fmt.Sprintf("GenerateToken(%v)", tt.args.user)) {
 				return
 			}
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &models.CustomJWTClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.algorithm, parsed.Method.Alg())
+			assert.Equal(t, keys.signing().kid, parsed.Header["kid"])
+
+			claims, err := a.ValidateTokenAndExtractClaims(token)
+			require.NoError(t, err)
+			assert.Equal(t, tt.args.user.UserID, claims.UserID)
+			assert.Equal(t, "session", claims.SessionID)
+			assert.Equal(t, tt.args.user.Role, claims.Role)
+			assert.NotEmpty(t, claims.ID)
+			assert.WithinDuration(t, expiresAt, claims.ExpiresAt.Time, time.Second)
 		})
 	}
 }
 
 func TestAuthenticatorService_ValidateTokenAndExtractClaims(t *testing.T) {
-	claims := &models.CustomJWTClaims{
-		UserID: 1,
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	keys := newTestKeySet(t, KeySetConfig{Algorithm: "EdDSA", GracePeriod: time.Hour})
+	a := NewAuthenticator(keys, time.Minute, bcrypt.MinCost)
+
+	token, _, err := a.GenerateToken(&models.User{UserID: 1}, "session")
+	require.NoError(t, err)
+
+	otherKeys := newTestKeySet(t, KeySetConfig{Algorithm: "EdDSA", GracePeriod: time.Hour})
+	otherToken, _, err := NewAuthenticator(otherKeys, time.Minute, bcrypt.MinCost).GenerateToken(&models.User{UserID: 1}, "session")
+	require.NoError(t, err)
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, &models.CustomJWTClaims{UserID: 1})
+	hmacToken.Header["kid"] = keys.signing().kid
+	hmacSigned, err := hmacToken.SignedString([]byte("key"))
+	require.NoError(t, err)
 
-	type fields struct {
-		key string
-	}
 	tests := []struct {
 		name    string
-		fields  fields
-		fault   string
+		token   string
 		wantErr bool
 	}{
 		{
-			name: "validate token and extract claims, no error",
-			fields: fields{
-				key: "key",
-			},
+			name:    "validate token and extract claims, no error",
+			token:   token,
 			wantErr: false,
 		},
 		{
-			name: "validate token and extract claims, error_invalid_key",
-			fields: fields{
-				key: "secret",
-			},
+			name:    "validate token and extract claims, error_unknown_key",
+			token:   otherToken,
 			wantErr: true,
 		},
 		{
-			name: "validate token and extract claims, error_invalid_signature",
-			fields: fields{
-				key: "key",
-			},
-			fault:   "fault",
+			name:    "validate token and extract claims, error_invalid_signature",
+			token:   token + "fault",
+			wantErr: true,
+		},
+		{
+			name:    "validate token and extract claims, error_hmac_algorithm",
+			token:   hmacSigned,
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			a := &AuthenticatorService{
-				key: tt.fields.key,
-			}
-			tk, err := token.SignedString([]byte("key"))
-			require.NoError(t, err)
-
-			_, err = a.ValidateTokenAndExtractClaims(tk + tt.fault)
-			assert.Equalf(t, tt.wantErr, err != nil, "ValidateTokenAndExtractClaims(%v)", tk)
+			_, err := a.ValidateTokenAndExtractClaims(tt.token)
+			assert.Equalf(t, tt.wantErr, err != nil, "ValidateTokenAndExtractClaims(%v)", tt.token)
 		})
 	}
 }
 
 func TestNewAuthenticator(t *testing.T) {
+	keys := &KeySet{}
+
 	type args struct {
-		key string
+		keys *KeySet
+		ttl  time.Duration
+		cost int
 	}
 	tests := []struct {
 		name string
@@ -196,17 +228,21 @@ func TestNewAuthenticator(t *testing.T) {
 		{
 			name: "new authenticator",
 			args: args{
-				key: "key",
+				keys: keys,
+				ttl:  time.Minute,
+				cost: bcrypt.MinCost,
 			},
 			want: &AuthenticatorService{
-				key: "key",
+				keys: keys,
+				ttl:  time.Minute,
+				cost: bcrypt.MinCost,
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := NewAuthenticator(tt.args.key)
-			assert.Truef(t, reflect.DeepEqual(got, tt.want), "NewAuthenticator(%v)", tt.args.key)
+			got := NewAuthenticator(tt.args.keys, tt.args.ttl, tt.args.cost)
+			assert.Truef(t, reflect.DeepEqual(got, tt.want), "NewAuthenticator(%v, %v, %v)", tt.args.keys, tt.args.ttl, tt.args.cost)
 		})
 	}
 }
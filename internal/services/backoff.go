@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// throttle is shared by all accrual workers and pauses every request
+// to the accrual system after it responds with 429 Too Many Requests.
+type throttle struct {
+	mu       sync.Mutex
+	until    time.Time
+	failures int
+	base     time.Duration
+	max      time.Duration
+}
+
+// newThrottle creates a new throttle.
+// base and max bound the exponential back-off used
+// when the accrual system does not advertise a Retry-After time.
+func newThrottle(base, max time.Duration) *throttle {
+	return &throttle{
+		base: base,
+		max:  max,
+	}
+}
+
+// Wait blocks until the pause is over.
+// If the context is done first, the context error is returned.
+func (t *throttle) Wait(ctx context.Context) error {
+	for {
+		t.mu.Lock()
+		d := time.Until(t.until)
+		t.mu.Unlock()
+
+		if d <= 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Pause pauses all requests according to the Retry-After header value.
+// Both delay-seconds and HTTP-date forms are supported.
+// If the value is missing or malformed, exponential back-off with jitter is used.
+// Pause returns the time until which requests are paused.
+func (t *throttle) Pause(retryAfter string) time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.failures++
+
+	d, ok := parseRetryAfter(retryAfter, now)
+	if !ok {
+		d = t.backoff()
+	}
+
+	// Concurrent workers may receive 429 at the same time,
+	// the pause is never shortened by them.
+	if until := now.Add(d); until.After(t.until) {
+		t.until = until
+	}
+
+	return t.until
+}
+
+// Reset resets the back-off after a request has not been rejected.
+func (t *throttle) Reset() {
+	t.mu.Lock()
+	t.failures = 0
+	t.mu.Unlock()
+}
+
+// backoff returns an exponentially growing delay with jitter
+// for the current number of consecutive failures.
+func (t *throttle) backoff() time.Duration {
+	d := expBackoff(t.base, t.max, t.failures)
+
+	// Equal jitter keeps the delay within [d/2, d].
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// expBackoff returns base doubled for every attempt after the first one,
+// capped at max.
+func expBackoff(base, max time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	if shift := attempt - 1; shift < 32 {
+		if d := base << shift; d > 0 && d < max {
+			return d
+		}
+	}
+
+	return max
+}
+
+// parseRetryAfter parses the Retry-After header value.
+// It returns false if the value is missing or malformed.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	d := date.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+
+	return d, true
+}
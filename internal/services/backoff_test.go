@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	type want struct {
+		d  time.Duration
+		ok bool
+	}
+	tests := []struct {
+		name  string
+		value string
+		want  want
+	}{
+		{
+			name:  "seconds",
+			value: "60",
+			want: want{
+				d:  time.Minute,
+				ok: true,
+			},
+		},
+		{
+			name:  "http date",
+			value: now.Add(30 * time.Second).Format(http.TimeFormat),
+			want: want{
+				d:  30 * time.Second,
+				ok: true,
+			},
+		},
+		{
+			name:  "http date in the past",
+			value: now.Add(-time.Minute).Format(http.TimeFormat),
+			want: want{
+				d:  0,
+				ok: true,
+			},
+		},
+		{
+			name:  "empty",
+			value: "",
+			want: want{
+				ok: false,
+			},
+		},
+		{
+			name:  "negative seconds",
+			value: "-1",
+			want: want{
+				ok: false,
+			},
+		},
+		{
+			name:  "malformed",
+			value: "soon",
+			want: want{
+				ok: false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, ok := parseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.want.ok, ok)
+			assert.Equal(t, tt.want.d, d)
+		})
+	}
+}
+
+func Test_throttle_backoff(t *testing.T) {
+	th := newThrottle(time.Second, 10*time.Second)
+
+	tests := []struct {
+		name     string
+		failures int
+		max      time.Duration
+	}{
+		{
+			name:     "first failure",
+			failures: 1,
+			max:      time.Second,
+		},
+		{
+			name:     "third failure",
+			failures: 3,
+			max:      4 * time.Second,
+		},
+		{
+			name:     "capped",
+			failures: 10,
+			max:      10 * time.Second,
+		},
+		{
+			name:     "overflow",
+			failures: 100,
+			max:      10 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			th.failures = tt.failures
+			d := th.backoff()
+			assert.GreaterOrEqual(t, d, tt.max/2)
+			assert.LessOrEqual(t, d, tt.max)
+		})
+	}
+}
+
+func Test_throttle_PauseAndWait(t *testing.T) {
+	th := newThrottle(time.Second, time.Minute)
+
+	until := th.Pause("1")
+	assert.WithinDuration(t, time.Now().Add(time.Second), until, 100*time.Millisecond)
+
+	// A shorter pause does not shorten the current one.
+	assert.Equal(t, until, th.Pause("0"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, th.Wait(ctx), context.DeadlineExceeded)
+
+	th.Reset()
+	assert.Equal(t, 0, th.failures)
+}
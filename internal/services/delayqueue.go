@@ -0,0 +1,130 @@
+package services
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// delayQueue is a queue of accrual tasks ordered by their next attempt time.
+// A task can be popped only when its next attempt is due.
+// The queue holds at most one task per order.
+type delayQueue struct {
+	mu      sync.Mutex
+	items   taskHeap
+	index   map[string]*taskItem
+	changed chan struct{}
+}
+
+type taskItem struct {
+	task *accrualTask
+	pos  int
+}
+
+// newDelayQueue creates a new empty delay queue.
+func newDelayQueue() *delayQueue {
+	return &delayQueue{
+		index:   make(map[string]*taskItem),
+		changed: make(chan struct{}),
+	}
+}
+
+// Push adds a task to the queue.
+// If a task for the same order is already queued, it is replaced.
+func (q *delayQueue) Push(task *accrualTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if item, ok := q.index[task.OrderNumber]; ok {
+		item.task = task
+		heap.Fix(&q.items, item.pos)
+	} else {
+		item = &taskItem{task: task}
+		q.index[task.OrderNumber] = item
+		heap.Push(&q.items, item)
+	}
+
+	// Wake up all waiting consumers, the head of the queue may have changed.
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+// Pop removes and returns the earliest task once its next attempt is due.
+// It blocks until such a task exists or the context is done.
+func (q *delayQueue) Pop(ctx context.Context) (*accrualTask, error) {
+	for {
+		q.mu.Lock()
+		changed := q.changed
+
+		var timer *time.Timer
+		var wait <-chan time.Time
+		if len(q.items) > 0 {
+			d := time.Until(q.items[0].task.NextAttemptAt)
+			if d <= 0 {
+				item := heap.Pop(&q.items).(*taskItem)
+				delete(q.index, item.task.OrderNumber)
+				q.mu.Unlock()
+
+				return item.task, nil
+			}
+
+			timer = time.NewTimer(d)
+			wait = timer.C
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-wait:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Len returns the number of queued tasks.
+func (q *delayQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.items)
+}
+
+// taskHeap implements heap.Interface ordered by the next attempt time.
+type taskHeap []*taskItem
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	return h[i].task.NextAttemptAt.Before(h[j].task.NextAttemptAt)
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *taskHeap) Push(x any) {
+	item := x.(*taskItem)
+	item.pos = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+
+	return item
+}
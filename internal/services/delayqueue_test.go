@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_delayQueue_Pop(t *testing.T) {
+	q := newDelayQueue()
+	now := time.Now()
+
+	q.Push(&accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "2030", NextAttemptAt: now.Add(50 * time.Millisecond)}})
+	q.Push(&accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "4010", NextAttemptAt: now.Add(-time.Second)}})
+	q.Push(&accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "1230", NextAttemptAt: now.Add(time.Hour)}})
+	assert.Equal(t, 3, q.Len())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	task, err := q.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "4010", task.OrderNumber)
+
+	task, err = q.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "2030", task.OrderNumber)
+	assert.False(t, time.Now().Before(task.NextAttemptAt))
+
+	_, err = q.Pop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, q.Len())
+}
+
+func Test_delayQueue_Push(t *testing.T) {
+	q := newDelayQueue()
+
+	q.Push(&accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "2030", NextAttemptAt: time.Now().Add(time.Hour)}})
+
+	done := make(chan *accrualTask)
+	go func() {
+		task, _ := q.Pop(context.Background())
+		done <- task
+	}()
+
+	// Rescheduling a queued order wakes up the waiting consumer.
+	q.Push(&accrualTask{AccrualTask: &models.AccrualTask{OrderNumber: "2030", NextAttemptAt: time.Now()}})
+
+	select {
+	case task := <-done:
+		assert.Equal(t, "2030", task.OrderNumber)
+	case <-time.After(time.Second):
+		t.Fatal("Pop() was not woken up")
+	}
+
+	assert.Equal(t, 0, q.Len())
+}
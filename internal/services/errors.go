@@ -2,6 +2,9 @@ package services
 
 import (
 	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"strings"
+	"time"
 )
 
 var (
@@ -9,12 +12,94 @@ var (
 	ErrInvalidOrderNumber        = errors.New("invalid order number")
 	ErrOrderAlreadyExists        = errors.New("order already exists")
 	ErrOrderAlreadyExistsForUser = errors.New("order already exists for this user")
+	ErrOrderNotFound             = errors.New("order not found")
+	ErrEmptyOrderBatch           = errors.New("empty order batch")
+	ErrOrderBatchTooLarge        = errors.New("order batch too large")
+	ErrOrderFinal                = errors.New("order is already in a final status")
 
 	ErrGenerateToken            = errors.New("failed to generate token")
 	ErrGenerateHashFromPassword = errors.New("failed to generate hash from password")
-	ErrIncorrectPassword        = errors.New("incorrect password")
+	ErrInvalidCredentials       = errors.New("invalid credentials")
+	ErrTooManyLoginAttempts     = errors.New("too many login attempts")
+	ErrInvalidRefreshToken      = errors.New("invalid refresh token")
+	ErrRefreshTokenReused       = errors.New("refresh token reused, session revoked")
+	ErrTokenRevoked             = errors.New("token revoked")
+	ErrUnknownSigningKey        = errors.New("unknown token signing key")
+	ErrUnsupportedAlgorithm     = errors.New("unsupported token signing algorithm")
+	ErrUnsupportedKey           = errors.New("unsupported token signing key")
+	ErrInsufficientScope        = errors.New("token does not have the required scope")
+	ErrInvalidScopes            = errors.New("scopes must be a non-empty list of scopes of the token, except account and admin")
+	ErrInvalidTokenName         = errors.New("token name must be 1 to 255 characters long")
+	ErrInvalidTokenTTL          = errors.New("token ttl must be positive and not longer than the maximum")
+	ErrMachineTokenNotFound     = errors.New("machine token not found")
+	ErrMachineTokenExpired      = errors.New("machine token expired")
 
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidLoginFormat = errors.New("invalid login format")
+	ErrCredentialsPolicy  = errors.New("credentials do not satisfy the policy")
 	ErrInsufficientFunds  = errors.New("insufficient funds")
+	ErrAccountHasBalance  = errors.New("account has remaining balance")
+
+	ErrInvalidWithdrawalSum    = errors.New("withdrawal sum must be positive")
+	ErrWithdrawalAlreadyExists = errors.New("withdrawal for this order already exists")
+	ErrWithdrawalReplayed      = errors.New("withdrawal with this idempotency key already done")
+	ErrIdempotencyKeyReused    = errors.New("idempotency key already used for another withdrawal")
+	ErrInvalidIdempotencyKey   = errors.New("idempotency key must be at most 255 characters long")
+	ErrWithdrawalNotFound      = errors.New("withdrawal not found")
+	ErrWithdrawalNotReversible = errors.New("only a pending withdrawal can be reversed")
+	ErrReversalReason          = errors.New("reversal reason must be not empty")
+
+	ErrInvalidAdjustment = errors.New("adjustment amount must be not zero")
+	ErrAdjustmentReason  = errors.New("adjustment reason must be not empty")
+
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrInvalidWebhookURL     = errors.New("webhook url must be an absolute http or https url")
+	ErrWebhookAddrForbidden  = errors.New("webhook url must not resolve to a loopback, private, link-local or unspecified address")
+	ErrInvalidWebhookEvents  = errors.New("webhook events must be a non-empty list of known event types")
+	ErrWebhookDeliveryStatus = errors.New("webhook endpoint responded with unexpected status")
+
+	ErrInvalidAPIKey    = errors.New("invalid api key")
+	ErrAPIKeyExpired    = errors.New("api key expired")
+	ErrAPIKeyRevoked    = errors.New("api key revoked")
+	ErrAPIKeyNotFound   = errors.New("api key not found")
+	ErrInvalidMerchant  = errors.New("merchant name must be 1 to 255 characters long")
+	ErrInvalidAPIKeyTTL = errors.New("api key ttl must not be negative")
+
+	ErrInvalidListLimit    = errors.New("limit must be between 1 and 1000")
+	ErrInvalidStatusFilter = errors.New("invalid status filter")
+	ErrInvalidDateRange    = errors.New("from must be before to")
 )
+
+// CredentialsPolicyError lists the credentials policy rules a user failed.
+// It matches ErrCredentialsPolicy with errors.Is.
+type CredentialsPolicyError struct {
+	Violations []models.PolicyViolation
+}
+
+func (e *CredentialsPolicyError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.Message)
+	}
+
+	return ErrCredentialsPolicy.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *CredentialsPolicyError) Is(target error) bool {
+	return target == ErrCredentialsPolicy
+}
+
+// LoginThrottledError is returned when log in attempts are delayed
+// or locked out after failed attempts.
+// It matches ErrTooManyLoginAttempts with errors.Is.
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrTooManyLoginAttempts
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is the number of events a subscriber may lag behind
+// before it is dropped. A dropped subscriber reconnects and resumes from the backlog.
+const subscriptionBuffer = 16
+
+// EventHubConfig is a configuration of the event hub.
+type EventHubConfig struct {
+	// Backlog is the number of recent events kept per user for resuming streams.
+	Backlog int
+	// Retention is how long recent events are kept.
+	Retention time.Duration
+}
+
+// EventHub is an in-process publish/subscribe hub of user events.
+// Recent events of every user are kept, so a subscriber can resume
+// the stream from the last event it received.
+// Event ids start from the current time in microseconds,
+// so ids issued after a restart are greater than the ones issued before it.
+type EventHub struct {
+	cfg EventHubConfig
+
+	mu     sync.Mutex
+	seq    uint64
+	users  map[int64]*userEvents
+	closed bool
+}
+
+// userEvents are the recent events and the subscriptions of a user.
+type userEvents struct {
+	recent []*models.Event
+	subs   map[*Subscription]struct{}
+}
+
+// Subscription is a stream of events of a user.
+type Subscription struct {
+	// Replay holds the recent events after the requested event id.
+	Replay []*models.Event
+	// Events delivers new events. It is closed when the subscriber
+	// falls behind, the hub is closed or the subscription is closed.
+	Events <-chan *models.Event
+
+	events chan *models.Event
+	userID int64
+	hub    *EventHub
+}
+
+// NewEventHub creates a new event hub and drops expired events
+// of inactive users every cfg.Retention until ctx is done.
+func NewEventHub(ctx context.Context, cfg EventHubConfig) *EventHub {
+	h := &EventHub{
+		cfg:   cfg,
+		seq:   uint64(time.Now().UnixMicro()),
+		users: make(map[int64]*userEvents),
+	}
+
+	if cfg.Retention > 0 {
+		go h.run(ctx)
+	}
+
+	return h
+}
+
+// Publish sends an event to all subscribers of a user and keeps it for resuming.
+func (h *EventHub) Publish(userID int64, eventType string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.seq++
+	event := &models.Event{
+		ID:        h.seq,
+		UserID:    userID,
+		Type:      eventType,
+		Data:      data,
+		CreatedAt: time.Now(),
+	}
+
+	u := h.user(userID)
+	u.recent = append(u.recent, event)
+	if len(u.recent) > h.cfg.Backlog {
+		u.recent = u.recent[len(u.recent)-h.cfg.Backlog:]
+	}
+
+	for sub := range u.subs {
+		select {
+		case sub.events <- event:
+		default:
+			// The subscriber is too slow, it resumes from the backlog after reconnecting.
+			h.unsubscribe(sub)
+		}
+	}
+}
+
+// Subscribe starts a stream of events of a user.
+// Recent events with ids greater than lastEventID are returned for replay,
+// zero lastEventID starts the stream from new events only.
+func (h *EventHub) Subscribe(userID int64, lastEventID uint64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events := make(chan *models.Event, subscriptionBuffer)
+	sub := &Subscription{
+		Events: events,
+		events: events,
+		userID: userID,
+		hub:    h,
+	}
+
+	if h.closed {
+		close(events)
+		return sub
+	}
+
+	u := h.user(userID)
+	if lastEventID > 0 {
+		for _, event := range u.recent {
+			if event.ID > lastEventID {
+				sub.Replay = append(sub.Replay, event)
+			}
+		}
+	}
+
+	u.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.unsubscribe(s)
+}
+
+// Close ends all subscriptions, so the streams can be finished on shutdown.
+func (h *EventHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, u := range h.users {
+		for sub := range u.subs {
+			h.unsubscribe(sub)
+		}
+	}
+}
+
+// user returns the events of a user, h.mu must be held.
+func (h *EventHub) user(userID int64) *userEvents {
+	u, ok := h.users[userID]
+	if !ok {
+		u = &userEvents{subs: make(map[*Subscription]struct{})}
+		h.users[userID] = u
+	}
+
+	return u
+}
+
+// unsubscribe removes a subscription and closes its channel, h.mu must be held.
+func (h *EventHub) unsubscribe(sub *Subscription) {
+	u, ok := h.users[sub.userID]
+	if !ok {
+		return
+	}
+
+	if _, ok = u.subs[sub]; !ok {
+		return
+	}
+
+	delete(u.subs, sub)
+	close(sub.events)
+}
+
+// prune drops expired events and forgets users without events and subscribers.
+func (h *EventHub) prune(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for userID, u := range h.users {
+		i := 0
+		for i < len(u.recent) && now.Sub(u.recent[i].CreatedAt) > h.cfg.Retention {
+			i++
+		}
+		u.recent = u.recent[i:]
+
+		if len(u.recent) == 0 && len(u.subs) == 0 {
+			delete(h.users, userID)
+		}
+	}
+}
+
+func (h *EventHub) run(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.Retention)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.prune(now)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestEventHub_Publish(t *testing.T) {
+	hub := NewEventHub(context.Background(), EventHubConfig{Backlog: 10})
+
+	sub := hub.Subscribe(1, 0)
+	defer sub.Close()
+	other := hub.Subscribe(2, 0)
+	defer other.Close()
+
+	hub.Publish(1, models.EventOrder, &models.Order{Number: "12345678903"})
+
+	select {
+	case event := <-sub.Events:
+		assert.Equal(t, int64(1), event.UserID)
+		assert.Equal(t, models.EventOrder, event.Type)
+	case <-time.After(time.Second):
+		t.Fatal("event is not delivered")
+	}
+
+	assert.Empty(t, other.Events, "events of other users are not delivered")
+}
+
+func TestEventHub_Subscribe(t *testing.T) {
+	hub := NewEventHub(context.Background(), EventHubConfig{Backlog: 2})
+
+	for i := 0; i < 3; i++ {
+		hub.Publish(1, models.EventOrder, i)
+	}
+
+	sub := hub.Subscribe(1, 0)
+	assert.Empty(t, sub.Replay, "a new stream starts from new events")
+	sub.Close()
+
+	// Only the backlog is kept, the oldest event is dropped.
+	sub = hub.Subscribe(1, 1)
+	require.Len(t, sub.Replay, 2)
+	assert.Equal(t, 1, sub.Replay[0].Data)
+	assert.Equal(t, 2, sub.Replay[1].Data)
+
+	resumed := hub.Subscribe(1, sub.Replay[0].ID)
+	require.Len(t, resumed.Replay, 1)
+	assert.Equal(t, sub.Replay[1].ID, resumed.Replay[0].ID)
+}
+
+func TestEventHub_slowSubscriber(t *testing.T) {
+	hub := NewEventHub(context.Background(), EventHubConfig{Backlog: 100})
+	sub := hub.Subscribe(1, 0)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(1, models.EventOrder, i)
+	}
+
+	received := 0
+	for range sub.Events {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received, "a subscriber that falls behind is dropped")
+
+	// Closing a dropped subscription is safe.
+	sub.Close()
+}
+
+func TestEventHub_Close(t *testing.T) {
+	hub := NewEventHub(context.Background(), EventHubConfig{Backlog: 10})
+	sub := hub.Subscribe(1, 0)
+
+	hub.Close()
+
+	_, ok := <-sub.Events
+	assert.False(t, ok)
+
+	_, ok = <-hub.Subscribe(1, 0).Events
+	assert.False(t, ok, "subscriptions to a closed hub end at once")
+}
+
+func TestEventHub_prune(t *testing.T) {
+	hub := NewEventHub(context.Background(), EventHubConfig{Backlog: 10, Retention: time.Hour})
+
+	hub.Publish(1, models.EventOrder, 1)
+	hub.Publish(2, models.EventOrder, 2)
+	sub := hub.Subscribe(2, 0)
+	defer sub.Close()
+
+	hub.prune(time.Now().Add(2 * time.Hour))
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	assert.NotContains(t, hub.users, int64(1))
+	require.Contains(t, hub.users, int64(2), "users with subscribers are kept")
+	assert.Empty(t, hub.users[2].recent)
+}
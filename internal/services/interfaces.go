@@ -3,69 +3,210 @@ package services
 import (
 	"context"
 	"github.com/leonf08/gophermart.git/internal/models"
+	"time"
 )
 
 //go:generate mockery --name Users --output ./mocks --filename users_mock.go
 //go:generate mockery --name Orders --output ./mocks --filename orders_mock.go
+//go:generate mockery --name Sessions --output ./mocks --filename sessions_mock.go
 //go:generate mockery --name Authenticator --output ./mocks --filename authenticator_mock.go
 //go:generate mockery --name UserRepo --output ./mocks --filename user_repo_mock.go
 //go:generate mockery --name OrderRepo --output ./mocks --filename order_repo_mock.go
+//go:generate mockery --name SessionRepo --output ./mocks --filename session_repo_mock.go
+//go:generate mockery --name LoginAttemptRepo --output ./mocks --filename login_attempt_repo_mock.go
+//go:generate mockery --name WithdrawalRepo --output ./mocks --filename withdrawal_repo_mock.go
+//go:generate mockery --name MachineTokens --output ./mocks --filename machine_tokens_mock.go
+//go:generate mockery --name MachineTokenRepo --output ./mocks --filename machine_token_repo_mock.go
+//go:generate mockery --name Admin --output ./mocks --filename admin_mock.go
+//go:generate mockery --name AdminRepo --output ./mocks --filename admin_repo_mock.go
+//go:generate mockery --name Merchants --output ./mocks --filename merchants_mock.go
+//go:generate mockery --name APIKeyRepo --output ./mocks --filename api_key_repo_mock.go
+//go:generate mockery --name Webhooks --output ./mocks --filename webhooks_mock.go
+//go:generate mockery --name WebhookRepo --output ./mocks --filename webhook_repo_mock.go
+//go:generate mockery --name AccrualRepo --output ./mocks --filename accrual_repo_mock.go
 type (
 	// UserRepo is an interface for working with the user repository.
 	UserRepo interface {
 		CreateUser(ctx context.Context, login, hashedPasswd string) (int64, error)
 		GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+		GetUserByID(ctx context.Context, userID int64) (*models.User, error)
+		ChangePassword(ctx context.Context, userID int64, hashedPasswd string) error
+		DeleteUser(ctx context.Context, userID int64, forfeitBalance bool) error
 		GetUserAccount(ctx context.Context, userID int64) (*models.UserAccount, error)
 		DoWithdrawal(ctx context.Context, w *models.Withdrawal) error
-		GetWithdrawalList(ctx context.Context, userID int64) ([]*models.Withdrawal, error)
+		GetWithdrawalList(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, error)
+		GetLedgerEntries(ctx context.Context, userID int64) ([]*models.LedgerEntry, error)
 	}
 
 	// OrderRepo is an interface for working with the order repository.
 	OrderRepo interface {
 		CreateOrder(ctx context.Context, order models.Order) error
+		CreateOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error)
 		GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error)
-		GetOrderList(ctx context.Context, userID int64) ([]*models.Order, error)
-		UpdateOrder(ctx context.Context, order *models.Order) error
+		GetOrderList(ctx context.Context, q *models.ListQuery) ([]*models.Order, error)
+		GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error)
+		UpdateOrderStatus(ctx context.Context, c *models.OrderStatusChange) (bool, error)
+	}
+
+	// SessionRepo is an interface for working with the session repository.
+	SessionRepo interface {
+		CreateSession(ctx context.Context, s *models.Session) error
+		GetSessionByRefreshHash(ctx context.Context, hash string) (*models.Session, error)
+		RotateSession(ctx context.Context, s *models.Session, newHash string) error
+		RevokeSession(ctx context.Context, sessionID string) error
+		RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+		IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error)
+		GetMachineTokenByHash(ctx context.Context, hash string) (*models.MachineToken, error)
+	}
+
+	// MachineTokenRepo is an interface for working with the machine token repository.
+	MachineTokenRepo interface {
+		CreateMachineToken(ctx context.Context, t *models.MachineToken) error
+		GetMachineTokens(ctx context.Context, userID int64) ([]*models.MachineToken, error)
+		RevokeMachineToken(ctx context.Context, userID int64, tokenID string) error
+	}
+
+	// LoginAttemptRepo is an interface for storing failed log in attempts.
+	LoginAttemptRepo interface {
+		GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error)
+		RecordLoginFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempts, error)
+		LockLogin(ctx context.Context, key string, until time.Time) error
+		ResetLoginAttempts(ctx context.Context, key string) error
+	}
+
+	// WithdrawalRepo is an interface for settling withdrawals in the repository.
+	WithdrawalRepo interface {
+		CompleteWithdrawals(ctx context.Context, before time.Time) (int64, error)
+	}
+
+	// AdminRepo is an interface for working with the repository on behalf of administrators.
+	AdminRepo interface {
+		SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error)
+		GetAdminUser(ctx context.Context, userID int64) (*models.AdminUser, error)
+		GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error)
+		GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error)
+		AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error)
+		ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error)
+		AddAuditEntry(ctx context.Context, e *models.AuditEntry) error
+		GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error)
+	}
+
+	// APIKeyRepo is an interface for working with the API key repository.
+	APIKeyRepo interface {
+		CreateAPIKey(ctx context.Context, k *models.APIKey) error
+		GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+		GetAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+		RevokeAPIKey(ctx context.Context, keyID int64) error
+		GetUserByLogin(ctx context.Context, login string) (*models.User, error)
+	}
+
+	// WebhookRepo is an interface for working with the webhook repository.
+	WebhookRepo interface {
+		CreateWebhook(ctx context.Context, w *models.Webhook) error
+		GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error)
+		DeleteWebhook(ctx context.Context, userID, webhookID int64) error
+		GetWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]*models.WebhookDelivery, error)
+		DispatchWebhookOutbox(ctx context.Context, at time.Time, limit int) (int64, error)
+		ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error)
+		UpdateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error
 	}
 
 	// AccrualRepo is an interface for working with the accrual repository.
 	AccrualRepo interface {
 		UserRepo
 		OrderRepo
+		GetPendingAccrualTasks(ctx context.Context) ([]*models.AccrualTask, error)
+		UpdateAccrualTask(ctx context.Context, task *models.AccrualTask) error
 	}
 
 	// Authenticator is an interface for working with the authenticator service.
 	Authenticator interface {
 		GenerateHashFromPassword(user *models.User) (string, error)
 		CheckPasswordHash(user, storedUser *models.User) error
-		GenerateToken(user *models.User) (string, error)
+		GenerateToken(user *models.User, sessionID string) (string, time.Time, error)
 		ValidateTokenAndExtractClaims(token string) (*models.CustomJWTClaims, error)
+		JWKS() models.JWKS
 	}
 
 	// Users is an interface for working with the user service.
 	Users interface {
 		RegisterUser(ctx context.Context, user *models.User) error
-		LoginUser(ctx context.Context, user *models.User) error
+		LoginUser(ctx context.Context, user *models.User, clientIP string) error
+		ChangePassword(ctx context.Context, userID int64, req *models.PasswordChangeRequest, clientIP string) error
+		DeleteUser(ctx context.Context, userID int64, req *models.AccountDeletionRequest, clientIP string) error
 		WithdrawFromAccount(ctx context.Context, w *models.Withdrawal) error
 		GetUserAccount(ctx context.Context, userID int64) (*models.UserAccount, error)
-		GetWithdrawals(ctx context.Context, userID int64) ([]*models.Withdrawal, error)
-		GetToken(user *models.User) (string, error)
+		GetWithdrawals(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, *models.Cursor, error)
+		GetLedger(ctx context.Context, userID int64) ([]*models.LedgerEntry, error)
+	}
+
+	// Sessions is an interface for working with the session service.
+	Sessions interface {
+		CreateSession(ctx context.Context, user *models.User) (*models.TokenPair, error)
+		RefreshSession(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+		RevokeSession(ctx context.Context, claims *models.CustomJWTClaims) error
+		Authenticate(ctx context.Context, token string) (*models.CustomJWTClaims, error)
 	}
 
 	// Orders is an interface for working with the order service.
 	Orders interface {
 		CreateNewOrder(ctx context.Context, userID int64, orderNum string) error
-		GetOrdersForUser(ctx context.Context, userID int64) ([]*models.Order, error)
+		CreateOrderBatch(ctx context.Context, userID int64, orderNums []string) ([]*models.OrderBatchResult, error)
+		GetOrdersForUser(ctx context.Context, q *models.ListQuery) ([]*models.Order, *models.Cursor, error)
+		GetOrder(ctx context.Context, userID int64, orderNum string) (*models.OrderDetail, error)
+	}
+
+	// MachineTokens is an interface for working with the machine token service.
+	MachineTokens interface {
+		IssueMachineToken(ctx context.Context, claims *models.CustomJWTClaims, req *models.MachineTokenRequest) (*models.MachineToken, error)
+		GetMachineTokens(ctx context.Context, userID int64) ([]*models.MachineToken, error)
+		RevokeMachineToken(ctx context.Context, userID int64, tokenID string) error
+	}
+
+	// Admin is an interface for working with the admin service.
+	Admin interface {
+		SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error)
+		GetUser(ctx context.Context, userID int64) (*models.AdminUser, error)
+		GetOrder(ctx context.Context, orderNum string) (*models.OrderDetail, error)
+		RecheckOrder(ctx context.Context, adminID int64, orderNum, reason string) error
+		AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error)
+		ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error)
+		GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error)
+	}
+
+	// Merchants is an interface for working with the merchant service.
+	Merchants interface {
+		IssueAPIKey(ctx context.Context, req *models.APIKeyRequest) (*models.APIKey, error)
+		GetAPIKeys(ctx context.Context) ([]*models.APIKey, error)
+		RevokeAPIKey(ctx context.Context, keyID int64) error
+		AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+		UploadOrder(ctx context.Context, req *models.MerchantOrder) error
+	}
+
+	// Webhooks is an interface for working with the webhook service.
+	Webhooks interface {
+		CreateWebhook(ctx context.Context, userID int64, req *models.WebhookRequest) (*models.Webhook, error)
+		GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error)
+		DeleteWebhook(ctx context.Context, userID, webhookID int64) error
+		GetWebhookDeliveries(ctx context.Context, userID, webhookID int64) ([]*models.WebhookDelivery, error)
 	}
 
 	// Logger is an interface for working with the logging tools
 	Logger interface {
 		Info(msg string, args ...any)
+		Warn(msg string, args ...any)
 		Error(msg string, args ...any)
 	}
 
 	// Accrual is an interface for working with the accrual service.
 	Accrual interface {
-		SendOrderAccrual(orderNum string)
+		SendOrderAccrual(ctx context.Context, orderNum string)
+		RecheckOrder(ctx context.Context, orderNum string) error
+	}
+
+	// Events is an interface for streaming events of users.
+	Events interface {
+		Publish(userID int64, eventType string, data any)
+		Subscribe(userID int64, lastEventID uint64) *Subscription
 	}
 )
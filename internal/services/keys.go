@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// rsaKeyBits is the size of generated RSA keys.
+	rsaKeyBits = 2048
+	// keyFileExt is the extension of key files, the file name is the key id.
+	keyFileExt = ".pem"
+	// keyRotationRetry is the delay before retrying a failed key rotation.
+	keyRotationRetry = time.Minute
+)
+
+// KeySetConfig is a configuration of the token signing keys.
+type KeySetConfig struct {
+	// Dir is a directory with PEM encoded private keys named <kid>.pem.
+	// Generated keys are stored there as well.
+	// If Dir is empty, keys are kept in memory only.
+	Dir string
+	// Algorithm is the signing algorithm of new keys, RS256 or EdDSA.
+	Algorithm string
+	// RotationInterval is how long a key is used for signing before a new one is generated.
+	// Zero disables rotation.
+	RotationInterval time.Duration
+	// GracePeriod is how long a retired key is still accepted and published.
+	GracePeriod time.Duration
+}
+
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	createdAt time.Time
+	retiredAt time.Time
+}
+
+// KeySet holds the token signing keys identified by kid.
+// The newest key signs tokens, retired keys are kept
+// to verify tokens issued before the rotation until the grace period ends.
+type KeySet struct {
+	cfg KeySetConfig
+	log Logger
+
+	mu     sync.RWMutex
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// NewKeySet loads the keys from cfg.Dir, generates a new active key
+// if there is none or it uses another algorithm,
+// and rotates the active key every cfg.RotationInterval until ctx is done.
+func NewKeySet(ctx context.Context, cfg KeySetConfig, log Logger) (*KeySet, error) {
+	if signingMethod(cfg.Algorithm) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, cfg.Algorithm)
+	}
+
+	k := &KeySet{
+		cfg:  cfg,
+		log:  log,
+		keys: make(map[string]*signingKey),
+	}
+
+	if err := k.load(); err != nil {
+		return nil, err
+	}
+
+	if k.active == nil || k.active.method.Alg() != cfg.Algorithm {
+		if err := k.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.RotationInterval > 0 {
+		go k.run(ctx)
+	}
+
+	return k, nil
+}
+
+// Rotate generates a new signing key and retires the active one.
+func (k *KeySet) Rotate() error {
+	key, err := generateKey(k.cfg.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	if k.cfg.Dir != "" {
+		if err = saveKey(k.cfg.Dir, key); err != nil {
+			return err
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.active != nil {
+		k.active.retiredAt = key.createdAt
+	}
+	k.active = key
+	k.keys[key.kid] = key
+	k.prune(key.createdAt)
+
+	return nil
+}
+
+// JWKS returns the public keys which are accepted for token verification.
+func (k *KeySet) JWKS() models.JWKS {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	set := models.JWKS{Keys: make([]models.JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		if k.expired(key, now) {
+			continue
+		}
+
+		set.Keys = append(set.Keys, publicJWK(key))
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+
+	return set
+}
+
+// signing returns the active signing key.
+func (k *KeySet) signing() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// lookup returns a key accepted for token verification by its id.
+func (k *KeySet) lookup(kid string) (*signingKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok || k.expired(key, time.Now()) {
+		return nil, false
+	}
+
+	return key, true
+}
+
+func (k *KeySet) expired(key *signingKey, now time.Time) bool {
+	return !key.retiredAt.IsZero() && now.Sub(key.retiredAt) > k.cfg.GracePeriod
+}
+
+// prune removes the keys whose grace period is over. Key files are left intact.
+func (k *KeySet) prune(now time.Time) {
+	for kid, key := range k.keys {
+		if k.expired(key, now) {
+			delete(k.keys, kid)
+		}
+	}
+}
+
+// run rotates the active key when it gets older than the rotation interval.
+func (k *KeySet) run(ctx context.Context) {
+	for {
+		wait := time.Until(k.signing().createdAt.Add(k.cfg.RotationInterval))
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := k.Rotate(); err != nil {
+			k.log.Error("services - KeySet - Rotate", "error", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(keyRotationRetry):
+			}
+			continue
+		}
+
+		k.log.Info("services - KeySet - Rotate", "kid", k.signing().kid)
+	}
+}
+
+// load reads the keys from the key directory.
+// The most recently created key becomes active,
+// every older key is considered retired when the next one was created.
+func (k *KeySet) load() error {
+	if k.cfg.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(k.cfg.Dir, 0o700); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(k.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != keyFileExt {
+			continue
+		}
+
+		key, err := loadKey(filepath.Join(k.cfg.Dir, e.Name()))
+		if err != nil {
+			return fmt.Errorf("load key %s: %w", e.Name(), err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].createdAt.Before(keys[j].createdAt)
+	})
+
+	for i, key := range keys {
+		if i < len(keys)-1 {
+			key.retiredAt = keys[i+1].createdAt
+		}
+		k.keys[key.kid] = key
+	}
+
+	if len(keys) > 0 {
+		k.active = keys[len(keys)-1]
+	}
+	k.prune(time.Now())
+
+	return nil
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		return jwt.SigningMethodRS256
+	case jwt.SigningMethodEdDSA.Alg():
+		return jwt.SigningMethodEdDSA
+	default:
+		return nil
+	}
+}
+
+func generateKey(alg string) (*signingKey, error) {
+	var (
+		private crypto.Signer
+		err     error
+	)
+
+	switch alg {
+	case jwt.SigningMethodRS256.Alg():
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := randomString(12)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signingKey{
+		kid:       kid,
+		method:    signingMethod(alg),
+		private:   private,
+		createdAt: time.Now(),
+	}, nil
+}
+
+// loadKey reads a PKCS#8 or PKCS#1 PEM encoded private key.
+// The key id is the file name without extension,
+// the creation time is the file modification time.
+func loadKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrUnsupportedKey
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{kid: strings.TrimSuffix(filepath.Base(path), keyFileExt)}
+	switch p := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private = jwt.SigningMethodRS256, p
+	case ed25519.PrivateKey:
+		key.method, key.private = jwt.SigningMethodEdDSA, p
+	default:
+		return nil, ErrUnsupportedKey
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	key.createdAt = info.ModTime()
+
+	return key, nil
+}
+
+func saveKey(dir string, key *signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.private)
+	if err != nil {
+		return err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	return os.WriteFile(filepath.Join(dir, key.kid+keyFileExt), data, 0o600)
+}
+
+func publicJWK(key *signingKey) models.JWK {
+	jwk := models.JWK{
+		Use: "sig",
+		Alg: key.method.Alg(),
+		Kid: key.kid,
+	}
+
+	switch pub := key.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	}
+
+	return jwk
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestKeySet(t *testing.T, cfg KeySetConfig) *KeySet {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	keys, err := NewKeySet(ctx, cfg, &nopLogger{})
+	require.NoError(t, err)
+
+	return keys
+}
+
+type nopLogger struct{}
+
+func (l *nopLogger) Info(msg string, args ...any) {}
+
+func (l *nopLogger) Warn(msg string, args ...any) {}
+
+func (l *nopLogger) Error(msg string, args ...any) {}
+
+func TestNewKeySet(t *testing.T) {
+	_, err := NewKeySet(context.Background(), KeySetConfig{Algorithm: "HS256"}, &nopLogger{})
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	dir := t.TempDir()
+	keys := newTestKeySet(t, KeySetConfig{Dir: dir, Algorithm: "RS256", GracePeriod: time.Hour})
+	kid := keys.signing().kid
+
+	_, err = os.Stat(filepath.Join(dir, kid+keyFileExt))
+	require.NoError(t, err, "generated key is stored in the key directory")
+
+	// The stored key is loaded and used on restart.
+	reloaded := newTestKeySet(t, KeySetConfig{Dir: dir, Algorithm: "RS256", GracePeriod: time.Hour})
+	assert.Equal(t, kid, reloaded.signing().kid)
+
+	// Switching the algorithm generates a new key, the old one is kept in grace.
+	switched := newTestKeySet(t, KeySetConfig{Dir: dir, Algorithm: "EdDSA", GracePeriod: time.Hour})
+	assert.NotEqual(t, kid, switched.signing().kid)
+	assert.Equal(t, "EdDSA", switched.signing().method.Alg())
+	_, ok := switched.lookup(kid)
+	assert.True(t, ok)
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	keys := newTestKeySet(t, KeySetConfig{Algorithm: "EdDSA", GracePeriod: time.Hour})
+	a := NewAuthenticator(keys, time.Minute, bcrypt.MinCost)
+
+	token, _, err := a.GenerateToken(&models.User{UserID: 1}, "session")
+	require.NoError(t, err)
+	oldKid := keys.signing().kid
+
+	require.NoError(t, keys.Rotate())
+	assert.NotEqual(t, oldKid, keys.signing().kid)
+
+	// Tokens signed with the retired key are accepted within the grace period.
+	_, err = a.ValidateTokenAndExtractClaims(token)
+	assert.NoError(t, err)
+	assert.Len(t, keys.JWKS().Keys, 2)
+
+	// After the grace period the retired key is dropped.
+	keys.mu.Lock()
+	keys.keys[oldKid].retiredAt = time.Now().Add(-2 * time.Hour)
+	keys.mu.Unlock()
+
+	_, err = a.ValidateTokenAndExtractClaims(token)
+	assert.ErrorIs(t, err, ErrUnknownSigningKey)
+	assert.Len(t, keys.JWKS().Keys, 1)
+}
+
+func TestKeySet_run(t *testing.T) {
+	keys := newTestKeySet(t, KeySetConfig{Algorithm: "EdDSA", RotationInterval: 50 * time.Millisecond, GracePeriod: time.Hour})
+	kid := keys.signing().kid
+
+	assert.Eventually(t, func() bool {
+		return keys.signing().kid != kid
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	rsaKeys := newTestKeySet(t, KeySetConfig{Algorithm: "RS256", GracePeriod: time.Hour})
+	jwks := rsaKeys.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Equal(t, "sig", jwks.Keys[0].Use)
+	assert.Equal(t, rsaKeys.signing().kid, jwks.Keys[0].Kid)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.NotEmpty(t, jwks.Keys[0].N)
+
+	edKeys := newTestKeySet(t, KeySetConfig{Algorithm: "EdDSA", GracePeriod: time.Hour})
+	jwks = edKeys.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[0].Crv)
+	assert.NotEmpty(t, jwks.Keys[0].X)
+	assert.Empty(t, jwks.Keys[0].N)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"github.com/leonf08/gophermart.git/internal/models"
+	"slices"
+)
+
+// Page size limits of order and withdrawal listings.
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+)
+
+// validateListQuery checks a listing query.
+// A query with neither a limit nor a cursor is not paginated and selects all items,
+// a query with a cursor but no limit selects a page of DefaultListLimit items.
+// Only the given statuses may be used as a filter.
+func validateListQuery(q *models.ListQuery, statuses ...string) error {
+	if q.Limit != 0 || q.Cursor != nil {
+		limit, err := checkLimit(q.Limit)
+		if err != nil {
+			return err
+		}
+
+		q.Limit = limit
+	}
+
+	for _, s := range q.Statuses {
+		if !slices.Contains(statuses, s) {
+			return ErrInvalidStatusFilter
+		}
+	}
+
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return ErrInvalidDateRange
+	}
+
+	return nil
+}
+
+// checkLimit checks a page size and returns DefaultListLimit instead of zero.
+func checkLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultListLimit, nil
+	}
+
+	if limit < 0 || limit > MaxListLimit {
+		return 0, ErrInvalidListLimit
+	}
+
+	return limit, nil
+}
+
+// paginate cuts a page of items fetched with one extra item over the limit.
+// If there is the extra item, the cursor of the last item on the page is returned
+// to continue the listing with, otherwise the listing is over and the cursor is nil.
+// A zero limit means the listing is not paginated.
+func paginate[T any](items []T, limit int, cursor func(T) *models.Cursor) ([]T, *models.Cursor) {
+	if limit == 0 || len(items) <= limit {
+		return items, nil
+	}
+
+	items = items[:limit]
+
+	return items, cursor(items[limit-1])
+}
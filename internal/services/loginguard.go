@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"time"
+)
+
+// Login attempt key prefixes, the attempts are tracked per login and per client address.
+const (
+	loginKeyPrefix = "login:"
+	ipKeyPrefix    = "ip:"
+)
+
+// LoginGuardConfig is a configuration of the brute-force protection.
+type LoginGuardConfig struct {
+	// Window is how long a failed attempt is remembered.
+	Window time.Duration
+	// DelayBase is the delay after the first failed attempt,
+	// it doubles with every next failed attempt up to DelayMax.
+	DelayBase time.Duration
+	DelayMax  time.Duration
+	// LoginMaxFailures and IPMaxFailures are the numbers of failed attempts
+	// within the window after which a login or a client address is locked out.
+	LoginMaxFailures int
+	IPMaxFailures    int
+	// LockoutDuration is how long a lockout lasts.
+	LockoutDuration time.Duration
+}
+
+type loginTarget struct {
+	key         string
+	maxFailures int
+}
+
+// LoginGuard protects log in against password guessing.
+// After a failed attempt the next attempt for the same login or from the same
+// client address is allowed only after a progressive delay,
+// too many failed attempts lock the login or the address out for a while.
+type LoginGuard struct {
+	repo LoginAttemptRepo
+	cfg  LoginGuardConfig
+}
+
+func NewLoginGuard(repo LoginAttemptRepo, cfg LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{
+		repo: repo,
+		cfg:  cfg,
+	}
+}
+
+// Check returns a *LoginThrottledError if a log in attempt
+// for the login from the client address is not allowed yet.
+func (g *LoginGuard) Check(ctx context.Context, login, clientIP string) error {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, target := range g.targets(login, clientIP) {
+		attempts, err := g.repo.GetLoginAttempts(ctx, target.key)
+		if err != nil {
+			return err
+		}
+
+		if d := g.retryAfter(attempts, now); d > wait {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// Failure records a failed log in attempt
+// and locks out the login or the client address if it has too many of them.
+func (g *LoginGuard) Failure(ctx context.Context, login, clientIP string) error {
+	now := time.Now()
+
+	for _, target := range g.targets(login, clientIP) {
+		attempts, err := g.repo.RecordLoginFailure(ctx, target.key, now, g.cfg.Window)
+		if err != nil {
+			return err
+		}
+
+		if target.maxFailures > 0 && attempts.Failures >= target.maxFailures {
+			if err = g.repo.LockLogin(ctx, target.key, now.Add(g.cfg.LockoutDuration)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Success forgets the failed attempts of a login after a successful log in.
+// The attempts from the client address are kept,
+// so an attacker can not reset them by logging in to an own account.
+func (g *LoginGuard) Success(ctx context.Context, login string) error {
+	return g.repo.ResetLoginAttempts(ctx, loginKeyPrefix+login)
+}
+
+// retryAfter returns how long to wait before the next attempt is allowed.
+func (g *LoginGuard) retryAfter(attempts *models.LoginAttempts, now time.Time) time.Duration {
+	var wait time.Duration
+	if attempts.LockedUntil != nil && attempts.LockedUntil.After(now) {
+		wait = attempts.LockedUntil.Sub(now)
+	}
+
+	if attempts.Failures == 0 || now.Sub(attempts.LastFailureAt) > g.cfg.Window {
+		return wait
+	}
+
+	if d := attempts.LastFailureAt.Add(g.delay(attempts.Failures)).Sub(now); d > wait {
+		wait = d
+	}
+
+	return wait
+}
+
+// delay returns the delay after the given number of failed attempts.
+func (g *LoginGuard) delay(failures int) time.Duration {
+	d := g.cfg.DelayBase
+	for i := 1; i < failures && d < g.cfg.DelayMax; i++ {
+		d *= 2
+	}
+
+	return min(d, g.cfg.DelayMax)
+}
+
+// targets returns the keys the attempts are tracked by with their lockout limits.
+func (g *LoginGuard) targets(login, clientIP string) []loginTarget {
+	targets := []loginTarget{{key: loginKeyPrefix + login, maxFailures: g.cfg.LoginMaxFailures}}
+	if clientIP != "" {
+		targets = append(targets, loginTarget{key: ipKeyPrefix + clientIP, maxFailures: g.cfg.IPMaxFailures})
+	}
+
+	return targets
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+var testLoginGuardConfig = LoginGuardConfig{
+	Window:           15 * time.Minute,
+	DelayBase:        time.Second,
+	DelayMax:         8 * time.Second,
+	LoginMaxFailures: 5,
+	IPMaxFailures:    20,
+	LockoutDuration:  10 * time.Minute,
+}
+
+func TestLoginGuard_delay(t *testing.T) {
+	g := NewLoginGuard(nil, testLoginGuardConfig)
+
+	assert.Equal(t, time.Second, g.delay(1))
+	assert.Equal(t, 2*time.Second, g.delay(2))
+	assert.Equal(t, 4*time.Second, g.delay(3))
+	assert.Equal(t, 8*time.Second, g.delay(4))
+	assert.Equal(t, 8*time.Second, g.delay(100))
+}
+
+func TestLoginGuard_Check(t *testing.T) {
+	now := time.Now()
+	lockedUntil := now.Add(5 * time.Minute)
+	expiredLock := now.Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		login     *models.LoginAttempts
+		ip        *models.LoginAttempts
+		wantRetry time.Duration
+	}{
+		{
+			name:  "no failures",
+			login: &models.LoginAttempts{},
+			ip:    &models.LoginAttempts{},
+		},
+		{
+			name:      "progressive delay after login failures",
+			login:     &models.LoginAttempts{Failures: 3, LastFailureAt: now},
+			ip:        &models.LoginAttempts{},
+			wantRetry: 4 * time.Second,
+		},
+		{
+			name:  "delay is over",
+			login: &models.LoginAttempts{Failures: 3, LastFailureAt: now.Add(-5 * time.Second)},
+			ip:    &models.LoginAttempts{},
+		},
+		{
+			name:  "failures outside the window",
+			login: &models.LoginAttempts{Failures: 4, LastFailureAt: now.Add(-time.Hour)},
+			ip:    &models.LoginAttempts{},
+		},
+		{
+			name:      "client address locked out",
+			login:     &models.LoginAttempts{},
+			ip:        &models.LoginAttempts{Failures: 20, LastFailureAt: now, LockedUntil: &lockedUntil},
+			wantRetry: 5 * time.Minute,
+		},
+		{
+			name:  "lockout is over",
+			login: &models.LoginAttempts{Failures: 5, LastFailureAt: now.Add(-11 * time.Minute), LockedUntil: &expiredLock},
+			ip:    &models.LoginAttempts{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewLoginAttemptRepo(t)
+			repo.On("GetLoginAttempts", mock.Anything, "login:gopher").Return(tt.login, nil)
+			repo.On("GetLoginAttempts", mock.Anything, "ip:10.0.0.1").Return(tt.ip, nil)
+
+			g := NewLoginGuard(repo, testLoginGuardConfig)
+			err := g.Check(context.Background(), "gopher", "10.0.0.1")
+			if tt.wantRetry == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrTooManyLoginAttempts)
+
+			var throttled *LoginThrottledError
+			require.ErrorAs(t, err, &throttled)
+			assert.InDelta(t, tt.wantRetry, throttled.RetryAfter, float64(time.Second))
+		})
+	}
+}
+
+func TestLoginGuard_Failure(t *testing.T) {
+	repo := mocks.NewLoginAttemptRepo(t)
+	repo.
+		On("RecordLoginFailure", mock.Anything, "login:gopher", mock.Anything, testLoginGuardConfig.Window).
+		Return(&models.LoginAttempts{Key: "login:gopher", Failures: 5}, nil)
+	repo.
+		On("RecordLoginFailure", mock.Anything, "ip:10.0.0.1", mock.Anything, testLoginGuardConfig.Window).
+		Return(&models.LoginAttempts{Key: "ip:10.0.0.1", Failures: 5}, nil)
+	repo.
+		On("LockLogin", mock.Anything, "login:gopher", mock.Anything).
+		Return(nil).
+		Once()
+
+	g := NewLoginGuard(repo, testLoginGuardConfig)
+	require.NoError(t, g.Failure(context.Background(), "gopher", "10.0.0.1"))
+
+	// The client address is below its own limit, only the login is locked out.
+	repo.AssertNotCalled(t, "LockLogin", mock.Anything, "ip:10.0.0.1", mock.Anything)
+}
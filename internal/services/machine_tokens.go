@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"slices"
+	"time"
+)
+
+// MachineTokenPrefix starts every machine token, so it can be told apart from a JWT access token.
+const MachineTokenPrefix = "gmt_"
+
+// maxTokenNameLen is the maximum length of a machine token name.
+const maxTokenNameLen = 255
+
+// MachineTokenManager is a service for scoped machine tokens,
+// long-lived access tokens of a user for services like an analytics job.
+// Machine tokens are opaque random strings rather than JWTs, so they outlive
+// the rotation of the token signing keys; the SessionManager looks them up by hash.
+type MachineTokenManager struct {
+	repo   MachineTokenRepo
+	maxTTL time.Duration
+}
+
+// NewMachineTokenManager creates a new machine token manager.
+// Machine tokens live at most maxTTL.
+func NewMachineTokenManager(repo MachineTokenRepo, maxTTL time.Duration) *MachineTokenManager {
+	return &MachineTokenManager{
+		repo:   repo,
+		maxTTL: maxTTL,
+	}
+}
+
+// IssueMachineToken issues a machine token for the user of the given access token claims.
+// The token may have only the scopes the access token has, except the account and admin scopes,
+// so a machine token can neither manage the account nor issue other tokens.
+// The token is returned only once, the repository keeps only its hash.
+func (m *MachineTokenManager) IssueMachineToken(ctx context.Context, claims *models.CustomJWTClaims,
+	req *models.MachineTokenRequest) (*models.MachineToken, error) {
+	if req.Name == "" || len(req.Name) > maxTokenNameLen {
+		return nil, ErrInvalidTokenName
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+
+	for _, scope := range req.Scopes {
+		if scope == models.ScopeAccount || scope == models.ScopeAdmin || !claims.HasScope(scope) {
+			return nil, ErrInvalidScopes
+		}
+	}
+
+	ttl := time.Duration(req.TTL) * time.Second
+	if req.TTL == 0 {
+		ttl = m.maxTTL
+	}
+
+	if ttl <= 0 || ttl > m.maxTTL {
+		return nil, ErrInvalidTokenTTL
+	}
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	tokenID, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	token := MachineTokenPrefix + secret
+	now := time.Now()
+	t := &models.MachineToken{
+		TokenID:   tokenID,
+		UserID:    claims.UserID,
+		TokenHash: hashToken(token),
+		Name:      req.Name,
+		Scopes:    scopes,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if err = m.repo.CreateMachineToken(ctx, t); err != nil {
+		return nil, err
+	}
+
+	t.Token = token
+
+	return t, nil
+}
+
+// GetMachineTokens returns the machine tokens of a user, newest first, without the tokens themselves.
+func (m *MachineTokenManager) GetMachineTokens(ctx context.Context, userID int64) ([]*models.MachineToken, error) {
+	return m.repo.GetMachineTokens(ctx, userID)
+}
+
+// RevokeMachineToken revokes a machine token of a user.
+// If the token does not exist, ErrMachineTokenNotFound is returned.
+func (m *MachineTokenManager) RevokeMachineToken(ctx context.Context, userID int64, tokenID string) error {
+	return m.repo.RevokeMachineToken(ctx, userID, tokenID)
+}
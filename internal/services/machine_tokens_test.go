@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMachineTokenManager_IssueMachineToken(t *testing.T) {
+	claims := &models.CustomJWTClaims{UserID: 1}
+
+	tests := []struct {
+		name       string
+		req        *models.MachineTokenRequest
+		wantScopes models.ScopeList
+		wantTTL    time.Duration
+		wantErr    error
+	}{
+		{
+			name:       "scopes are sorted and deduplicated",
+			req:        &models.MachineTokenRequest{Name: "analytics", Scopes: []string{"orders:read", "balance:read", "orders:read"}, TTL: 3600},
+			wantScopes: models.ScopeList{"balance:read", "orders:read"},
+			wantTTL:    time.Hour,
+		},
+		{
+			name:       "maximum ttl by default",
+			req:        &models.MachineTokenRequest{Name: "analytics", Scopes: []string{"orders:read"}},
+			wantScopes: models.ScopeList{"orders:read"},
+			wantTTL:    24 * time.Hour,
+		},
+		{
+			name:    "empty name",
+			req:     &models.MachineTokenRequest{Scopes: []string{"orders:read"}},
+			wantErr: ErrInvalidTokenName,
+		},
+		{
+			name:    "no scopes",
+			req:     &models.MachineTokenRequest{Name: "analytics"},
+			wantErr: ErrInvalidScopes,
+		},
+		{
+			name:    "account scope",
+			req:     &models.MachineTokenRequest{Name: "analytics", Scopes: []string{"account"}},
+			wantErr: ErrInvalidScopes,
+		},
+		{
+			name:    "scope the user does not have",
+			req:     &models.MachineTokenRequest{Name: "analytics", Scopes: []string{"admin"}},
+			wantErr: ErrInvalidScopes,
+		},
+		{
+			name:    "ttl over the maximum",
+			req:     &models.MachineTokenRequest{Name: "analytics", Scopes: []string{"orders:read"}, TTL: 90000},
+			wantErr: ErrInvalidTokenTTL,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewMachineTokenRepo(t)
+			m := NewMachineTokenManager(repo, 24*time.Hour)
+
+			var stored *models.MachineToken
+			if tt.wantErr == nil {
+				repo.
+					On("CreateMachineToken", mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						stored = args.Get(1).(*models.MachineToken)
+					}).
+					Return(nil)
+			}
+
+			got, err := m.IssueMachineToken(context.Background(), claims, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(got.Token, MachineTokenPrefix))
+			assert.Equal(t, hashToken(got.Token), stored.TokenHash)
+			assert.Equal(t, int64(1), stored.UserID)
+			assert.Equal(t, tt.wantScopes, stored.Scopes)
+			assert.Equal(t, tt.wantTTL, stored.ExpiresAt.Sub(stored.CreatedAt))
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"strings"
+	"time"
+)
+
+const (
+	// APIKeyPrefix starts every API key, so it can be told apart from other credentials.
+	APIKeyPrefix = "gmk_"
+	// apiKeyLookupLen is the number of random bytes in the lookup prefix of an API key.
+	apiKeyLookupLen = 6
+	// maxMerchantLen is the maximum length of a merchant name.
+	maxMerchantLen = 255
+)
+
+// MerchantManager is a service for merchant backends which submit orders on behalf of users.
+// Merchants authenticate with API keys issued by administrators.
+type MerchantManager struct {
+	repo   APIKeyRepo
+	orders Orders
+}
+
+// NewMerchantManager creates a new merchant manager.
+func NewMerchantManager(repo APIKeyRepo, orders Orders) *MerchantManager {
+	return &MerchantManager{
+		repo:   repo,
+		orders: orders,
+	}
+}
+
+// IssueAPIKey issues an API key of a merchant.
+// The key is returned only once, the repository keeps its prefix and the hash of its secret.
+func (m *MerchantManager) IssueAPIKey(ctx context.Context, req *models.APIKeyRequest) (*models.APIKey, error) {
+	if req.Merchant == "" || len(req.Merchant) > maxMerchantLen {
+		return nil, ErrInvalidMerchant
+	}
+
+	if req.TTL < 0 {
+		return nil, ErrInvalidAPIKeyTTL
+	}
+
+	b := make([]byte, apiKeyLookupLen)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.Join(ErrGenerateToken, err)
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := hex.EncodeToString(b)
+	k := &models.APIKey{
+		Merchant:   req.Merchant,
+		Prefix:     prefix,
+		SecretHash: hashToken(secret),
+		CreatedAt:  time.Now(),
+	}
+
+	if req.TTL > 0 {
+		expiresAt := k.CreatedAt.Add(time.Duration(req.TTL) * time.Second)
+		k.ExpiresAt = &expiresAt
+	}
+
+	if err = m.repo.CreateAPIKey(ctx, k); err != nil {
+		return nil, err
+	}
+
+	k.Key = APIKeyPrefix + prefix + "." + secret
+
+	return k, nil
+}
+
+// GetAPIKeys returns all API keys, newest first, without the keys themselves.
+func (m *MerchantManager) GetAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	return m.repo.GetAPIKeys(ctx)
+}
+
+// RevokeAPIKey revokes an API key.
+// If the key does not exist, ErrAPIKeyNotFound is returned.
+func (m *MerchantManager) RevokeAPIKey(ctx context.Context, keyID int64) error {
+	return m.repo.RevokeAPIKey(ctx, keyID)
+}
+
+// AuthenticateAPIKey looks an API key up by its prefix and checks its secret.
+// If the key is malformed, unknown or its secret does not match, ErrInvalidAPIKey is returned.
+func (m *MerchantManager) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	prefix, secret, ok := strings.Cut(strings.TrimPrefix(key, APIKeyPrefix), ".")
+	if !ok || !strings.HasPrefix(key, APIKeyPrefix) || prefix == "" || secret == "" {
+		return nil, ErrInvalidAPIKey
+	}
+
+	k, err := m.repo.GetAPIKeyByPrefix(ctx, prefix)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(k.SecretHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if k.RevokedAt != nil {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	if k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	return k, nil
+}
+
+// UploadOrder uploads an order on behalf of the user with the given login.
+// If the user does not exist, ErrUserNotFound is returned,
+// otherwise the order is created as if the user uploaded it.
+func (m *MerchantManager) UploadOrder(ctx context.Context, req *models.MerchantOrder) error {
+	user, err := m.repo.GetUserByLogin(ctx, req.Login)
+	if err != nil {
+		return err
+	}
+
+	return m.orders.CreateNewOrder(ctx, user.UserID, req.Number)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMerchantManager_IssueAPIKey(t *testing.T) {
+	repo := mocks.NewAPIKeyRepo(t)
+	m := NewMerchantManager(repo, nil)
+
+	var stored *models.APIKey
+	repo.
+		On("CreateAPIKey", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.APIKey)
+		}).
+		Return(nil)
+
+	key, err := m.IssueAPIKey(context.Background(), &models.APIKeyRequest{Merchant: "shop", TTL: 3600})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(key.Key, APIKeyPrefix+stored.Prefix+"."))
+	assert.Equal(t, hashToken(strings.TrimPrefix(key.Key, APIKeyPrefix+stored.Prefix+".")), stored.SecretHash)
+	require.NotNil(t, stored.ExpiresAt)
+	assert.Equal(t, time.Hour, stored.ExpiresAt.Sub(stored.CreatedAt))
+
+	_, err = m.IssueAPIKey(context.Background(), &models.APIKeyRequest{})
+	assert.ErrorIs(t, err, ErrInvalidMerchant)
+
+	_, err = m.IssueAPIKey(context.Background(), &models.APIKeyRequest{Merchant: "shop", TTL: -1})
+	assert.ErrorIs(t, err, ErrInvalidAPIKeyTTL)
+}
+
+func TestMerchantManager_AuthenticateAPIKey(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	revoked := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		key     string
+		stored  *models.APIKey
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:   "valid key",
+			key:    "gmk_0a1b2c3d4e5f.secret",
+			stored: &models.APIKey{KeyID: 1, Merchant: "shop", Prefix: "0a1b2c3d4e5f", SecretHash: hashToken("secret")},
+		},
+		{
+			name:    "wrong secret",
+			key:     "gmk_0a1b2c3d4e5f.guess",
+			stored:  &models.APIKey{KeyID: 1, Merchant: "shop", Prefix: "0a1b2c3d4e5f", SecretHash: hashToken("secret")},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "unknown prefix",
+			key:     "gmk_0a1b2c3d4e5f.secret",
+			repoErr: ErrAPIKeyNotFound,
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "expired key",
+			key:     "gmk_0a1b2c3d4e5f.secret",
+			stored:  &models.APIKey{KeyID: 1, Prefix: "0a1b2c3d4e5f", SecretHash: hashToken("secret"), ExpiresAt: &expired},
+			wantErr: ErrAPIKeyExpired,
+		},
+		{
+			name:    "revoked key",
+			key:     "gmk_0a1b2c3d4e5f.secret",
+			stored:  &models.APIKey{KeyID: 1, Prefix: "0a1b2c3d4e5f", SecretHash: hashToken("secret"), RevokedAt: &revoked},
+			wantErr: ErrAPIKeyRevoked,
+		},
+		{
+			name:    "malformed key",
+			key:     "0a1b2c3d4e5f.secret",
+			wantErr: ErrInvalidAPIKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewAPIKeyRepo(t)
+			m := NewMerchantManager(repo, nil)
+
+			if tt.stored != nil || tt.repoErr != nil {
+				repo.On("GetAPIKeyByPrefix", mock.Anything, "0a1b2c3d4e5f").Return(tt.stored, tt.repoErr)
+			}
+
+			got, err := m.AuthenticateAPIKey(context.Background(), tt.key)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.stored, got)
+			}
+		})
+	}
+}
+
+func TestMerchantManager_UploadOrder(t *testing.T) {
+	repo := mocks.NewAPIKeyRepo(t)
+	orders := mocks.NewOrders(t)
+	m := NewMerchantManager(repo, orders)
+
+	repo.On("GetUserByLogin", mock.Anything, "gopher").Return(&models.User{UserID: 1, Login: "gopher"}, nil)
+	repo.On("GetUserByLogin", mock.Anything, "nobody").Return(nil, ErrUserNotFound)
+	orders.On("CreateNewOrder", mock.Anything, int64(1), "79927398713").Return(nil).Once()
+
+	err := m.UploadOrder(context.Background(), &models.MerchantOrder{Login: "gopher", Number: "79927398713"})
+	assert.NoError(t, err)
+
+	err = m.UploadOrder(context.Background(), &models.MerchantOrder{Login: "nobody", Number: "79927398713"})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
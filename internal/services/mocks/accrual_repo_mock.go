@@ -0,0 +1,407 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AccrualRepo is an autogenerated mock type for the AccrualRepo type
+type AccrualRepo struct {
+	mock.Mock
+}
+
+// ChangePassword provides a mock function with given fields: ctx, userID, hashedPasswd
+func (_m *AccrualRepo) ChangePassword(ctx context.Context, userID int64, hashedPasswd string) error {
+	ret := _m.Called(ctx, userID, hashedPasswd)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, hashedPasswd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrder provides a mock function with given fields: ctx, order
+func (_m *AccrualRepo) CreateOrder(ctx context.Context, order models.Order) error {
+	ret := _m.Called(ctx, order)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, models.Order) error); ok {
+		r0 = rf(ctx, order)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateOrders provides a mock function with given fields: ctx, orders
+func (_m *AccrualRepo) CreateOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error) {
+	ret := _m.Called(ctx, orders)
+
+	var r0 []*models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Order) ([]*models.Order, error)); ok {
+		return rf(ctx, orders)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Order) []*models.Order); ok {
+		r0 = rf(ctx, orders)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Order) error); ok {
+		r1 = rf(ctx, orders)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateUser provides a mock function with given fields: ctx, login, hashedPasswd
+func (_m *AccrualRepo) CreateUser(ctx context.Context, login string, hashedPasswd string) (int64, error) {
+	ret := _m.Called(ctx, login, hashedPasswd)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int64, error)); ok {
+		return rf(ctx, login, hashedPasswd)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
+		r0 = rf(ctx, login, hashedPasswd)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, login, hashedPasswd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userID, forfeitBalance
+func (_m *AccrualRepo) DeleteUser(ctx context.Context, userID int64, forfeitBalance bool) error {
+	ret := _m.Called(ctx, userID, forfeitBalance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) error); ok {
+		r0 = rf(ctx, userID, forfeitBalance)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DoWithdrawal provides a mock function with given fields: ctx, w
+func (_m *AccrualRepo) DoWithdrawal(ctx context.Context, w *models.Withdrawal) error {
+	ret := _m.Called(ctx, w)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Withdrawal) error); ok {
+		r0 = rf(ctx, w)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLedgerEntries provides a mock function with given fields: ctx, userID
+func (_m *AccrualRepo) GetLedgerEntries(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.LedgerEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.LedgerEntry, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.LedgerEntry); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.LedgerEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderByNumber provides a mock function with given fields: ctx, orderNum
+func (_m *AccrualRepo) GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error) {
+	ret := _m.Called(ctx, orderNum)
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Order, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Order); ok {
+		r0 = rf(ctx, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderList provides a mock function with given fields: ctx, q
+func (_m *AccrualRepo) GetOrderList(ctx context.Context, q *models.ListQuery) ([]*models.Order, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 []*models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Order, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Order); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderStatusHistory provides a mock function with given fields: ctx, orderNum
+func (_m *AccrualRepo) GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error) {
+	ret := _m.Called(ctx, orderNum)
+
+	var r0 []*models.OrderStatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.OrderStatusChange, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.OrderStatusChange); ok {
+		r0 = rf(ctx, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OrderStatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPendingAccrualTasks provides a mock function with given fields: ctx
+func (_m *AccrualRepo) GetPendingAccrualTasks(ctx context.Context) ([]*models.AccrualTask, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []*models.AccrualTask
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]*models.AccrualTask, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []*models.AccrualTask); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AccrualTask)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserAccount provides a mock function with given fields: ctx, userID
+func (_m *AccrualRepo) GetUserAccount(ctx context.Context, userID int64) (*models.UserAccount, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.UserAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.UserAccount, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.UserAccount); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByID provides a mock function with given fields: ctx, userID
+func (_m *AccrualRepo) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.User, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByLogin provides a mock function with given fields: ctx, login
+func (_m *AccrualRepo) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
+	ret := _m.Called(ctx, login)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.User, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.User); ok {
+		r0 = rf(ctx, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWithdrawalList provides a mock function with given fields: ctx, q
+func (_m *AccrualRepo) GetWithdrawalList(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 []*models.Withdrawal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Withdrawal, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Withdrawal); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Withdrawal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateAccrualTask provides a mock function with given fields: ctx, task
+func (_m *AccrualRepo) UpdateAccrualTask(ctx context.Context, task *models.AccrualTask) error {
+	ret := _m.Called(ctx, task)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.AccrualTask) error); ok {
+		r0 = rf(ctx, task)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateOrderStatus provides a mock function with given fields: ctx, c
+func (_m *AccrualRepo) UpdateOrderStatus(ctx context.Context, c *models.OrderStatusChange) (bool, error) {
+	ret := _m.Called(ctx, c)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderStatusChange) (bool, error)); ok {
+		return rf(ctx, c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderStatusChange) bool); ok {
+		r0 = rf(ctx, c)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.OrderStatusChange) error); ok {
+		r1 = rf(ctx, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAccrualRepo creates a new instance of AccrualRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAccrualRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AccrualRepo {
+	mock := &AccrualRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,199 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Admin is an autogenerated mock type for the Admin type
+type Admin struct {
+	mock.Mock
+}
+
+// AdjustBalance provides a mock function with given fields: ctx, adj
+func (_m *Admin) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error) {
+	ret := _m.Called(ctx, adj)
+
+	var r0 *models.UserAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BalanceAdjustment) (*models.UserAccount, error)); ok {
+		return rf(ctx, adj)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BalanceAdjustment) *models.UserAccount); ok {
+		r0 = rf(ctx, adj)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.BalanceAdjustment) error); ok {
+		r1 = rf(ctx, adj)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditLog provides a mock function with given fields: ctx, userID, limit
+func (_m *Admin) GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error) {
+	ret := _m.Called(ctx, userID, limit)
+
+	var r0 []*models.AuditEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]*models.AuditEntry, error)); ok {
+		return rf(ctx, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []*models.AuditEntry); ok {
+		r0 = rf(ctx, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrder provides a mock function with given fields: ctx, orderNum
+func (_m *Admin) GetOrder(ctx context.Context, orderNum string) (*models.OrderDetail, error) {
+	ret := _m.Called(ctx, orderNum)
+
+	var r0 *models.OrderDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.OrderDetail, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.OrderDetail); ok {
+		r0 = rf(ctx, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OrderDetail)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUser provides a mock function with given fields: ctx, userID
+func (_m *Admin) GetUser(ctx context.Context, userID int64) (*models.AdminUser, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.AdminUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.AdminUser, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.AdminUser); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AdminUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecheckOrder provides a mock function with given fields: ctx, adminID, orderNum, reason
+func (_m *Admin) RecheckOrder(ctx context.Context, adminID int64, orderNum string, reason string) error {
+	ret := _m.Called(ctx, adminID, orderNum, reason)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string) error); ok {
+		r0 = rf(ctx, adminID, orderNum, reason)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ReverseWithdrawal provides a mock function with given fields: ctx, rev
+func (_m *Admin) ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error) {
+	ret := _m.Called(ctx, rev)
+
+	var r0 *models.Withdrawal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WithdrawalReversal) (*models.Withdrawal, error)); ok {
+		return rf(ctx, rev)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WithdrawalReversal) *models.Withdrawal); ok {
+		r0 = rf(ctx, rev)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Withdrawal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WithdrawalReversal) error); ok {
+		r1 = rf(ctx, rev)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, login, limit
+func (_m *Admin) SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error) {
+	ret := _m.Called(ctx, login, limit)
+
+	var r0 []*models.AdminUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*models.AdminUser, error)); ok {
+		return rf(ctx, login, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*models.AdminUser); ok {
+		r0 = rf(ctx, login, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AdminUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, login, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAdmin creates a new instance of Admin. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAdmin(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Admin {
+	mock := &Admin{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
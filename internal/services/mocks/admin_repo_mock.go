@@ -0,0 +1,225 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// AdminRepo is an autogenerated mock type for the AdminRepo type
+type AdminRepo struct {
+	mock.Mock
+}
+
+// AddAuditEntry provides a mock function with given fields: ctx, e
+func (_m *AdminRepo) AddAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	ret := _m.Called(ctx, e)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.AuditEntry) error); ok {
+		r0 = rf(ctx, e)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AdjustBalance provides a mock function with given fields: ctx, adj
+func (_m *AdminRepo) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error) {
+	ret := _m.Called(ctx, adj)
+
+	var r0 *models.UserAccount
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BalanceAdjustment) (*models.UserAccount, error)); ok {
+		return rf(ctx, adj)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.BalanceAdjustment) *models.UserAccount); ok {
+		r0 = rf(ctx, adj)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserAccount)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.BalanceAdjustment) error); ok {
+		r1 = rf(ctx, adj)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAdminUser provides a mock function with given fields: ctx, userID
+func (_m *AdminRepo) GetAdminUser(ctx context.Context, userID int64) (*models.AdminUser, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.AdminUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.AdminUser, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.AdminUser); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.AdminUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAuditLog provides a mock function with given fields: ctx, userID, limit
+func (_m *AdminRepo) GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error) {
+	ret := _m.Called(ctx, userID, limit)
+
+	var r0 []*models.AuditEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) ([]*models.AuditEntry, error)); ok {
+		return rf(ctx, userID, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int) []*models.AuditEntry); ok {
+		r0 = rf(ctx, userID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int) error); ok {
+		r1 = rf(ctx, userID, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderByNumber provides a mock function with given fields: ctx, orderNum
+func (_m *AdminRepo) GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error) {
+	ret := _m.Called(ctx, orderNum)
+
+	var r0 *models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.Order, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Order); ok {
+		r0 = rf(ctx, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrderStatusHistory provides a mock function with given fields: ctx, orderNum
+func (_m *AdminRepo) GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error) {
+	ret := _m.Called(ctx, orderNum)
+
+	var r0 []*models.OrderStatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.OrderStatusChange, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.OrderStatusChange); ok {
+		r0 = rf(ctx, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OrderStatusChange)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReverseWithdrawal provides a mock function with given fields: ctx, rev
+func (_m *AdminRepo) ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error) {
+	ret := _m.Called(ctx, rev)
+
+	var r0 *models.Withdrawal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WithdrawalReversal) (*models.Withdrawal, error)); ok {
+		return rf(ctx, rev)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.WithdrawalReversal) *models.Withdrawal); ok {
+		r0 = rf(ctx, rev)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Withdrawal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.WithdrawalReversal) error); ok {
+		r1 = rf(ctx, rev)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchUsers provides a mock function with given fields: ctx, login, limit
+func (_m *AdminRepo) SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error) {
+	ret := _m.Called(ctx, login, limit)
+
+	var r0 []*models.AdminUser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]*models.AdminUser, error)); ok {
+		return rf(ctx, login, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []*models.AdminUser); ok {
+		r0 = rf(ctx, login, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.AdminUser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, login, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAdminRepo creates a new instance of AdminRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAdminRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AdminRepo {
+	mock := &AdminRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -5,6 +5,8 @@ package mocks
 import (
 	models "github.com/leonf08/gophermart.git/internal/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Authenticator is an autogenerated mock type for the Authenticator type
@@ -50,28 +52,49 @@ func (_m *Authenticator) GenerateHashFromPassword(user *models.User) (string, er
 	return r0, r1
 }
 
-// GenerateToken provides a mock function with given fields: user
-func (_m *Authenticator) GenerateToken(user *models.User) (string, error) {
-	ret := _m.Called(user)
+// GenerateToken provides a mock function with given fields: user, sessionID
+func (_m *Authenticator) GenerateToken(user *models.User, sessionID string) (string, time.Time, error) {
+	ret := _m.Called(user, sessionID)
 
 	var r0 string
-	var r1 error
-	if rf, ok := ret.Get(0).(func(*models.User) (string, error)); ok {
-		return rf(user)
+	var r1 time.Time
+	var r2 error
+	if rf, ok := ret.Get(0).(func(*models.User, string) (string, time.Time, error)); ok {
+		return rf(user, sessionID)
 	}
-	if rf, ok := ret.Get(0).(func(*models.User) string); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(*models.User, string) string); ok {
+		r0 = rf(user, sessionID)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(*models.User) error); ok {
-		r1 = rf(user)
+	if rf, ok := ret.Get(1).(func(*models.User, string) time.Time); ok {
+		r1 = rf(user, sessionID)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(time.Time)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(*models.User, string) error); ok {
+		r2 = rf(user, sessionID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// JWKS provides a mock function with given fields:
+func (_m *Authenticator) JWKS() models.JWKS {
+	ret := _m.Called()
+
+	var r0 models.JWKS
+	if rf, ok := ret.Get(0).(func() models.JWKS); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(models.JWKS)
+	}
+
+	return r0
 }
 
 // ValidateTokenAndExtractClaims provides a mock function with given fields: token
@@ -0,0 +1,111 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// LoginAttemptRepo is an autogenerated mock type for the LoginAttemptRepo type
+type LoginAttemptRepo struct {
+	mock.Mock
+}
+
+// GetLoginAttempts provides a mock function with given fields: ctx, key
+func (_m *LoginAttemptRepo) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	ret := _m.Called(ctx, key)
+
+	var r0 *models.LoginAttempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.LoginAttempts, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.LoginAttempts); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempts)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LockLogin provides a mock function with given fields: ctx, key, until
+func (_m *LoginAttemptRepo) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ret := _m.Called(ctx, key, until)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time) error); ok {
+		r0 = rf(ctx, key, until)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RecordLoginFailure provides a mock function with given fields: ctx, key, at, window
+func (_m *LoginAttemptRepo) RecordLoginFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	ret := _m.Called(ctx, key, at, window)
+
+	var r0 *models.LoginAttempts
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) (*models.LoginAttempts, error)); ok {
+		return rf(ctx, key, at, window)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Duration) *models.LoginAttempts); ok {
+		r0 = rf(ctx, key, at, window)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.LoginAttempts)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Duration) error); ok {
+		r1 = rf(ctx, key, at, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResetLoginAttempts provides a mock function with given fields: ctx, key
+func (_m *LoginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	ret := _m.Called(ctx, key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewLoginAttemptRepo creates a new instance of LoginAttemptRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewLoginAttemptRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *LoginAttemptRepo {
+	mock := &LoginAttemptRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
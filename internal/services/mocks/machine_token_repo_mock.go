@@ -0,0 +1,83 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// MachineTokenRepo is an autogenerated mock type for the MachineTokenRepo type
+type MachineTokenRepo struct {
+	mock.Mock
+}
+
+// CreateMachineToken provides a mock function with given fields: ctx, t
+func (_m *MachineTokenRepo) CreateMachineToken(ctx context.Context, t *models.MachineToken) error {
+	ret := _m.Called(ctx, t)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.MachineToken) error); ok {
+		r0 = rf(ctx, t)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetMachineTokens provides a mock function with given fields: ctx, userID
+func (_m *MachineTokenRepo) GetMachineTokens(ctx context.Context, userID int64) ([]*models.MachineToken, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.MachineToken
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.MachineToken, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.MachineToken); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.MachineToken)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeMachineToken provides a mock function with given fields: ctx, userID, tokenID
+func (_m *MachineTokenRepo) RevokeMachineToken(ctx context.Context, userID int64, tokenID string) error {
+	ret := _m.Called(ctx, userID, tokenID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, tokenID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewMachineTokenRepo creates a new instance of MachineTokenRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMachineTokenRepo(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MachineTokenRepo {
+	mock := &MachineTokenRepo{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
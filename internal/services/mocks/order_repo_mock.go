@@ -28,6 +28,32 @@ func (_m *OrderRepo) CreateOrder(ctx context.Context, order models.Order) error
 	return r0
 }
 
+// CreateOrders provides a mock function with given fields: ctx, orders
+func (_m *OrderRepo) CreateOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error) {
+	ret := _m.Called(ctx, orders)
+
+	var r0 []*models.Order
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Order) ([]*models.Order, error)); ok {
+		return rf(ctx, orders)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []models.Order) []*models.Order); ok {
+		r0 = rf(ctx, orders)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []models.Order) error); ok {
+		r1 = rf(ctx, orders)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOrderByNumber provides a mock function with given fields: ctx, orderNum
 func (_m *OrderRepo) GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error) {
 	ret := _m.Called(ctx, orderNum)
@@ -54,25 +80,25 @@ func (_m *OrderRepo) GetOrderByNumber(ctx context.Context, orderNum string) (*mo
 	return r0, r1
 }
 
-// GetOrderList provides a mock function with given fields: ctx, userID
-func (_m *OrderRepo) GetOrderList(ctx context.Context, userID int64) ([]*models.Order, error) {
-	ret := _m.Called(ctx, userID)
+// GetOrderList provides a mock function with given fields: ctx, q
+func (_m *OrderRepo) GetOrderList(ctx context.Context, q *models.ListQuery) ([]*models.Order, error) {
+	ret := _m.Called(ctx, q)
 
 	var r0 []*models.Order
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.Order, error)); ok {
-		return rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Order, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.Order); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Order); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Order)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) error); ok {
+		r1 = rf(ctx, q)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -80,18 +106,54 @@ func (_m *OrderRepo) GetOrderList(ctx context.Context, userID int64) ([]*models.
 	return r0, r1
 }
 
-// UpdateOrder provides a mock function with given fields: ctx, order
-func (_m *OrderRepo) UpdateOrder(ctx context.Context, order *models.Order) error {
-	ret := _m.Called(ctx, order)
+// GetOrderStatusHistory provides a mock function with given fields: ctx, orderNum
+func (_m *OrderRepo) GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error) {
+	ret := _m.Called(ctx, orderNum)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.Order) error); ok {
-		r0 = rf(ctx, order)
+	var r0 []*models.OrderStatusChange
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]*models.OrderStatusChange, error)); ok {
+		return rf(ctx, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.OrderStatusChange); ok {
+		r0 = rf(ctx, orderNum)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.OrderStatusChange)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, orderNum)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateOrderStatus provides a mock function with given fields: ctx, c
+func (_m *OrderRepo) UpdateOrderStatus(ctx context.Context, c *models.OrderStatusChange) (bool, error) {
+	ret := _m.Called(ctx, c)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderStatusChange) (bool, error)); ok {
+		return rf(ctx, c)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.OrderStatusChange) bool); ok {
+		r0 = rf(ctx, c)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.OrderStatusChange) error); ok {
+		r1 = rf(ctx, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewOrderRepo creates a new instance of OrderRepo. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
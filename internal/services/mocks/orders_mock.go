@@ -28,25 +28,51 @@ func (_m *Orders) CreateNewOrder(ctx context.Context, userID int64, orderNum str
 	return r0
 }
 
-// GetOrdersForUser provides a mock function with given fields: ctx, userID
-func (_m *Orders) GetOrdersForUser(ctx context.Context, userID int64) ([]*models.Order, error) {
-	ret := _m.Called(ctx, userID)
+// CreateOrderBatch provides a mock function with given fields: ctx, userID, orderNums
+func (_m *Orders) CreateOrderBatch(ctx context.Context, userID int64, orderNums []string) ([]*models.OrderBatchResult, error) {
+	ret := _m.Called(ctx, userID, orderNums)
 
-	var r0 []*models.Order
+	var r0 []*models.OrderBatchResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.Order, error)); ok {
-		return rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) ([]*models.OrderBatchResult, error)); ok {
+		return rf(ctx, userID, orderNums)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.Order); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, []string) []*models.OrderBatchResult); ok {
+		r0 = rf(ctx, userID, orderNums)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*models.Order)
+			r0 = ret.Get(0).([]*models.OrderBatchResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, []string) error); ok {
+		r1 = rf(ctx, userID, orderNums)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOrder provides a mock function with given fields: ctx, userID, orderNum
+func (_m *Orders) GetOrder(ctx context.Context, userID int64, orderNum string) (*models.OrderDetail, error) {
+	ret := _m.Called(ctx, userID, orderNum)
+
+	var r0 *models.OrderDetail
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) (*models.OrderDetail, error)); ok {
+		return rf(ctx, userID, orderNum)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) *models.OrderDetail); ok {
+		r0 = rf(ctx, userID, orderNum)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OrderDetail)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string) error); ok {
+		r1 = rf(ctx, userID, orderNum)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -54,6 +80,41 @@ func (_m *Orders) GetOrdersForUser(ctx context.Context, userID int64) ([]*models
 	return r0, r1
 }
 
+// GetOrdersForUser provides a mock function with given fields: ctx, q
+func (_m *Orders) GetOrdersForUser(ctx context.Context, q *models.ListQuery) ([]*models.Order, *models.Cursor, error) {
+	ret := _m.Called(ctx, q)
+
+	var r0 []*models.Order
+	var r1 *models.Cursor
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Order, *models.Cursor, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Order); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Order)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) *models.Cursor); ok {
+		r1 = rf(ctx, q)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*models.Cursor)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *models.ListQuery) error); ok {
+		r2 = rf(ctx, q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // NewOrders creates a new instance of Orders. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewOrders(t interface {
@@ -0,0 +1,121 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Sessions is an autogenerated mock type for the Sessions type
+type Sessions struct {
+	mock.Mock
+}
+
+// Authenticate provides a mock function with given fields: ctx, token
+func (_m *Sessions) Authenticate(ctx context.Context, token string) (*models.CustomJWTClaims, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *models.CustomJWTClaims
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.CustomJWTClaims, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.CustomJWTClaims); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.CustomJWTClaims)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateSession provides a mock function with given fields: ctx, user
+func (_m *Sessions) CreateSession(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	ret := _m.Called(ctx, user)
+
+	var r0 *models.TokenPair
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) (*models.TokenPair, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) *models.TokenPair); ok {
+		r0 = rf(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TokenPair)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshSession provides a mock function with given fields: ctx, refreshToken
+func (_m *Sessions) RefreshSession(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	ret := _m.Called(ctx, refreshToken)
+
+	var r0 *models.TokenPair
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*models.TokenPair, error)); ok {
+		return rf(ctx, refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.TokenPair); ok {
+		r0 = rf(ctx, refreshToken)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TokenPair)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeSession provides a mock function with given fields: ctx, claims
+func (_m *Sessions) RevokeSession(ctx context.Context, claims *models.CustomJWTClaims) error {
+	ret := _m.Called(ctx, claims)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.CustomJWTClaims) error); ok {
+		r0 = rf(ctx, claims)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewSessions creates a new instance of Sessions. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewSessions(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Sessions {
+	mock := &Sessions{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
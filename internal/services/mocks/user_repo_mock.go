@@ -14,18 +14,56 @@ type UserRepo struct {
 	mock.Mock
 }
 
+// ChangePassword provides a mock function with given fields: ctx, userID, hashedPasswd
+func (_m *UserRepo) ChangePassword(ctx context.Context, userID int64, hashedPasswd string) error {
+	ret := _m.Called(ctx, userID, hashedPasswd)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string) error); ok {
+		r0 = rf(ctx, userID, hashedPasswd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateUser provides a mock function with given fields: ctx, login, hashedPasswd
-func (_m *UserRepo) CreateUser(ctx context.Context, login, hashedPasswd string) (int64, error) {
+func (_m *UserRepo) CreateUser(ctx context.Context, login string, hashedPasswd string) (int64, error) {
 	ret := _m.Called(ctx, login, hashedPasswd)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (int64, error)); ok {
+		return rf(ctx, login, hashedPasswd)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) int64); ok {
 		r0 = rf(ctx, login, hashedPasswd)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, login, hashedPasswd)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userID, forfeitBalance
+func (_m *UserRepo) DeleteUser(ctx context.Context, userID int64, forfeitBalance bool) error {
+	ret := _m.Called(ctx, userID, forfeitBalance)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, bool) error); ok {
+		r0 = rf(ctx, userID, forfeitBalance)
 	} else {
 		r0 = ret.Error(0)
 	}
 
-	return 0, r0
+	return r0
 }
 
 // DoWithdrawal provides a mock function with given fields: ctx, w
@@ -42,6 +80,32 @@ func (_m *UserRepo) DoWithdrawal(ctx context.Context, w *models.Withdrawal) erro
 	return r0
 }
 
+// GetLedgerEntries provides a mock function with given fields: ctx, userID
+func (_m *UserRepo) GetLedgerEntries(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.LedgerEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.LedgerEntry, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.LedgerEntry); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.LedgerEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserAccount provides a mock function with given fields: ctx, userID
 func (_m *UserRepo) GetUserAccount(ctx context.Context, userID int64) (*models.UserAccount, error) {
 	ret := _m.Called(ctx, userID)
@@ -68,6 +132,32 @@ func (_m *UserRepo) GetUserAccount(ctx context.Context, userID int64) (*models.U
 	return r0, r1
 }
 
+// GetUserByID provides a mock function with given fields: ctx, userID
+func (_m *UserRepo) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*models.User, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *models.User); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserByLogin provides a mock function with given fields: ctx, login
 func (_m *UserRepo) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
 	ret := _m.Called(ctx, login)
@@ -94,25 +184,25 @@ func (_m *UserRepo) GetUserByLogin(ctx context.Context, login string) (*models.U
 	return r0, r1
 }
 
-// GetWithdrawalList provides a mock function with given fields: ctx, userID
-func (_m *UserRepo) GetWithdrawalList(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-	ret := _m.Called(ctx, userID)
+// GetWithdrawalList provides a mock function with given fields: ctx, q
+func (_m *UserRepo) GetWithdrawalList(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, error) {
+	ret := _m.Called(ctx, q)
 
 	var r0 []*models.Withdrawal
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.Withdrawal, error)); ok {
-		return rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Withdrawal, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.Withdrawal); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Withdrawal); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Withdrawal)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) error); ok {
+		r1 = rf(ctx, q)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -14,23 +14,53 @@ type Users struct {
 	mock.Mock
 }
 
-// GetToken provides a mock function with given fields: user
-func (_m *Users) GetToken(user *models.User) (string, error) {
-	ret := _m.Called(user)
+// ChangePassword provides a mock function with given fields: ctx, userID, req, clientIP
+func (_m *Users) ChangePassword(ctx context.Context, userID int64, req *models.PasswordChangeRequest, clientIP string) error {
+	ret := _m.Called(ctx, userID, req, clientIP)
 
-	var r0 string
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *models.PasswordChangeRequest, string) error); ok {
+		r0 = rf(ctx, userID, req, clientIP)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userID, req, clientIP
+func (_m *Users) DeleteUser(ctx context.Context, userID int64, req *models.AccountDeletionRequest, clientIP string) error {
+	ret := _m.Called(ctx, userID, req, clientIP)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *models.AccountDeletionRequest, string) error); ok {
+		r0 = rf(ctx, userID, req, clientIP)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetLedger provides a mock function with given fields: ctx, userID
+func (_m *Users) GetLedger(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.LedgerEntry
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*models.User) (string, error)); ok {
-		return rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.LedgerEntry, error)); ok {
+		return rf(ctx, userID)
 	}
-	if rf, ok := ret.Get(0).(func(*models.User) string); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.LedgerEntry); ok {
+		r0 = rf(ctx, userID)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.LedgerEntry)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*models.User) error); ok {
-		r1 = rf(user)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -64,39 +94,48 @@ func (_m *Users) GetUserAccount(ctx context.Context, userID int64) (*models.User
 	return r0, r1
 }
 
-// GetWithdrawals provides a mock function with given fields: ctx, userID
-func (_m *Users) GetWithdrawals(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-	ret := _m.Called(ctx, userID)
+// GetWithdrawals provides a mock function with given fields: ctx, q
+func (_m *Users) GetWithdrawals(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, *models.Cursor, error) {
+	ret := _m.Called(ctx, q)
 
 	var r0 []*models.Withdrawal
-	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.Withdrawal, error)); ok {
-		return rf(ctx, userID)
+	var r1 *models.Cursor
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) ([]*models.Withdrawal, *models.Cursor, error)); ok {
+		return rf(ctx, q)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.Withdrawal); ok {
-		r0 = rf(ctx, userID)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.ListQuery) []*models.Withdrawal); ok {
+		r0 = rf(ctx, q)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]*models.Withdrawal)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
-		r1 = rf(ctx, userID)
+	if rf, ok := ret.Get(1).(func(context.Context, *models.ListQuery) *models.Cursor); ok {
+		r1 = rf(ctx, q)
 	} else {
-		r1 = ret.Error(1)
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*models.Cursor)
+		}
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(context.Context, *models.ListQuery) error); ok {
+		r2 = rf(ctx, q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
-// LoginUser provides a mock function with given fields: ctx, user
-func (_m *Users) LoginUser(ctx context.Context, user *models.User) error {
-	ret := _m.Called(ctx, user)
+// LoginUser provides a mock function with given fields: ctx, user, clientIP
+func (_m *Users) LoginUser(ctx context.Context, user *models.User, clientIP string) error {
+	ret := _m.Called(ctx, user, clientIP)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
-		r0 = rf(ctx, user)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(ctx, user, clientIP)
 	} else {
 		r0 = ret.Error(0)
 	}
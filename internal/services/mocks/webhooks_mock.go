@@ -0,0 +1,121 @@
+// Code generated by mockery v2.37.1. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	models "github.com/leonf08/gophermart.git/internal/models"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Webhooks is an autogenerated mock type for the Webhooks type
+type Webhooks struct {
+	mock.Mock
+}
+
+// CreateWebhook provides a mock function with given fields: ctx, userID, req
+func (_m *Webhooks) CreateWebhook(ctx context.Context, userID int64, req *models.WebhookRequest) (*models.Webhook, error) {
+	ret := _m.Called(ctx, userID, req)
+
+	var r0 *models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *models.WebhookRequest) (*models.Webhook, error)); ok {
+		return rf(ctx, userID, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, *models.WebhookRequest) *models.Webhook); ok {
+		r0 = rf(ctx, userID, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, *models.WebhookRequest) error); ok {
+		r1 = rf(ctx, userID, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteWebhook provides a mock function with given fields: ctx, userID, webhookID
+func (_m *Webhooks) DeleteWebhook(ctx context.Context, userID int64, webhookID int64) error {
+	ret := _m.Called(ctx, userID, webhookID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, userID, webhookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetWebhookDeliveries provides a mock function with given fields: ctx, userID, webhookID
+func (_m *Webhooks) GetWebhookDeliveries(ctx context.Context, userID int64, webhookID int64) ([]*models.WebhookDelivery, error) {
+	ret := _m.Called(ctx, userID, webhookID)
+
+	var r0 []*models.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) ([]*models.WebhookDelivery, error)); ok {
+		return rf(ctx, userID, webhookID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.WebhookDelivery); ok {
+		r0 = rf(ctx, userID, webhookID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, userID, webhookID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhooks provides a mock function with given fields: ctx, userID
+func (_m *Webhooks) GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	ret := _m.Called(ctx, userID)
+
+	var r0 []*models.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]*models.Webhook, error)); ok {
+		return rf(ctx, userID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []*models.Webhook); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWebhooks creates a new instance of Webhooks. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhooks(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *Webhooks {
+	mock := &Webhooks{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
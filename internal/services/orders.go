@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	"github.com/leonf08/gophermart.git/internal/metrics"
 	"github.com/leonf08/gophermart.git/internal/models"
 	"github.com/leonf08/gophermart.git/internal/services/utils"
+	"github.com/leonf08/gophermart.git/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
@@ -13,6 +17,9 @@ type OrderManager struct {
 	accrual Accrual
 }
 
+// MaxOrderBatchSize is the maximum number of orders in a batch upload.
+const MaxOrderBatchSize = 1000
+
 // NewOrderManager creates a new order manager.
 func NewOrderManager(repo OrderRepo, accrual Accrual) *OrderManager {
 	return &OrderManager{
@@ -21,10 +28,21 @@ func NewOrderManager(repo OrderRepo, accrual Accrual) *OrderManager {
 	}
 }
 
+// expectedOrderErrors are the outcomes of invalid or repeated requests,
+// they are not recorded as span errors.
+var expectedOrderErrors = []error{
+	ErrInvalidOrderNumber, ErrInvalidOrderNumberFormat, ErrOrderAlreadyExists, ErrOrderAlreadyExistsForUser,
+	ErrOrderNotFound, ErrEmptyOrderBatch, ErrOrderBatchTooLarge,
+	ErrInvalidListLimit, ErrInvalidStatusFilter, ErrInvalidDateRange,
+}
+
 // CreateNewOrder creates a new order.
 // If the order creation fails, an error is returned.
 // If the order creation succeeds, nil is returned.
-func (o *OrderManager) CreateNewOrder(ctx context.Context, userID int64, orderNum string) error {
+func (o *OrderManager) CreateNewOrder(ctx context.Context, userID int64, orderNum string) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderManager.CreateNewOrder", trace.WithAttributes(tracing.OrderNumber(orderNum)))
+	defer func() { tracing.End(span, err, expectedOrderErrors...) }()
+
 	// Check if the order number is valid.
 	if !utils.IsNumber(orderNum) {
 		return ErrInvalidOrderNumber
@@ -55,26 +73,149 @@ func (o *OrderManager) CreateNewOrder(ctx context.Context, userID int64, orderNu
 		return err
 	}
 
+	metrics.OrderStatusChanged(models.OrderStatusNew)
+
 	// Register order in accrual service.
-	o.accrual.SendOrderAccrual(orderNum)
+	o.accrual.SendOrderAccrual(ctx, orderNum)
 
 	return nil
 }
 
-// GetOrdersForUser returns all orders for a given user.
-// If the order retrieval fails, an error is returned.
-// If the order retrieval succeeds, the orders are returned.
-func (o *OrderManager) GetOrdersForUser(ctx context.Context, userID int64) ([]*models.Order, error) {
-	// Retrieve orders.
-	orders, err := o.repo.GetOrderList(ctx, userID)
+// CreateOrderBatch creates the orders of a batch upload in one transaction.
+// The result of every order number is returned in the order of the batch:
+// accepted if the order is created, duplicate-own if it is already uploaded by the user
+// or repeated in the batch, conflict if it is uploaded by another user
+// and invalid if the number is malformed or fails the Luhn check.
+// If the batch is empty or too large, or the order creation fails, an error is returned.
+func (o *OrderManager) CreateOrderBatch(ctx context.Context, userID int64, orderNums []string) (_ []*models.OrderBatchResult, err error) {
+	ctx, span := tracing.Start(ctx, "OrderManager.CreateOrderBatch", trace.WithAttributes(attribute.Int("order.batch_size", len(orderNums))))
+	defer func() { tracing.End(span, err, expectedOrderErrors...) }()
+
+	if len(orderNums) == 0 {
+		return nil, ErrEmptyOrderBatch
+	}
+
+	if len(orderNums) > MaxOrderBatchSize {
+		return nil, ErrOrderBatchTooLarge
+	}
+
+	results := make([]*models.OrderBatchResult, len(orderNums))
+	orders := make([]models.Order, 0, len(orderNums))
+	seen := make(map[string]bool, len(orderNums))
+	now := time.Now()
+
+	for i, orderNum := range orderNums {
+		results[i] = &models.OrderBatchResult{Number: orderNum, Result: models.OrderBatchAccepted}
+
+		switch {
+		case !utils.IsNumber(orderNum) || !utils.LuhnValidate(orderNum):
+			results[i].Result = models.OrderBatchInvalid
+		case seen[orderNum]:
+			results[i].Result = models.OrderBatchDuplicateOwn
+		default:
+			seen[orderNum] = true
+			orders = append(orders, models.Order{
+				UserID:     userID,
+				Number:     orderNum,
+				Status:     models.OrderStatusNew,
+				UploadedAt: now,
+			})
+		}
+	}
+
+	if len(orders) == 0 {
+		return results, nil
+	}
+
+	existing, err := o.repo.CreateOrders(ctx, orders)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := make(map[string]int64, len(existing))
+	for _, order := range existing {
+		owners[order.Number] = order.UserID
+	}
+
+	for _, res := range results {
+		if res.Result != models.OrderBatchAccepted {
+			continue
+		}
+
+		owner, ok := owners[res.Number]
+		switch {
+		case !ok:
+			metrics.OrderStatusChanged(models.OrderStatusNew)
+
+			// Register the created order in accrual service.
+			o.accrual.SendOrderAccrual(ctx, res.Number)
+		case owner == userID:
+			res.Result = models.OrderBatchDuplicateOwn
+		default:
+			res.Result = models.OrderBatchConflict
+		}
+	}
+
+	return results, nil
+}
+
+// GetOrdersForUser returns a page of orders of a user, oldest first.
+// If there are more orders after the page, a cursor to continue with is returned.
+// If the query is invalid or the order retrieval fails, an error is returned.
+func (o *OrderManager) GetOrdersForUser(ctx context.Context, q *models.ListQuery) (_ []*models.Order, _ *models.Cursor, err error) {
+	ctx, span := tracing.Start(ctx, "OrderManager.GetOrdersForUser")
+	defer func() { tracing.End(span, err, expectedOrderErrors...) }()
+
+	err = validateListQuery(q, models.OrderStatusNew, models.OrderStatusRegistered,
+		models.OrderStatusProcessing, models.OrderStatusInvalid, models.OrderStatusProcessed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Retrieve one order over the limit to know if there is a next page.
+	fetch := *q
+	if fetch.Limit > 0 {
+		fetch.Limit++
+	}
+	orders, err := o.repo.GetOrderList(ctx, &fetch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	orders, next := paginate(orders, q.Limit, func(order *models.Order) *models.Cursor {
+		return &models.Cursor{Time: order.UploadedAt, Key: order.Number}
+	})
+
+	return orders, next, nil
+}
+
+// GetOrder returns an order of a user with its status history.
+// If the order does not exist or belongs to another user, ErrOrderNotFound is returned.
+func (o *OrderManager) GetOrder(ctx context.Context, userID int64, orderNum string) (_ *models.OrderDetail, err error) {
+	ctx, span := tracing.Start(ctx, "OrderManager.GetOrder", trace.WithAttributes(tracing.OrderNumber(orderNum)))
+	defer func() { tracing.End(span, err, expectedOrderErrors...) }()
+
+	if !utils.IsNumber(orderNum) {
+		return nil, ErrInvalidOrderNumber
+	}
+
+	order, err := o.repo.GetOrderByNumber(ctx, orderNum)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert integer accrual to float accrual
-	for _, order := range orders {
-		order.Accrual /= 100
+	// Orders of other users are not disclosed.
+	if order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+
+	history, err := o.repo.GetOrderStatusHistory(ctx, orderNum)
+	if err != nil {
+		return nil, err
 	}
 
-	return orders, nil
+	return &models.OrderDetail{
+		Order:   *order,
+		History: history,
+	}, nil
 }
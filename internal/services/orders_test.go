@@ -9,11 +9,14 @@ import (
 	"github.com/stretchr/testify/mock"
 	"reflect"
 	"testing"
+	"time"
 )
 
 type mockAccrual struct{}
 
-func (m *mockAccrual) SendOrderAccrual(_ string) {}
+func (m *mockAccrual) SendOrderAccrual(_ context.Context, _ string) {}
+
+func (m *mockAccrual) RecheckOrder(_ context.Context, _ string) error { return nil }
 
 func TestNewOrderManager(t *testing.T) {
 	type args struct {
@@ -136,7 +139,7 @@ func TestOrderManager_CreateNewOrder(t *testing.T) {
 	}
 
 	repo.
-		On("GetOrderByNumber", context.Background(), mock.Anything).
+		On("GetOrderByNumber", mock.Anything, mock.Anything).
 		Return(func(ctx context.Context, num string) (*models.Order, error) {
 			if num == "2030" {
 				return nil, errors.New("error")
@@ -152,7 +155,7 @@ func TestOrderManager_CreateNewOrder(t *testing.T) {
 		})
 
 	repo.
-		On("CreateOrder", context.Background(), mock.Anything).
+		On("CreateOrder", mock.Anything, mock.Anything).
 		Return(func(ctx context.Context, order models.Order) error {
 			if order.UserID == 2 {
 				return errors.New("error")
@@ -174,16 +177,94 @@ func TestOrderManager_CreateNewOrder(t *testing.T) {
 	}
 }
 
+func TestOrderManager_CreateOrderBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		orderNums []string
+		existing  []*models.Order
+		want      []string
+		wantErr   error
+	}{
+		{
+			name:    "empty batch",
+			wantErr: ErrEmptyOrderBatch,
+		},
+		{
+			name:      "batch too large",
+			orderNums: make([]string, MaxOrderBatchSize+1),
+			wantErr:   ErrOrderBatchTooLarge,
+		},
+		{
+			name:      "all invalid",
+			orderNums: []string{"12345hfjfh", "79927398710"},
+			want:      []string{models.OrderBatchInvalid, models.OrderBatchInvalid},
+		},
+		{
+			name:      "mixed results",
+			orderNums: []string{"79927398713", "4561261212345467", "12345678903", "79927398713", "1"},
+			existing: []*models.Order{
+				{UserID: 1, Number: "4561261212345467"},
+				{UserID: 2, Number: "12345678903"},
+			},
+			want: []string{
+				models.OrderBatchAccepted,
+				models.OrderBatchDuplicateOwn,
+				models.OrderBatchConflict,
+				models.OrderBatchDuplicateOwn,
+				models.OrderBatchInvalid,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewOrderRepo(t)
+			if tt.existing != nil {
+				repo.
+					On("CreateOrders", mock.Anything, mock.MatchedBy(func(orders []models.Order) bool {
+						return len(orders) == 3
+					})).
+					Return(tt.existing, nil).
+					Once()
+			}
+
+			o := NewOrderManager(repo, &mockAccrual{})
+			got, err := o.CreateOrderBatch(context.Background(), 1, tt.orderNums)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			if assert.Len(t, got, len(tt.want)) {
+				for i, res := range got {
+					assert.Equal(t, tt.orderNums[i], res.Number)
+					assert.Equal(t, tt.want[i], res.Result)
+				}
+			}
+		})
+	}
+}
+
 func TestOrderManager_GetOrdersForUser(t *testing.T) {
 	repo := mocks.NewOrderRepo(t)
 	accr := &mockAccrual{}
 
+	uploadedAt := time.Date(2023, 12, 8, 12, 0, 0, 0, time.UTC)
+	page := []*models.Order{
+		{UserID: 3, Number: "1", UploadedAt: uploadedAt},
+		{UserID: 3, Number: "2", UploadedAt: uploadedAt},
+		{UserID: 3, Number: "3", UploadedAt: uploadedAt},
+	}
+	from := uploadedAt
+	to := uploadedAt.Add(-time.Hour)
+
 	type args struct {
-		userID int64
+		q *models.ListQuery
 	}
 	type want struct {
 		orders []*models.Order
-		err    bool
+		next   *models.Cursor
+		err    error
 	}
 	tests := []struct {
 		name string
@@ -193,7 +274,7 @@ func TestOrderManager_GetOrdersForUser(t *testing.T) {
 		{
 			name: "GetOrdersForUser_no_error",
 			args: args{
-				userID: 1,
+				q: &models.ListQuery{UserID: 1},
 			},
 			want: want{
 				orders: []*models.Order{
@@ -201,30 +282,81 @@ func TestOrderManager_GetOrdersForUser(t *testing.T) {
 						UserID: 1,
 					},
 				},
-				err: false,
+			},
+		},
+		{
+			name: "GetOrdersForUser_all_orders_without_limit",
+			args: args{
+				q: &models.ListQuery{UserID: 3},
+			},
+			want: want{
+				orders: page,
+			},
+		},
+		{
+			name: "GetOrdersForUser_next_page",
+			args: args{
+				q: &models.ListQuery{UserID: 3, Limit: 2},
+			},
+			want: want{
+				orders: page[:2],
+				next:   &models.Cursor{Time: uploadedAt, Key: "2"},
 			},
 		},
 		{
 			name: "GetOrdersForUser_error",
 			args: args{
-				userID: 2,
+				q: &models.ListQuery{UserID: 2},
+			},
+			want: want{
+				err: errors.New("error"),
+			},
+		},
+		{
+			name: "GetOrdersForUser_invalid_limit",
+			args: args{
+				q: &models.ListQuery{UserID: 1, Limit: MaxListLimit + 1},
 			},
 			want: want{
-				orders: nil,
-				err:    true,
+				err: ErrInvalidListLimit,
+			},
+		},
+		{
+			name: "GetOrdersForUser_invalid_status",
+			args: args{
+				q: &models.ListQuery{UserID: 1, Statuses: []string{"UNKNOWN"}},
+			},
+			want: want{
+				err: ErrInvalidStatusFilter,
+			},
+		},
+		{
+			name: "GetOrdersForUser_invalid_date_range",
+			args: args{
+				q: &models.ListQuery{UserID: 1, From: &from, To: &to},
+			},
+			want: want{
+				err: ErrInvalidDateRange,
 			},
 		},
 	}
 
 	repo.
-		On("GetOrderList", context.Background(), mock.Anything).
-		Return(func(ctx context.Context, userID int64) ([]*models.Order, error) {
-			if userID == 1 {
+		On("GetOrderList", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, q *models.ListQuery) ([]*models.Order, error) {
+			switch q.UserID {
+			case 1:
 				return []*models.Order{
 					{
 						UserID: 1,
 					},
 				}, nil
+			case 3:
+				if q.Limit == 0 {
+					return page, nil
+				}
+
+				return page[:q.Limit], nil
 			}
 
 			return nil, errors.New("error")
@@ -236,9 +368,76 @@ func TestOrderManager_GetOrdersForUser(t *testing.T) {
 				repo:    repo,
 				accrual: accr,
 			}
-			got, err := o.GetOrdersForUser(context.Background(), tt.args.userID)
+			got, next, err := o.GetOrdersForUser(context.Background(), tt.args.q)
 			assert.Equal(t, tt.want.orders, got)
-			assert.Equal(t, tt.want.err, err != nil)
+			assert.Equal(t, tt.want.next, next)
+			assert.Equal(t, tt.want.err, err)
+		})
+	}
+}
+
+func TestOrderManager_GetOrder(t *testing.T) {
+	repo := mocks.NewOrderRepo(t)
+
+	order := &models.Order{UserID: 1, Number: "12345678903", Status: models.OrderStatusProcessed, Accrual: 50000}
+	history := []*models.OrderStatusChange{
+		{OrderNumber: "12345678903", Status: models.OrderStatusNew},
+		{OrderNumber: "12345678903", Status: models.OrderStatusProcessed, Accrual: 50000},
+	}
+
+	repo.On("GetOrderByNumber", mock.Anything, "12345678903").Return(order, nil)
+	repo.On("GetOrderByNumber", mock.Anything, "79927398713").Return(nil, ErrOrderNotFound)
+	repo.On("GetOrderStatusHistory", mock.Anything, "12345678903").Return(history, nil).Once()
+
+	type want struct {
+		order *models.OrderDetail
+		err   error
+	}
+	tests := []struct {
+		name     string
+		userID   int64
+		orderNum string
+		want     want
+	}{
+		{
+			name:     "GetOrder_no_error",
+			userID:   1,
+			orderNum: "12345678903",
+			want: want{
+				order: &models.OrderDetail{Order: *order, History: history},
+			},
+		},
+		{
+			name:     "GetOrder_other_user",
+			userID:   2,
+			orderNum: "12345678903",
+			want: want{
+				err: ErrOrderNotFound,
+			},
+		},
+		{
+			name:     "GetOrder_not_found",
+			userID:   1,
+			orderNum: "79927398713",
+			want: want{
+				err: ErrOrderNotFound,
+			},
+		},
+		{
+			name:     "GetOrder_invalid_number",
+			userID:   1,
+			orderNum: "12345abc",
+			want: want{
+				err: ErrInvalidOrderNumber,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewOrderManager(repo, &mockAccrual{})
+			got, err := o.GetOrder(context.Background(), tt.userID, tt.orderNum)
+			assert.Equal(t, tt.want.order, got)
+			assert.ErrorIs(t, err, tt.want.err)
 		})
 	}
 }
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcryptMaxPasswordLen is the maximum length of a password in bytes bcrypt can hash.
+const bcryptMaxPasswordLen = 72
+
+// Credentials policy rules.
+const (
+	RuleMinLength   = "min_length"
+	RuleMaxLength   = "max_length"
+	RuleCharset     = "charset"
+	RuleDenylist    = "denylist"
+	RuleSameAsLogin = "same_as_login"
+)
+
+// CredentialsPolicyConfig is a configuration of the credentials policy.
+type CredentialsPolicyConfig struct {
+	LoginMinLength int
+	LoginMaxLength int
+	// LoginCharset is a regular expression character class body, e.g. a-zA-Z0-9._-
+	LoginCharset      string
+	PasswordMinLength int
+	PasswordMaxLength int
+	// DenylistFile is a file with breached or common passwords, one per line.
+	// Empty lines and lines starting with # are ignored. Optional.
+	DenylistFile string
+}
+
+// CredentialsPolicy checks logins and passwords of new users.
+type CredentialsPolicy struct {
+	cfg      CredentialsPolicyConfig
+	charset  *regexp.Regexp
+	denylist map[string]struct{}
+}
+
+// NewCredentialsPolicy validates the configuration and loads the password denylist.
+func NewCredentialsPolicy(cfg CredentialsPolicyConfig) (*CredentialsPolicy, error) {
+	if cfg.LoginMinLength < 1 || cfg.LoginMaxLength < cfg.LoginMinLength {
+		return nil, fmt.Errorf("invalid login length limits %d..%d", cfg.LoginMinLength, cfg.LoginMaxLength)
+	}
+
+	if cfg.PasswordMinLength < 1 || cfg.PasswordMaxLength < cfg.PasswordMinLength ||
+		cfg.PasswordMaxLength > bcryptMaxPasswordLen {
+		return nil, fmt.Errorf("invalid password length limits %d..%d, at most %d bytes are supported",
+			cfg.PasswordMinLength, cfg.PasswordMaxLength, bcryptMaxPasswordLen)
+	}
+
+	charset, err := regexp.Compile("^[" + cfg.LoginCharset + "]*$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid login charset: %w", err)
+	}
+
+	p := &CredentialsPolicy{
+		cfg:      cfg,
+		charset:  charset,
+		denylist: make(map[string]struct{}),
+	}
+
+	if cfg.DenylistFile != "" {
+		if err = p.loadDenylist(cfg.DenylistFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Validate checks the user credentials against every rule of the policy.
+// If any rule fails, a *CredentialsPolicyError listing all failed rules is returned.
+func (p *CredentialsPolicy) Validate(user *models.User) error {
+	v := &policyViolations{}
+	p.checkLogin(v, user.Login)
+	p.checkPassword(v, user.Login, user.Password)
+
+	return v.err()
+}
+
+// ValidatePassword checks a new password of an existing user against the password rules only,
+// so users whose logins predate the policy can still change their passwords.
+// If any rule fails, a *CredentialsPolicyError listing all failed rules is returned.
+func (p *CredentialsPolicy) ValidatePassword(login, password string) error {
+	v := &policyViolations{}
+	p.checkPassword(v, login, password)
+
+	return v.err()
+}
+
+func (p *CredentialsPolicy) checkLogin(v *policyViolations, login string) {
+	loginLen := utf8.RuneCountInString(login)
+	if loginLen < p.cfg.LoginMinLength {
+		v.add("login", RuleMinLength, "login must be at least %d characters long", p.cfg.LoginMinLength)
+	}
+	if loginLen > p.cfg.LoginMaxLength {
+		v.add("login", RuleMaxLength, "login must be at most %d characters long", p.cfg.LoginMaxLength)
+	}
+	if !p.charset.MatchString(login) {
+		v.add("login", RuleCharset, "login may contain only [%s] characters", p.cfg.LoginCharset)
+	}
+}
+
+func (p *CredentialsPolicy) checkPassword(v *policyViolations, login, password string) {
+	if utf8.RuneCountInString(password) < p.cfg.PasswordMinLength {
+		v.add("password", RuleMinLength, "password must be at least %d characters long", p.cfg.PasswordMinLength)
+	}
+	if len(password) > p.cfg.PasswordMaxLength {
+		v.add("password", RuleMaxLength, "password must be at most %d bytes long", p.cfg.PasswordMaxLength)
+	}
+	if _, ok := p.denylist[strings.ToLower(password)]; ok {
+		v.add("password", RuleDenylist, "password is too common or was found in a data breach")
+	}
+	if password != "" && strings.EqualFold(password, login) {
+		v.add("password", RuleSameAsLogin, "password must differ from login")
+	}
+}
+
+// policyViolations collects failed rules of the credentials policy.
+type policyViolations struct {
+	violations []models.PolicyViolation
+}
+
+func (v *policyViolations) add(field, rule, format string, args ...any) {
+	v.violations = append(v.violations, models.PolicyViolation{
+		Field:   field,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *policyViolations) err() error {
+	if len(v.violations) > 0 {
+		return &CredentialsPolicyError{Violations: v.violations}
+	}
+
+	return nil
+}
+
+func (p *CredentialsPolicy) loadDenylist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("load password denylist: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p.denylist[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("load password denylist: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestPolicy(t *testing.T) *CredentialsPolicy {
+	t.Helper()
+
+	denylist := filepath.Join(t.TempDir(), "denylist.txt")
+	require.NoError(t, os.WriteFile(denylist, []byte("# common passwords\nPassword1\n\nqwertyuiop\n"), 0o600))
+
+	policy, err := NewCredentialsPolicy(CredentialsPolicyConfig{
+		LoginMinLength:    3,
+		LoginMaxLength:    16,
+		LoginCharset:      "a-zA-Z0-9._-",
+		PasswordMinLength: 8,
+		PasswordMaxLength: 72,
+		DenylistFile:      denylist,
+	})
+	require.NoError(t, err)
+
+	return policy
+}
+
+func TestNewCredentialsPolicy(t *testing.T) {
+	valid := CredentialsPolicyConfig{
+		LoginMinLength:    3,
+		LoginMaxLength:    16,
+		LoginCharset:      "a-z",
+		PasswordMinLength: 8,
+		PasswordMaxLength: 72,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(cfg *CredentialsPolicyConfig)
+		wantErr bool
+	}{
+		{
+			name:   "valid config",
+			modify: func(cfg *CredentialsPolicyConfig) {},
+		},
+		{
+			name:    "login max length less than min length",
+			modify:  func(cfg *CredentialsPolicyConfig) { cfg.LoginMaxLength = 2 },
+			wantErr: true,
+		},
+		{
+			name:    "password longer than bcrypt supports",
+			modify:  func(cfg *CredentialsPolicyConfig) { cfg.PasswordMaxLength = 100 },
+			wantErr: true,
+		},
+		{
+			name:    "invalid charset",
+			modify:  func(cfg *CredentialsPolicyConfig) { cfg.LoginCharset = "z-a" },
+			wantErr: true,
+		},
+		{
+			name:    "missing denylist file",
+			modify:  func(cfg *CredentialsPolicyConfig) { cfg.DenylistFile = filepath.Join(t.TempDir(), "missing.txt") },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+
+			_, err := NewCredentialsPolicy(cfg)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestCredentialsPolicy_Validate(t *testing.T) {
+	policy := newTestPolicy(t)
+
+	tests := []struct {
+		name  string
+		user  *models.User
+		rules []string
+	}{
+		{
+			name: "valid credentials",
+			user: &models.User{Login: "gopher.01", Password: "correct horse"},
+		},
+		{
+			name:  "login too long and invalid charset",
+			user:  &models.User{Login: "gopher with spaces", Password: "correct horse"},
+			rules: []string{"login:" + RuleMaxLength, "login:" + RuleCharset},
+		},
+		{
+			name:  "non-ascii login",
+			user:  &models.User{Login: "гофер", Password: "correct horse"},
+			rules: []string{"login:" + RuleCharset},
+		},
+		{
+			name:  "denylisted password, case-insensitive",
+			user:  &models.User{Login: "gopher", Password: "password1"},
+			rules: []string{"password:" + RuleDenylist},
+		},
+		{
+			name:  "password too long for bcrypt",
+			user:  &models.User{Login: "gopher", Password: string(make([]byte, 73))},
+			rules: []string{"password:" + RuleMaxLength},
+		},
+		{
+			name:  "password same as login",
+			user:  &models.User{Login: "gopher_gopher", Password: "Gopher_Gopher"},
+			rules: []string{"password:" + RuleSameAsLogin},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.user)
+			if tt.rules == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrCredentialsPolicy)
+
+			var policyErr *CredentialsPolicyError
+			require.ErrorAs(t, err, &policyErr)
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				rules = append(rules, v.Field+":"+v.Rule)
+			}
+			assert.Equal(t, tt.rules, rules)
+		})
+	}
+}
+
+func TestCredentialsPolicy_ValidatePassword(t *testing.T) {
+	policy := newTestPolicy(t)
+
+	// The login breaks the login rules, they are not checked.
+	assert.NoError(t, policy.ValidatePassword("a b", "correct horse"))
+
+	err := policy.ValidatePassword("a b", "short")
+	var policyErr *CredentialsPolicyError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, []models.PolicyViolation{{
+		Field:   "password",
+		Rule:    RuleMinLength,
+		Message: "password must be at least 8 characters long",
+	}}, policyErr.Violations)
+
+	err = policy.ValidatePassword("gopher_gopher", "Gopher_Gopher")
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, RuleSameAsLogin, policyErr.Violations[0].Rule)
+}
@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"strings"
+)
+
+// SearchUsers gets users from database whose login contains the given substring,
+// ordered by login, at most limit users. Deleted users are included.
+func (r *Repository) SearchUsers(ctx context.Context, login string, limit int) ([]*models.AdminUser, error) {
+	query := `SELECT user_id, login, role, current, withdrawn, deleted_at FROM users
+		WHERE login LIKE '%' || $1::text || '%' ESCAPE '\' ORDER BY login LIMIT $2`
+
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(login)
+	users := make([]*models.AdminUser, 0)
+	err := r.db.SelectContext(ctx, &users, query, escaped, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetAdminUser gets a user from database by user id, deleted users included.
+// If user does not exist, returns services.ErrUserNotFound.
+func (r *Repository) GetAdminUser(ctx context.Context, userID int64) (*models.AdminUser, error) {
+	query := `SELECT user_id, login, role, current, withdrawn, deleted_at FROM users WHERE user_id = $1`
+	user := &models.AdminUser{}
+	err := r.db.GetContext(ctx, user, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetUserRole sets the role of the users with the given logins.
+// Returns the number of updated users.
+func (r *Repository) SetUserRole(ctx context.Context, logins []string, role string) (int64, error) {
+	query := `UPDATE users SET role = $1 WHERE login = ANY($2) AND deleted_at IS NULL AND role <> $1`
+
+	res, err := r.db.ExecContext(ctx, query, role, logins)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// AdjustBalance changes the current balance of a user by the adjustment amount in one transaction,
+// posting the adjustment to the ledger and recording it in the audit log.
+// If user does not exist or was deleted, returns services.ErrUserNotFound.
+// If a debit exceeds the current balance, returns services.ErrInsufficientFunds.
+func (r *Repository) AdjustBalance(ctx context.Context, adj *models.BalanceAdjustment) (*models.UserAccount, error) {
+	queryUpdateAcc := `UPDATE users SET current = current + $1 WHERE user_id = $2 AND deleted_at IS NULL
+		RETURNING user_id, current, withdrawn`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	account := &models.UserAccount{}
+	err = tx.GetContext(ctx, account, queryUpdateAcc, adj.Amount, adj.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+			return nil, services.ErrInsufficientFunds
+		}
+
+		return nil, err
+	}
+
+	err = postLedgerTransfer(ctx, tx, ledgerTransfer{
+		kind:   models.LedgerKindAdjustment,
+		userID: adj.UserID,
+		from:   models.LedgerAccountAdjustment,
+		to:     models.LedgerAccountCurrent,
+		amount: adj.Amount,
+		at:     adj.At,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userID := adj.UserID
+	err = insertAuditEntry(ctx, tx, &models.AuditEntry{
+		AdminID:   adj.AdminID,
+		Action:    models.AuditActionBalanceAdjustment,
+		UserID:    &userID,
+		Amount:    adj.Amount,
+		Reason:    adj.Reason,
+		CreatedAt: adj.At,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// AddAuditEntry records an action of an administrator in the audit log.
+func (r *Repository) AddAuditEntry(ctx context.Context, e *models.AuditEntry) error {
+	return insertAuditEntry(ctx, r.db, e)
+}
+
+// insertAuditEntry records an action of an administrator in the audit log
+// with the given database handle, so it can be a part of a database transaction.
+func insertAuditEntry(ctx context.Context, db sqlx.ExecerContext, e *models.AuditEntry) error {
+	query := `INSERT INTO admin_audit_log (admin_id, action, user_id, order_number, amount, reason, created_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5::bigint, 0), $6, $7)`
+
+	_, err := db.ExecContext(ctx, query, e.AdminID, e.Action, e.UserID, e.OrderNumber, e.Amount, e.Reason, e.CreatedAt)
+
+	return err
+}
+
+// GetAuditLog gets the latest entries of the audit log, newest first, at most limit entries.
+// If userID is not zero, only the entries about the user are returned.
+func (r *Repository) GetAuditLog(ctx context.Context, userID int64, limit int) ([]*models.AuditEntry, error) {
+	query := `SELECT audit_id, admin_id, action, user_id, COALESCE(order_number, '') AS order_number, amount, reason,
+		created_at FROM admin_audit_log WHERE $1::bigint = 0 OR user_id = $1 ORDER BY audit_id DESC LIMIT $2`
+	entries := make([]*models.AuditEntry, 0)
+	err := r.db.SelectContext(ctx, &entries, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
+)
+
+// CreateAPIKey creates a new API key in database and sets its id.
+func (r *Repository) CreateAPIKey(ctx context.Context, k *models.APIKey) error {
+	query := `INSERT INTO api_keys (merchant, prefix, secret_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING key_id`
+
+	return r.db.QueryRowContext(ctx, query, k.Merchant, k.Prefix, k.SecretHash, k.CreatedAt, k.ExpiresAt).Scan(&k.KeyID)
+}
+
+// GetAPIKeyByPrefix gets an API key from database by its lookup prefix.
+// If the key does not exist, returns services.ErrAPIKeyNotFound.
+func (r *Repository) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	query := `SELECT key_id, merchant, prefix, secret_hash, created_at, expires_at, revoked_at
+		FROM api_keys WHERE prefix = $1`
+
+	k := &models.APIKey{}
+	err := r.db.GetContext(ctx, k, query, prefix)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// GetAPIKeys gets all API keys from database, newest first.
+func (r *Repository) GetAPIKeys(ctx context.Context) ([]*models.APIKey, error) {
+	query := `SELECT key_id, merchant, prefix, secret_hash, created_at, expires_at, revoked_at
+		FROM api_keys ORDER BY key_id DESC`
+	keys := make([]*models.APIKey, 0)
+	err := r.db.SelectContext(ctx, &keys, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey marks an API key as revoked.
+// Revoking an already revoked key is a no-op.
+// If the key does not exist, returns services.ErrAPIKeyNotFound.
+func (r *Repository) RevokeAPIKey(ctx context.Context, keyID int64) error {
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE key_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, time.Now(), keyID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
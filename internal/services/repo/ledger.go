@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"time"
+)
+
+// ledgerTransfer is a movement of points between two accounts of a user.
+type ledgerTransfer struct {
+	kind        string
+	userID      int64
+	orderNumber string
+	from        string
+	to          string
+	amount      models.Points
+	at          time.Time
+}
+
+// postLedgerTransfer records a ledger transaction of two entries,
+// debiting one account and crediting the other, within the given database transaction.
+// If recording fails, returns error.
+// If recording succeeds, returns nil.
+func postLedgerTransfer(ctx context.Context, tx *sqlx.Tx, t ledgerTransfer) error {
+	querySeq := `SELECT nextval('ledger_transaction_seq')`
+	queryInsert := `INSERT INTO ledger_entries (transaction_id, user_id, account, kind, amount, order_number, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7), ($1, $2, $8, $4, $9, NULLIF($6, ''), $7)`
+
+	var transactionID int64
+	err := tx.GetContext(ctx, &transactionID, querySeq)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryInsert, transactionID, t.userID, t.from, t.kind, -t.amount, t.orderNumber, t.at,
+		t.to, t.amount)
+
+	return err
+}
+
+// GetLedgerEntries gets all ledger entries of a user ordered by their creation.
+// If query fails, returns error.
+// If query succeeds, returns nil.
+func (r *Repository) GetLedgerEntries(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+	query := `SELECT entry_id, transaction_id, user_id, account, kind, amount, COALESCE(order_number, '') AS order_number,
+		created_at FROM ledger_entries WHERE user_id = $1 ORDER BY entry_id`
+	entries := make([]*models.LedgerEntry, 0)
+	err := r.db.SelectContext(ctx, &entries, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ReconcileLedger compares balances stored in the users table
+// with balances derived from the ledger.
+// If query fails, returns error.
+// If query succeeds, returns ids of users whose balances do not match the ledger.
+func (r *Repository) ReconcileLedger(ctx context.Context) ([]int64, error) {
+	query := `SELECT u.user_id FROM users u
+		LEFT JOIN (
+			SELECT user_id,
+				SUM(amount) FILTER (WHERE account = 'CURRENT') AS current,
+				SUM(amount) FILTER (WHERE account = 'WITHDRAWN') AS withdrawn
+			FROM ledger_entries GROUP BY user_id
+		) l ON l.user_id = u.user_id
+		WHERE COALESCE(u.current, 0) <> COALESCE(l.current, 0) OR COALESCE(u.withdrawn, 0) <> COALESCE(l.withdrawn, 0)
+		ORDER BY u.user_id`
+	userIDs := make([]int64, 0)
+	err := r.db.SelectContext(ctx, &userIDs, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return userIDs, nil
+}
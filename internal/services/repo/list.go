@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"fmt"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"strings"
+)
+
+// listColumns are the columns a listing is filtered and ordered by.
+type listColumns struct {
+	time   string
+	key    string
+	status string
+}
+
+// buildListQuery appends the filters, the keyset condition, the ordering
+// and the limit of a listing query to a select from a table of user items.
+// A zero limit selects all items.
+// The items are ordered by time and key, so the (time, key) cursor
+// of the last item on a page is a unique position to continue from.
+func buildListQuery(selectFrom string, cols listColumns, q *models.ListQuery) (string, []any) {
+	var sb strings.Builder
+	args := []any{q.UserID}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	sb.WriteString(selectFrom)
+	sb.WriteString(" WHERE user_id = $1")
+
+	if len(q.Statuses) > 0 && cols.status != "" {
+		fmt.Fprintf(&sb, " AND %s::text = ANY(%s)", cols.status, arg(q.Statuses))
+	}
+
+	if q.From != nil {
+		fmt.Fprintf(&sb, " AND %s >= %s", cols.time, arg(*q.From))
+	}
+
+	if q.To != nil {
+		fmt.Fprintf(&sb, " AND %s < %s", cols.time, arg(*q.To))
+	}
+
+	if q.Cursor != nil {
+		fmt.Fprintf(&sb, " AND (%s, %s) > (%s, %s)", cols.time, cols.key, arg(q.Cursor.Time), arg(q.Cursor.Key))
+	}
+
+	fmt.Fprintf(&sb, " ORDER BY %s, %s", cols.time, cols.key)
+
+	if q.Limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %s", arg(q.Limit))
+	}
+
+	return sb.String(), args
+}
@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"time"
+)
+
+// GetLoginAttempts gets the failed log in attempts by key from database.
+// If there are no attempts, returns an empty state.
+func (r *Repository) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	query := `SELECT key, failures, last_failure_at, locked_until FROM login_attempts WHERE key = $1`
+
+	attempts := &models.LoginAttempts{}
+	err := r.db.GetContext(ctx, attempts, query, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &models.LoginAttempts{Key: key}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// RecordLoginFailure records a failed log in attempt and returns the updated state.
+// Failures older than the window are forgotten, so the count starts over.
+// Forgotten attempts of other keys are purged as well.
+func (r *Repository) RecordLoginFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	queryPurge := `DELETE FROM login_attempts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $2) AND key <> $3`
+	queryRecord := `INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until`
+
+	since := at.Add(-window)
+	_, err := r.db.ExecContext(ctx, queryPurge, since, at, key)
+	if err != nil {
+		return nil, err
+	}
+
+	attempts := &models.LoginAttempts{}
+	err = r.db.GetContext(ctx, attempts, queryRecord, key, at, since)
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// LockLogin locks out log in attempts by key until the given time.
+func (r *Repository) LockLogin(ctx context.Context, key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $1 WHERE key = $2`
+
+	_, err := r.db.ExecContext(ctx, query, until, key)
+
+	return err
+}
+
+// ResetLoginAttempts forgets the failed log in attempts by key.
+func (r *Repository) ResetLoginAttempts(ctx context.Context, key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1`
+
+	_, err := r.db.ExecContext(ctx, query, key)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
+)
+
+// CreateMachineToken creates a new machine token in database.
+func (r *Repository) CreateMachineToken(ctx context.Context, t *models.MachineToken) error {
+	query := `INSERT INTO machine_tokens (token_id, user_id, token_hash, name, scope, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query, t.TokenID, t.UserID, t.TokenHash, t.Name, t.Scopes, t.CreatedAt, t.ExpiresAt)
+
+	return err
+}
+
+// GetMachineTokenByHash gets a machine token from database by the hash of the token.
+// If the token does not exist, returns services.ErrMachineTokenNotFound.
+func (r *Repository) GetMachineTokenByHash(ctx context.Context, hash string) (*models.MachineToken, error) {
+	query := `SELECT token_id, user_id, token_hash, name, scope, created_at, expires_at, revoked_at
+		FROM machine_tokens WHERE token_hash = $1`
+
+	t := &models.MachineToken{}
+	err := r.db.GetContext(ctx, t, query, hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrMachineTokenNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// GetMachineTokens gets the machine tokens of a user from database, newest first.
+func (r *Repository) GetMachineTokens(ctx context.Context, userID int64) ([]*models.MachineToken, error) {
+	query := `SELECT token_id, user_id, name, scope, created_at, expires_at, revoked_at FROM machine_tokens
+		WHERE user_id = $1 ORDER BY created_at DESC, token_id`
+	tokens := make([]*models.MachineToken, 0)
+	err := r.db.SelectContext(ctx, &tokens, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// RevokeMachineToken marks a machine token of a user as revoked.
+// Revoking an already revoked token is a no-op.
+// If the token does not exist, returns services.ErrMachineTokenNotFound.
+func (r *Repository) RevokeMachineToken(ctx context.Context, userID int64, tokenID string) error {
+	query := `UPDATE machine_tokens SET revoked_at = COALESCE(revoked_at, $1) WHERE token_id = $2 AND user_id = $3`
+
+	res, err := r.db.ExecContext(ctx, query, time.Now(), tokenID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrMachineTokenNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package repo
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"sync"
+	"time"
+)
+
+// MemoryLoginAttempts keeps failed log in attempts in memory.
+// It is suitable for a single instance, the attempts are lost on restart.
+type MemoryLoginAttempts struct {
+	mu        sync.Mutex
+	attempts  map[string]models.LoginAttempts
+	lastPurge time.Time
+}
+
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{
+		attempts: make(map[string]models.LoginAttempts),
+	}
+}
+
+// GetLoginAttempts returns the failed log in attempts by key.
+// If there are no attempts, returns an empty state.
+func (m *MemoryLoginAttempts) GetLoginAttempts(_ context.Context, key string) (*models.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return &models.LoginAttempts{Key: key}, nil
+	}
+
+	return &attempts, nil
+}
+
+// RecordLoginFailure records a failed log in attempt and returns the updated state.
+// Failures older than the window are forgotten, so the count starts over.
+// Forgotten attempts of other keys are purged at most once per window.
+func (m *MemoryLoginAttempts) RecordLoginFailure(_ context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	since := at.Add(-window)
+	if m.lastPurge.Before(since) {
+		for k, a := range m.attempts {
+			if a.LastFailureAt.Before(since) && (a.LockedUntil == nil || a.LockedUntil.Before(at)) {
+				delete(m.attempts, k)
+			}
+		}
+		m.lastPurge = at
+	}
+
+	attempts, ok := m.attempts[key]
+	if !ok || attempts.LastFailureAt.Before(since) {
+		attempts.Key = key
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = at
+	m.attempts[key] = attempts
+
+	return &attempts, nil
+}
+
+// LockLogin locks out log in attempts by key until the given time.
+func (m *MemoryLoginAttempts) LockLogin(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return nil
+	}
+
+	attempts.LockedUntil = &until
+	m.attempts[key] = attempts
+
+	return nil
+}
+
+// ResetLoginAttempts forgets the failed log in attempts by key.
+func (m *MemoryLoginAttempts) ResetLoginAttempts(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+
+	return nil
+}
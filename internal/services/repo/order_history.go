@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/leonf08/gophermart.git/internal/models"
+)
+
+// insertOrderStatusChange appends an entry to the status history of an order,
+// unless the latest entry already has the same status, so polling an order
+// in the same status again does not repeat it in the history.
+// Returns whether the entry was appended.
+func insertOrderStatusChange(ctx context.Context, db sqlx.ExecerContext, c *models.OrderStatusChange) (bool, error) {
+	query := `INSERT INTO order_status_history (order_number, status, accrual, accrual_response, created_at)
+		SELECT $1::varchar, $2::order_status, $3::bigint, $4::text, $5::timestamp
+		WHERE NOT EXISTS (
+			SELECT 1 FROM (
+				SELECT status FROM order_status_history WHERE order_number = $1 ORDER BY history_id DESC LIMIT 1
+			) latest WHERE latest.status = $2
+		)`
+
+	res, err := db.ExecContext(ctx, query, c.OrderNumber, c.Status, c.Accrual, c.AccrualResponse, c.ChangedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// GetOrderStatusHistory gets the status history of an order, oldest change first.
+// If query fails, returns error.
+// If query succeeds, returns nil.
+func (r *Repository) GetOrderStatusHistory(ctx context.Context, orderNum string) ([]*models.OrderStatusChange, error) {
+	query := `SELECT order_number, status, accrual, accrual_response, created_at FROM order_status_history
+		WHERE order_number = $1 ORDER BY history_id`
+	history := make([]*models.OrderStatusChange, 0)
+	err := r.db.SelectContext(ctx, &history, query, orderNum)
+	if err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -2,10 +2,22 @@ package repo
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
 )
 
+// orderWebhookEvents are the webhook events of the final order statuses.
+var orderWebhookEvents = map[string]string{
+	models.OrderStatusProcessed: models.WebhookEventOrderProcessed,
+	models.OrderStatusInvalid:   models.WebhookEventOrderInvalid,
+}
+
 type Repository struct {
 	db *sqlx.DB
 }
@@ -29,12 +41,31 @@ func (r *Repository) CreateUser(ctx context.Context, login, hashedPasswd string)
 }
 
 // GetUserByLogin gets a user from database by login.
-// If user does not exist, returns error.
+// If user does not exist, returns services.ErrUserNotFound.
 // If user exists, returns nil.
 func (r *Repository) GetUserByLogin(ctx context.Context, login string) (*models.User, error) {
-	query := `SELECT user_id, login, password FROM users WHERE login = $1`
+	query := `SELECT user_id, login, password, role FROM users WHERE login = $1 AND deleted_at IS NULL`
 	user := &models.User{}
 	err := r.db.GetContext(ctx, user, query, login)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetUserByID gets a user from database by user id.
+// If user does not exist or was deleted, returns services.ErrUserNotFound.
+func (r *Repository) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
+	query := `SELECT user_id, login, password, role FROM users WHERE user_id = $1 AND deleted_at IS NULL`
+	user := &models.User{}
+	err := r.db.GetContext(ctx, user, query, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrUserNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +73,123 @@ func (r *Repository) GetUserByLogin(ctx context.Context, login string) (*models.
 	return user, nil
 }
 
+// ChangePassword sets a new password hash of a user
+// and revokes all sessions and machine tokens of the user within one transaction.
+// If user does not exist or was deleted, returns services.ErrUserNotFound.
+func (r *Repository) ChangePassword(ctx context.Context, userID int64, hashedPasswd string) error {
+	queryUpdate := `UPDATE users SET password = $1 WHERE user_id = $2 AND deleted_at IS NULL`
+	queryRevoke := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	queryRevokeTokens := `UPDATE machine_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, queryUpdate, hashedPasswd, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrUserNotFound
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, queryRevoke, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeTokens, now, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteUser anonymises a user, revokes all sessions and machine tokens and deletes the webhooks of the user.
+// Orders, withdrawals and ledger entries are kept for accounting.
+// If the user has remaining balance, it is forfeited to the loyalty program
+// when forfeitBalance is true, otherwise services.ErrAccountHasBalance is returned.
+// If user does not exist or was already deleted, returns services.ErrUserNotFound.
+func (r *Repository) DeleteUser(ctx context.Context, userID int64, forfeitBalance bool) error {
+	queryLock := `SELECT current FROM users WHERE user_id = $1 AND deleted_at IS NULL FOR UPDATE`
+	queryForfeit := `UPDATE users SET current = 0 WHERE user_id = $1`
+	queryAnonymise := `UPDATE users SET login = 'deleted-' || user_id, password = '', deleted_at = $1 WHERE user_id = $2`
+	queryRevoke := `UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	queryRevokeTokens := `UPDATE machine_tokens SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`
+	queryWebhooks := `DELETE FROM webhooks WHERE user_id = $1`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current models.Points
+	err = tx.GetContext(ctx, &current, queryLock, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return services.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if current > 0 {
+		if !forfeitBalance {
+			return services.ErrAccountHasBalance
+		}
+
+		_, err = tx.ExecContext(ctx, queryForfeit, userID)
+		if err != nil {
+			return err
+		}
+
+		err = postLedgerTransfer(ctx, tx, ledgerTransfer{
+			kind:   models.LedgerKindForfeiture,
+			userID: userID,
+			from:   models.LedgerAccountCurrent,
+			to:     models.LedgerAccountProgram,
+			amount: current,
+			at:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, queryAnonymise, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevoke, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryRevokeTokens, now, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, queryWebhooks, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // GetUserAccount gets a user account from database by user id.
 // If user account does not exist, returns error.
 // If user account exists, returns nil.
@@ -57,25 +205,82 @@ func (r *Repository) GetUserAccount(ctx context.Context, userID int64) (*models.
 }
 
 // DoWithdrawal does a withdrawal and updates user account.
+// The balance is checked and updated by a single conditional statement
+// within the withdrawal transaction, so concurrent withdrawals
+// can not overdraw the account.
+// A user can make only one withdrawal per order number. If the withdrawal
+// carries an idempotency key which was already used for the same order and sum,
+// the original withdrawal is loaded into w and services.ErrWithdrawalReplayed is returned.
+// The withdrawal webhook event is written to the outbox within the same transaction.
+// If the balance is insufficient, returns services.ErrInsufficientFunds.
 // If withdrawal fails, returns error.
 // If withdrawal succeeds, returns nil.
 func (r *Repository) DoWithdrawal(ctx context.Context, w *models.Withdrawal) error {
-	queryWithdraw := `INSERT INTO withdrawals (user_id, order_number, sum, updated_at) VALUES ($1, $2, $3, $4)`
-	queryUpdateAcc := `UPDATE users SET current = current - $1, withdrawn = withdrawn + $1 WHERE user_id = $2`
+	queryUpdateAcc := `UPDATE users SET current = current - $1, withdrawn = withdrawn + $1
+		WHERE user_id = $2 AND current >= $1`
+	queryWithdraw := `INSERT INTO withdrawals (user_id, order_number, sum, status, updated_at, idempotency_key)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))`
 
-	tx, err := r.db.BeginTx(ctx, nil)
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
 	defer tx.Rollback()
 
-	_, err = tx.ExecContext(ctx, queryWithdraw, w.UserID, w.OrderNumber, w.Sum, w.ProcessedAt)
+	err = findWithdrawalConflict(ctx, tx, w)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, queryUpdateAcc, w.Sum, w.UserID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.CheckViolation {
+			return services.ErrInsufficientFunds
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrInsufficientFunds
+	}
+
+	_, err = tx.ExecContext(ctx, queryWithdraw, w.UserID, w.OrderNumber, w.Sum, w.Status, w.ProcessedAt,
+		w.IdempotencyKey)
+	if err != nil {
+		// A concurrent request has inserted a conflicting withdrawal.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			_ = tx.Rollback()
+			if conflict := findWithdrawalConflict(ctx, r.db, w); conflict != nil {
+				return conflict
+			}
+		}
+
+		return err
+	}
+
+	err = postLedgerTransfer(ctx, tx, ledgerTransfer{
+		kind:        models.LedgerKindWithdrawal,
+		userID:      w.UserID,
+		orderNumber: w.OrderNumber,
+		from:        models.LedgerAccountCurrent,
+		to:          models.LedgerAccountWithdrawn,
+		amount:      w.Sum,
+		at:          w.ProcessedAt,
+	})
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.ExecContext(ctx, queryUpdateAcc, w.Sum, w.UserID)
+	err = insertWebhookEvent(ctx, tx, w.UserID, models.WebhookEventWithdrawal, w, w.ProcessedAt)
 	if err != nil {
 		return err
 	}
@@ -83,13 +288,49 @@ func (r *Repository) DoWithdrawal(ctx context.Context, w *models.Withdrawal) err
 	return tx.Commit()
 }
 
-// GetWithdrawalList gets a list of withdrawals from database by user id.
+// findWithdrawalConflict looks for an existing withdrawal of the user
+// with the same order number or idempotency key.
+// If the idempotency key was used for the same order and sum, the original
+// withdrawal is loaded into w and services.ErrWithdrawalReplayed is returned.
+// If there is no such withdrawal, returns nil.
+func findWithdrawalConflict(ctx context.Context, q sqlx.QueryerContext, w *models.Withdrawal) error {
+	query := `SELECT user_id, order_number, sum, status, updated_at, reversed_at, reversed_by, reversal_reason,
+		COALESCE(idempotency_key, '') AS idempotency_key FROM withdrawals WHERE user_id = $1 AND (order_number = $2 OR idempotency_key = NULLIF($3, ''))`
+	existing := make([]*models.Withdrawal, 0)
+	err := sqlx.SelectContext(ctx, q, &existing, query, w.UserID, w.OrderNumber, w.IdempotencyKey)
+	if err != nil {
+		return err
+	}
+
+	var conflict error
+	for _, e := range existing {
+		if w.IdempotencyKey == "" || e.IdempotencyKey != w.IdempotencyKey {
+			conflict = services.ErrWithdrawalAlreadyExists
+			continue
+		}
+
+		if e.OrderNumber != w.OrderNumber || e.Sum != w.Sum {
+			return services.ErrIdempotencyKeyReused
+		}
+
+		*w = *e
+
+		return services.ErrWithdrawalReplayed
+	}
+
+	return conflict
+}
+
+// GetWithdrawalList gets a page of withdrawals from database by user id,
+// ordered by processing time, at most q.Limit withdrawals if it is set.
 // If list of withdrawals does not exist, returns error.
 // If list of withdrawals exists, returns nil.
-func (r *Repository) GetWithdrawalList(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-	query := `SELECT * FROM withdrawals WHERE user_id = $1`
+func (r *Repository) GetWithdrawalList(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, error) {
+	query, args := buildListQuery(`SELECT user_id, order_number, sum, status, updated_at, reversed_at, reversed_by,
+		reversal_reason FROM withdrawals`,
+		listColumns{time: "updated_at", key: "order_number", status: "status"}, q)
 	withdrawals := make([]*models.Withdrawal, 0)
-	err := r.db.SelectContext(ctx, &withdrawals, query, userID)
+	err := r.db.SelectContext(ctx, &withdrawals, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -97,26 +338,101 @@ func (r *Repository) GetWithdrawalList(ctx context.Context, userID int64) ([]*mo
 	return withdrawals, nil
 }
 
-// CreateOrder creates a new order in database.
+// CreateOrder creates a new order in database
+// and starts its status history with the upload.
 // If order creation fails, returns error.
 // If order creation succeeds, returns nil.
 func (r *Repository) CreateOrder(ctx context.Context, order models.Order) error {
 	query := `INSERT INTO orders (user_id, number, status, created_at) VALUES ($1, $2, $3, $4)`
-	_, err := r.db.ExecContext(ctx, query, order.UserID, order.Number, order.Status, order.UploadedAt)
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, query, order.UserID, order.Number, order.Status, order.UploadedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = insertOrderStatusChange(ctx, tx, &models.OrderStatusChange{
+		OrderNumber: order.Number,
+		Status:      order.Status,
+		ChangedAt:   order.UploadedAt,
+	})
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// CreateOrders creates orders in database in one transaction.
+// The orders which already exist are not changed and are returned
+// as they are in database, the rest of the orders are created.
+func (r *Repository) CreateOrders(ctx context.Context, orders []models.Order) ([]*models.Order, error) {
+	queryInsert := `INSERT INTO orders (user_id, number, status, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (number) DO NOTHING`
+	querySelect := `SELECT user_id, number, status, accrual, created_at FROM orders WHERE number = $1`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var existing []*models.Order
+	for _, order := range orders {
+		res, err := tx.ExecContext(ctx, queryInsert, order.UserID, order.Number, order.Status, order.UploadedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 0 {
+			e := &models.Order{}
+			if err = tx.GetContext(ctx, e, querySelect, order.Number); err != nil {
+				return nil, err
+			}
+
+			existing = append(existing, e)
+			continue
+		}
+
+		_, err = insertOrderStatusChange(ctx, tx, &models.OrderStatusChange{
+			OrderNumber: order.Number,
+			Status:      order.Status,
+			ChangedAt:   order.UploadedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
 }
 
 // GetOrderByNumber gets an order from database by order number.
-// If order does not exist, returns error.
+// If order does not exist, returns services.ErrOrderNotFound.
 // If order exists, returns nil.
 func (r *Repository) GetOrderByNumber(ctx context.Context, orderNum string) (*models.Order, error) {
-	query := `SELECT * FROM orders WHERE number = $1`
+	query := `SELECT user_id, number, status, accrual, created_at FROM orders WHERE number = $1`
 	order := &models.Order{}
 	err := r.db.GetContext(ctx, order, query, orderNum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrOrderNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -124,13 +440,15 @@ func (r *Repository) GetOrderByNumber(ctx context.Context, orderNum string) (*mo
 	return order, nil
 }
 
-// GetOrderList gets a list of orders from database by user id.
+// GetOrderList gets a page of orders from database by user id,
+// ordered by upload time, at most q.Limit orders if it is set.
 // If list of orders does not exist, returns error.
 // If list of orders exists, returns nil.
-func (r *Repository) GetOrderList(ctx context.Context, userID int64) ([]*models.Order, error) {
-	query := `SELECT * FROM orders WHERE user_id = $1`
+func (r *Repository) GetOrderList(ctx context.Context, q *models.ListQuery) ([]*models.Order, error) {
+	query, args := buildListQuery(`SELECT user_id, number, status, accrual, created_at FROM orders`,
+		listColumns{time: "created_at", key: "number", status: "status"}, q)
 	orders := make([]*models.Order, 0)
-	err := r.db.SelectContext(ctx, &orders, query, userID)
+	err := r.db.SelectContext(ctx, &orders, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -138,40 +456,122 @@ func (r *Repository) GetOrderList(ctx context.Context, userID int64) ([]*models.
 	return orders, nil
 }
 
-// UpdateOrder updates an order.
+// UpdateOrderStatus applies a status change reported by the accrual system to an order
+// and appends it to the status history of the order within the same transaction.
+// The REGISTERED status is only recorded in the history, it does not change the order.
+// The order row is locked for the duration of the transaction and orders
+// in a final status are never updated again, so the accrual is credited
+// to the user exactly once, when the order moves to the PROCESSED status.
+// Moving to a final status writes the order webhook event to the outbox within the same transaction.
 // If update fails, returns error.
-// If update succeeds, returns nil.
-func (r *Repository) UpdateOrder(ctx context.Context, order *models.Order) error {
-	if order.Accrual == 0 {
-		query := `UPDATE orders SET status = $1 WHERE number = $2`
-		_, err := r.db.ExecContext(ctx, query, order.Status, order.Number)
-		if err != nil {
-			return err
-		}
+// If update succeeds, returns whether the status history was appended, false if the order
+// is already in the same or a final status.
+func (r *Repository) UpdateOrderStatus(ctx context.Context, c *models.OrderStatusChange) (bool, error) {
+	querySelect := `SELECT user_id, status, created_at FROM orders WHERE number = $1 FOR UPDATE`
+	queryUpdateOrder := `UPDATE orders SET status = $1, accrual = $2 WHERE number = $3`
+	queryCreditOrder := `UPDATE orders SET accrual_credited_at = $1 WHERE number = $2`
+	queryUpdateAcc := `UPDATE users SET current = current + $1 WHERE user_id = $2`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	stored := &models.Order{}
+	err = tx.GetContext(ctx, stored, querySelect, c.OrderNumber)
+	if err != nil {
+		return false, err
+	}
+
+	if stored.Status == models.OrderStatusProcessed || stored.Status == models.OrderStatusInvalid {
+		return false, nil
+	}
 
-	} else {
-		queryUpdateOrder := `UPDATE orders SET status = $1, accrual = $2 WHERE number = $3`
-		queryUpdateAcc := `UPDATE users SET current = current + $1 WHERE user_id = $2`
+	changed, err := insertOrderStatusChange(ctx, tx, c)
+	if err != nil {
+		return false, err
+	}
 
-		tx, err := r.db.BeginTx(ctx, nil)
+	if c.Status == models.OrderStatusRegistered {
+		return changed, tx.Commit()
+	}
+
+	var accrual models.Points
+	if c.Status == models.OrderStatusProcessed {
+		accrual = c.Accrual
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdateOrder, c.Status, accrual, c.OrderNumber)
+	if err != nil {
+		return false, err
+	}
+
+	if accrual > 0 {
+		now := time.Now()
+		_, err = tx.ExecContext(ctx, queryCreditOrder, now, c.OrderNumber)
 		if err != nil {
-			return err
+			return false, err
 		}
 
-		defer tx.Rollback()
+		_, err = tx.ExecContext(ctx, queryUpdateAcc, accrual, stored.UserID)
+		if err != nil {
+			return false, err
+		}
 
-		_, err = tx.ExecContext(ctx, queryUpdateOrder, order.Status, order.Accrual, order.Number)
+		err = postLedgerTransfer(ctx, tx, ledgerTransfer{
+			kind:        models.LedgerKindAccrual,
+			userID:      stored.UserID,
+			orderNumber: c.OrderNumber,
+			from:        models.LedgerAccountProgram,
+			to:          models.LedgerAccountCurrent,
+			amount:      accrual,
+			at:          now,
+		})
 		if err != nil {
-			return err
+			return false, err
 		}
+	}
 
-		_, err = tx.ExecContext(ctx, queryUpdateAcc, order.Accrual, order.UserID)
+	if eventType, ok := orderWebhookEvents[c.Status]; ok {
+		err = insertWebhookEvent(ctx, tx, stored.UserID, eventType, &models.Order{
+			Number:     c.OrderNumber,
+			Status:     c.Status,
+			Accrual:    accrual,
+			UploadedAt: stored.UploadedAt,
+		}, time.Now())
 		if err != nil {
-			return err
+			return false, err
 		}
+	}
+
+	return changed, tx.Commit()
+}
 
-		return tx.Commit()
+// GetPendingAccrualTasks gets polling state of all orders which are not in a final status yet.
+// Dead-lettered orders are skipped.
+// If query fails, returns error.
+// If query succeeds, returns tasks ordered by the next attempt time.
+func (r *Repository) GetPendingAccrualTasks(ctx context.Context) ([]*models.AccrualTask, error) {
+	query := `SELECT number, accrual_attempts, accrual_next_at, accrual_last_error, accrual_dead_at FROM orders
+		WHERE status NOT IN ('INVALID', 'PROCESSED') AND accrual_dead_at IS NULL ORDER BY accrual_next_at`
+	tasks := make([]*models.AccrualTask, 0)
+	err := r.db.SelectContext(ctx, &tasks, query)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return tasks, nil
+}
+
+// UpdateAccrualTask saves polling state of an order.
+// If update fails, returns error.
+// If update succeeds, returns nil.
+func (r *Repository) UpdateAccrualTask(ctx context.Context, task *models.AccrualTask) error {
+	query := `UPDATE orders SET accrual_attempts = $1, accrual_next_at = $2, accrual_last_error = $3, accrual_dead_at = $4
+		WHERE number = $5`
+	_, err := r.db.ExecContext(ctx, query, task.Attempts, task.NextAttemptAt, task.LastError, task.DeadAt, task.OrderNumber)
+
+	return err
 }
@@ -0,0 +1,103 @@
+package repo
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
+)
+
+// CreateSession creates a new session in database.
+// If session creation fails, returns error.
+func (r *Repository) CreateSession(ctx context.Context, s *models.Session) error {
+	query := `INSERT INTO sessions (session_id, user_id, refresh_token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	_, err := r.db.ExecContext(ctx, query, s.SessionID, s.UserID, s.RefreshTokenHash, s.CreatedAt, s.ExpiresAt)
+
+	return err
+}
+
+// GetSessionByRefreshHash gets a session from database by the hash
+// of its current or previous refresh token, with the current role of its user.
+// If session does not exist, returns error.
+func (r *Repository) GetSessionByRefreshHash(ctx context.Context, hash string) (*models.Session, error) {
+	query := `SELECT s.session_id, s.user_id, s.refresh_token_hash,
+		COALESCE(s.previous_token_hash, '') AS previous_token_hash, s.created_at, s.expires_at, s.revoked_at, u.role
+		FROM sessions s JOIN users u ON u.user_id = s.user_id
+		WHERE s.refresh_token_hash = $1 OR s.previous_token_hash = $1`
+
+	session := &models.Session{}
+	err := r.db.GetContext(ctx, session, query, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// RotateSession replaces the refresh token of a session with a new one
+// and extends the session until s.ExpiresAt.
+// The session is updated only if its refresh token is still s.RefreshTokenHash,
+// so a refresh token can be exchanged only once even by concurrent requests.
+// Otherwise, returns services.ErrInvalidRefreshToken.
+func (r *Repository) RotateSession(ctx context.Context, s *models.Session, newHash string) error {
+	query := `UPDATE sessions SET previous_token_hash = refresh_token_hash, refresh_token_hash = $1, expires_at = $2
+		WHERE session_id = $3 AND refresh_token_hash = $4 AND revoked_at IS NULL`
+
+	res, err := r.db.ExecContext(ctx, query, newHash, s.ExpiresAt, s.SessionID, s.RefreshTokenHash)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrInvalidRefreshToken
+	}
+
+	s.PreviousTokenHash, s.RefreshTokenHash = s.RefreshTokenHash, newHash
+
+	return nil
+}
+
+// RevokeSession marks a session as revoked.
+// Revoking an already revoked session is a no-op.
+func (r *Repository) RevokeSession(ctx context.Context, sessionID string) error {
+	query := `UPDATE sessions SET revoked_at = $1 WHERE session_id = $2 AND revoked_at IS NULL`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), sessionID)
+
+	return err
+}
+
+// RevokeToken records an access token id as revoked until the token expires.
+// Revoked tokens which have already expired are purged.
+func (r *Repository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	queryPurge := `DELETE FROM revoked_tokens WHERE expires_at < $1`
+	queryRevoke := `INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING`
+
+	_, err := r.db.ExecContext(ctx, queryPurge, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, queryRevoke, jti, expiresAt)
+
+	return err
+}
+
+// IsTokenRevoked reports whether an access token was revoked
+// either by its id or together with its session.
+func (r *Repository) IsTokenRevoked(ctx context.Context, jti, sessionID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+		OR EXISTS (SELECT 1 FROM sessions WHERE session_id = $2 AND revoked_at IS NOT NULL)`
+
+	var revoked bool
+	err := r.db.QueryRowContext(ctx, query, jti, sessionID).Scan(&revoked)
+
+	return revoked, err
+}
@@ -0,0 +1,168 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
+)
+
+// insertWebhookEvent writes an event to the webhook outbox within the given database transaction,
+// so the event is sent if and only if the change it reports is committed.
+// If insertion fails, returns error.
+// If insertion succeeds, returns nil.
+func insertWebhookEvent(ctx context.Context, tx *sqlx.Tx, userID int64, eventType string, data any, at time.Time) error {
+	query := `INSERT INTO webhook_outbox (user_id, event_type, payload, created_at) VALUES ($1, $2, $3, $4)`
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, query, userID, eventType, string(payload), at)
+
+	return err
+}
+
+// CreateWebhook creates a new webhook of a user and sets its id.
+// If creation fails, returns error.
+// If creation succeeds, returns nil.
+func (r *Repository) CreateWebhook(ctx context.Context, w *models.Webhook) error {
+	query := `INSERT INTO webhooks (user_id, url, secret, events, created_at) VALUES ($1, $2, $3, $4, $5)
+		RETURNING webhook_id`
+
+	return r.db.GetContext(ctx, &w.WebhookID, query, w.UserID, w.URL, w.Secret, w.Events, w.CreatedAt)
+}
+
+// GetWebhooks gets the webhooks of a user without their secrets.
+// If query fails, returns error.
+// If query succeeds, returns nil.
+func (r *Repository) GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	query := `SELECT webhook_id, user_id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY webhook_id`
+	webhooks := make([]*models.Webhook, 0)
+	err := r.db.SelectContext(ctx, &webhooks, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes a webhook of a user with its delivery log.
+// If the user has no such webhook, returns services.ErrWebhookNotFound.
+func (r *Repository) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	query := `DELETE FROM webhooks WHERE webhook_id = $1 AND user_id = $2`
+
+	res, err := r.db.ExecContext(ctx, query, webhookID, userID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return services.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries gets the latest deliveries of a webhook of a user, newest first.
+// If the user has no such webhook, returns services.ErrWebhookNotFound.
+func (r *Repository) GetWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]*models.WebhookDelivery, error) {
+	queryWebhook := `SELECT webhook_id FROM webhooks WHERE webhook_id = $1 AND user_id = $2`
+	queryDeliveries := `SELECT d.delivery_id, d.webhook_id, d.outbox_id, o.event_type, d.status, d.attempts,
+			COALESCE(d.response_status, 0) AS response_status, COALESCE(d.last_error, '') AS last_error,
+			d.created_at, d.next_attempt_at, d.delivered_at
+		FROM webhook_deliveries d JOIN webhook_outbox o ON o.outbox_id = d.outbox_id
+		WHERE d.webhook_id = $1 ORDER BY d.delivery_id DESC LIMIT $2`
+
+	var id int64
+	err := r.db.GetContext(ctx, &id, queryWebhook, webhookID, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	err = r.db.SelectContext(ctx, &deliveries, queryDeliveries, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// DispatchWebhookOutbox turns up to limit new outbox events into pending deliveries,
+// one per webhook of the event user subscribed to the event type.
+// Concurrent dispatchers skip the events locked by each other.
+// If dispatching fails, returns error.
+// If dispatching succeeds, returns the number of dispatched events.
+func (r *Repository) DispatchWebhookOutbox(ctx context.Context, at time.Time, limit int) (int64, error) {
+	query := `WITH batch AS (
+			SELECT outbox_id, user_id, event_type FROM webhook_outbox
+			WHERE dispatched_at IS NULL ORDER BY outbox_id LIMIT $2 FOR UPDATE SKIP LOCKED
+		), fanout AS (
+			INSERT INTO webhook_deliveries (outbox_id, webhook_id, status, attempts, next_attempt_at, created_at)
+			SELECT b.outbox_id, w.webhook_id, 'PENDING', 0, $1, $1 FROM batch b
+			JOIN webhooks w ON w.user_id = b.user_id AND b.event_type = ANY(string_to_array(w.events, ','))
+		)
+		UPDATE webhook_outbox SET dispatched_at = $1 WHERE outbox_id IN (SELECT outbox_id FROM batch)`
+
+	res, err := r.db.ExecContext(ctx, query, at, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// ClaimWebhookDeliveries gets up to limit pending deliveries due at now with their endpoints and events.
+// The claimed deliveries are not due again until leaseUntil, so concurrent workers
+// do not send them twice and the deliveries of a crashed worker are retried after the lease.
+// If query fails, returns error.
+// If query succeeds, returns nil.
+func (r *Repository) ClaimWebhookDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	query := `WITH due AS (
+			SELECT delivery_id FROM webhook_deliveries
+			WHERE status = 'PENDING' AND next_attempt_at <= $1 ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_deliveries d SET next_attempt_at = $2 FROM due WHERE d.delivery_id = due.delivery_id
+			RETURNING d.delivery_id, d.webhook_id, d.outbox_id, d.status, d.attempts, d.created_at, d.next_attempt_at
+		)
+		SELECT c.delivery_id, c.webhook_id, c.outbox_id, o.event_type, c.status, c.attempts, c.created_at,
+			c.next_attempt_at, w.url, w.secret, o.payload, o.created_at AS event_at
+		FROM claimed c
+		JOIN webhooks w ON w.webhook_id = c.webhook_id
+		JOIN webhook_outbox o ON o.outbox_id = c.outbox_id`
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	err := r.db.SelectContext(ctx, &deliveries, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// UpdateWebhookDelivery saves the result of a delivery attempt.
+// If update fails, returns error.
+// If update succeeds, returns nil.
+func (r *Repository) UpdateWebhookDelivery(ctx context.Context, d *models.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt_at = $3,
+		response_status = NULLIF($4, 0), last_error = NULLIF($5, ''), delivered_at = $6 WHERE delivery_id = $7`
+
+	_, err := r.db.ExecContext(ctx, query, d.Status, d.Attempts, d.NextAttemptAt, d.ResponseStatus, d.LastError,
+		d.DeliveredAt, d.DeliveryID)
+
+	return err
+}
@@ -0,0 +1,109 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services"
+	"time"
+)
+
+// ReverseWithdrawal reverses a pending withdrawal of a user in one transaction:
+// the sum is moved back from withdrawn to current points, the reversal is posted
+// to the ledger and the withdrawal is kept with the reversed status, who reversed it and why.
+// The reversal is recorded in the audit log within the same transaction.
+// If the withdrawal does not exist, returns services.ErrWithdrawalNotFound.
+// If the withdrawal is not pending, returns services.ErrWithdrawalNotReversible.
+func (r *Repository) ReverseWithdrawal(ctx context.Context, rev *models.WithdrawalReversal) (*models.Withdrawal, error) {
+	querySelect := `SELECT user_id, order_number, sum, status, updated_at, reversed_at FROM withdrawals
+		WHERE user_id = $1 AND order_number = $2 FOR UPDATE`
+	queryUpdateAcc := `UPDATE users SET current = current + $1, withdrawn = withdrawn - $1 WHERE user_id = $2`
+	queryReverse := `UPDATE withdrawals SET status = $1, reversed_at = $2, reversed_by = $3, reversal_reason = $4
+		WHERE user_id = $5 AND order_number = $6`
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	w := &models.Withdrawal{}
+	err = tx.GetContext(ctx, w, querySelect, rev.UserID, rev.OrderNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, services.ErrWithdrawalNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if w.Status != models.WithdrawalStatusPending {
+		return nil, services.ErrWithdrawalNotReversible
+	}
+
+	_, err = tx.ExecContext(ctx, queryUpdateAcc, w.Sum, rev.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	w.Status = models.WithdrawalStatusReversed
+	w.ReversedAt = &rev.At
+	w.ReversedBy = rev.ReversedBy
+	w.ReversalReason = rev.Reason
+	_, err = tx.ExecContext(ctx, queryReverse, w.Status, rev.At, rev.ReversedBy, rev.Reason, rev.UserID, rev.OrderNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	err = postLedgerTransfer(ctx, tx, ledgerTransfer{
+		kind:        models.LedgerKindReversal,
+		userID:      rev.UserID,
+		orderNumber: rev.OrderNumber,
+		from:        models.LedgerAccountWithdrawn,
+		to:          models.LedgerAccountCurrent,
+		amount:      w.Sum,
+		at:          rev.At,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	userID := rev.UserID
+	err = insertAuditEntry(ctx, tx, &models.AuditEntry{
+		AdminID:     rev.AdminID,
+		Action:      models.AuditActionWithdrawalReverse,
+		UserID:      &userID,
+		OrderNumber: rev.OrderNumber,
+		Amount:      w.Sum,
+		Reason:      rev.Reason,
+		CreatedAt:   rev.At,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertWebhookEvent(ctx, tx, rev.UserID, models.WebhookEventWithdrawalReversed, w, rev.At)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// CompleteWithdrawals completes the pending withdrawals processed before the given time,
+// after that they can not be reversed. Returns the number of completed withdrawals.
+func (r *Repository) CompleteWithdrawals(ctx context.Context, before time.Time) (int64, error) {
+	query := `UPDATE withdrawals SET status = $1 WHERE status = $2 AND updated_at < $3`
+
+	res, err := r.db.ExecContext(ctx, query, models.WithdrawalStatusCompleted, models.WithdrawalStatusPending, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
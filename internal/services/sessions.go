@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"strings"
+	"time"
+)
+
+// tokenType is the type of access tokens issued by the SessionManager.
+const tokenType = "Bearer"
+
+type SessionManager struct {
+	repo       SessionRepo
+	auth       Authenticator
+	refreshTTL time.Duration
+}
+
+func NewSessionManager(repo SessionRepo, auth Authenticator, refreshTTL time.Duration) *SessionManager {
+	return &SessionManager{
+		repo:       repo,
+		auth:       auth,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// CreateSession starts a new session for a logged-in user
+// and returns an access token and a refresh token for it.
+// Only a hash of the refresh token is stored.
+func (s *SessionManager) CreateSession(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	sessionID, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		SessionID:        sessionID,
+		UserID:           user.UserID,
+		RefreshTokenHash: hashToken(refreshToken),
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(s.refreshTTL),
+	}
+
+	err = s.repo.CreateSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, sessionID, refreshToken)
+}
+
+// RefreshSession exchanges a refresh token for a new token pair.
+// The refresh token is rotated, so every refresh token can be used only once.
+// If the previous refresh token of the session is presented again,
+// the token is considered stolen: the whole session is revoked
+// and ErrRefreshTokenReused is returned.
+// If the refresh token is unknown, expired or revoked, ErrInvalidRefreshToken is returned.
+func (s *SessionManager) RefreshSession(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	hash := hashToken(refreshToken)
+	session, err := s.repo.GetSessionByRefreshHash(ctx, hash)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if session.RefreshTokenHash != hash {
+		if err = s.repo.RevokeSession(ctx, session.SessionID); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrRefreshTokenReused
+	}
+
+	newToken, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session.ExpiresAt = time.Now().Add(s.refreshTTL)
+	err = s.repo.RotateSession(ctx, session, hashToken(newToken))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(&models.User{UserID: session.UserID, Role: session.Role}, session.SessionID, newToken)
+}
+
+// RevokeSession logs out the session of the given access token claims.
+// The access token itself is revoked until it expires,
+// the refresh token of the session can not be used any more.
+func (s *SessionManager) RevokeSession(ctx context.Context, claims *models.CustomJWTClaims) error {
+	var expiresAt time.Time
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+
+	err := s.repo.RevokeToken(ctx, claims.ID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	return s.repo.RevokeSession(ctx, claims.SessionID)
+}
+
+// Authenticate validates an access token or a machine token and returns its claims.
+// If the token or its session was revoked, ErrTokenRevoked is returned.
+func (s *SessionManager) Authenticate(ctx context.Context, token string) (*models.CustomJWTClaims, error) {
+	if strings.HasPrefix(token, MachineTokenPrefix) {
+		return s.authenticateMachineToken(ctx, token)
+	}
+
+	claims, err := s.auth.ValidateTokenAndExtractClaims(token)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.repo.IsTokenRevoked(ctx, claims.ID, claims.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return claims, nil
+}
+
+// authenticateMachineToken looks up a machine token by its hash
+// and returns claims with the user and the scopes of the token.
+// If the token does not exist, ErrMachineTokenNotFound is returned.
+func (s *SessionManager) authenticateMachineToken(ctx context.Context, token string) (*models.CustomJWTClaims, error) {
+	t, err := s.repo.GetMachineTokenByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if t.RevokedAt != nil {
+		return nil, ErrTokenRevoked
+	}
+
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, ErrMachineTokenExpired
+	}
+
+	return &models.CustomJWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        t.TokenID,
+			IssuedAt:  jwt.NewNumericDate(t.CreatedAt),
+			ExpiresAt: jwt.NewNumericDate(t.ExpiresAt),
+		},
+		UserID: t.UserID,
+		Scope:  t.Scopes.String(),
+	}, nil
+}
+
+func (s *SessionManager) issueTokens(user *models.User, sessionID, refreshToken string) (*models.TokenPair, error) {
+	accessToken, expiresAt, err := s.auth.GenerateToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    tokenType,
+		ExpiresIn:    int64(time.Until(expiresAt).Round(time.Second).Seconds()),
+	}, nil
+}
+
+// hashToken returns a hex encoded SHA-256 hash of a token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as a URL-safe string.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Join(ErrGenerateToken, err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestSessionManager_CreateSession(t *testing.T) {
+	repo := mocks.NewSessionRepo(t)
+	auth := mocks.NewAuthenticator(t)
+	s := NewSessionManager(repo, auth, time.Hour)
+
+	var stored *models.Session
+	repo.
+		On("CreateSession", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.Session)
+		}).
+		Return(nil)
+	auth.
+		On("GenerateToken", mock.Anything, mock.Anything).
+		Return("token", time.Now().Add(15*time.Minute), nil)
+
+	tokens, err := s.CreateSession(context.Background(), &models.User{UserID: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, "token", tokens.AccessToken)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, int64(900), tokens.ExpiresIn)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	assert.Equal(t, int64(1), stored.UserID)
+	assert.Equal(t, hashToken(tokens.RefreshToken), stored.RefreshTokenHash)
+	assert.NotEqual(t, tokens.RefreshToken, stored.RefreshTokenHash)
+	auth.AssertCalled(t, "GenerateToken", &models.User{UserID: 1}, stored.SessionID)
+}
+
+func TestSessionManager_RefreshSession(t *testing.T) {
+	revokedAt := time.Now()
+	sessions := map[string]*models.Session{
+		hashToken("current"): {
+			SessionID:         "active",
+			UserID:            1,
+			RefreshTokenHash:  hashToken("current"),
+			PreviousTokenHash: hashToken("previous"),
+			ExpiresAt:         time.Now().Add(time.Hour),
+		},
+		hashToken("expired"): {
+			SessionID:        "expired",
+			UserID:           1,
+			RefreshTokenHash: hashToken("expired"),
+			ExpiresAt:        time.Now().Add(-time.Hour),
+		},
+		hashToken("revoked"): {
+			SessionID:        "revoked",
+			UserID:           1,
+			RefreshTokenHash: hashToken("revoked"),
+			ExpiresAt:        time.Now().Add(time.Hour),
+			RevokedAt:        &revokedAt,
+		},
+	}
+	sessions[hashToken("previous")] = sessions[hashToken("current")]
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "rotate refresh token",
+			token: "current",
+		},
+		{
+			name:    "unknown token",
+			token:   "unknown",
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name:    "expired session",
+			token:   "expired",
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name:    "revoked session",
+			token:   "revoked",
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name:    "reused refresh token",
+			token:   "previous",
+			wantErr: ErrRefreshTokenReused,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewSessionRepo(t)
+			auth := mocks.NewAuthenticator(t)
+			s := NewSessionManager(repo, auth, time.Hour)
+
+			repo.
+				On("GetSessionByRefreshHash", mock.Anything, mock.Anything).
+				Return(func(ctx context.Context, hash string) (*models.Session, error) {
+					session, ok := sessions[hash]
+					if !ok {
+						return nil, errors.New("not found")
+					}
+
+					copied := *session
+					return &copied, nil
+				})
+
+			switch tt.wantErr {
+			case nil:
+				repo.On("RotateSession", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				auth.On("GenerateToken", mock.Anything, "active").Return("token", time.Now().Add(time.Minute), nil)
+			case ErrRefreshTokenReused:
+				repo.On("RevokeSession", mock.Anything, "active").Return(nil)
+			}
+
+			tokens, err := s.RefreshSession(context.Background(), tt.token)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+
+			assert.Equal(t, "token", tokens.AccessToken)
+			assert.NotEqual(t, tt.token, tokens.RefreshToken)
+			repo.AssertCalled(t, "RotateSession", mock.Anything, mock.Anything, hashToken(tokens.RefreshToken))
+		})
+	}
+}
+
+func TestSessionManager_RevokeSession(t *testing.T) {
+	repo := mocks.NewSessionRepo(t)
+	s := NewSessionManager(repo, nil, time.Hour)
+
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	claims := &models.CustomJWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		UserID:    1,
+		SessionID: "session",
+	}
+
+	repo.On("RevokeToken", mock.Anything, "jti", expiresAt).Return(nil)
+	repo.On("RevokeSession", mock.Anything, "session").Return(nil)
+
+	assert.NoError(t, s.RevokeSession(context.Background(), claims))
+}
+
+func TestSessionManager_Authenticate(t *testing.T) {
+	tests := []struct {
+		name    string
+		revoked bool
+		wantErr error
+	}{
+		{
+			name: "valid token",
+		},
+		{
+			name:    "revoked token",
+			revoked: true,
+			wantErr: ErrTokenRevoked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewSessionRepo(t)
+			auth := mocks.NewAuthenticator(t)
+			s := NewSessionManager(repo, auth, time.Hour)
+
+			claims := &models.CustomJWTClaims{
+				RegisteredClaims: jwt.RegisteredClaims{ID: "jti"},
+				UserID:           1,
+				SessionID:        "session",
+			}
+			auth.On("ValidateTokenAndExtractClaims", "token").Return(claims, nil)
+			repo.On("IsTokenRevoked", mock.Anything, "jti", "session").Return(tt.revoked, nil)
+
+			got, err := s.Authenticate(context.Background(), "token")
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, claims, got)
+			}
+		})
+	}
+}
+
+func TestSessionManager_AuthenticateMachineToken(t *testing.T) {
+	revokedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		token   *models.MachineToken
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:  "valid token",
+			token: &models.MachineToken{TokenID: "id", UserID: 1, Scopes: models.ScopeList{"balance:read"}, ExpiresAt: time.Now().Add(time.Hour)},
+		},
+		{
+			name:    "unknown token",
+			repoErr: ErrMachineTokenNotFound,
+			wantErr: ErrMachineTokenNotFound,
+		},
+		{
+			name:    "revoked token",
+			token:   &models.MachineToken{TokenID: "id", UserID: 1, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt},
+			wantErr: ErrTokenRevoked,
+		},
+		{
+			name:    "expired token",
+			token:   &models.MachineToken{TokenID: "id", UserID: 1, ExpiresAt: time.Now().Add(-time.Hour)},
+			wantErr: ErrMachineTokenExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewSessionRepo(t)
+			auth := mocks.NewAuthenticator(t)
+			s := NewSessionManager(repo, auth, time.Hour)
+
+			repo.On("GetMachineTokenByHash", mock.Anything, hashToken("gmt_token")).Return(tt.token, tt.repoErr)
+
+			got, err := s.Authenticate(context.Background(), "gmt_token")
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, "id", got.ID)
+				assert.Equal(t, int64(1), got.UserID)
+				assert.Equal(t, []string{"balance:read"}, got.Scopes())
+			}
+
+			auth.AssertNotCalled(t, "ValidateTokenAndExtractClaims", mock.Anything)
+		})
+	}
+}
@@ -2,28 +2,65 @@ package services
 
 import (
 	"context"
+	"errors"
 	"github.com/leonf08/gophermart.git/internal/models"
 	"github.com/leonf08/gophermart.git/internal/services/utils"
+	"sync"
 	"time"
 )
 
+// maxIdempotencyKeyLen is the maximum length of a withdrawal idempotency key.
+const maxIdempotencyKeyLen = 255
+
+// BalancePolicy tells what happens to the remaining balance when a user deletes the account.
+type BalancePolicy string
+
+const (
+	// BalancePolicyReject rejects the deletion while the account has remaining balance.
+	BalancePolicyReject BalancePolicy = "reject"
+	// BalancePolicyForfeit forfeits the remaining balance to the loyalty program.
+	BalancePolicyForfeit BalancePolicy = "forfeit"
+)
+
+// dummyPassword is hashed to compare passwords against when a login does not exist,
+// so a log in attempt takes the same time whether the login exists or not.
+const dummyPassword = "gophermart-dummy-password"
+
 type UserManager struct {
-	repo UserRepo
-	auth Authenticator
+	repo   UserRepo
+	auth   Authenticator
+	policy *CredentialsPolicy
+	guard  *LoginGuard
+
+	balancePolicy BalancePolicy
+
+	dummyOnce sync.Once
+	dummyHash string
 }
 
-func NewUserManager(repo UserRepo, auth Authenticator) *UserManager {
+func NewUserManager(repo UserRepo, auth Authenticator, policy *CredentialsPolicy, guard *LoginGuard,
+	balancePolicy BalancePolicy) *UserManager {
 	return &UserManager{
-		repo: repo,
-		auth: auth,
+		repo:          repo,
+		auth:          auth,
+		policy:        policy,
+		guard:         guard,
+		balancePolicy: balancePolicy,
 	}
 }
 
 // RegisterUser registers a new user.
 // If the user registration fails, an error is returned.
 // If the user registration succeeds, nil is returned.
-// The user registration fails if the user already exists.
+// The user registration fails if the user already exists
+// or the credentials do not satisfy the credentials policy,
+// in the latter case a *CredentialsPolicyError is returned.
 func (u *UserManager) RegisterUser(ctx context.Context, user *models.User) error {
+	// Check the credentials policy.
+	if err := u.policy.Validate(user); err != nil {
+		return err
+	}
+
 	// Generate hash from password.
 	hashedPasswd, err := u.auth.GenerateHashFromPassword(user)
 	if err != nil {
@@ -44,42 +81,124 @@ func (u *UserManager) RegisterUser(ctx context.Context, user *models.User) error
 // LoginUser logs in a user.
 // If the user login fails, an error is returned.
 // If the user login succeeds, nil is returned.
-// The user login fails if the user does not exist or the password is incorrect.
-// The user login succeeds if the user exists and the password is correct.
-func (u *UserManager) LoginUser(ctx context.Context, user *models.User) error {
+// The user login fails with ErrInvalidCredentials if the user does not exist
+// or the password is incorrect, the two cases are indistinguishable.
+// After failed attempts from the same login or client address further attempts
+// are delayed or locked out, then a *LoginThrottledError is returned.
+func (u *UserManager) LoginUser(ctx context.Context, user *models.User, clientIP string) error {
 	// Check if the login is valid.
 	if !utils.IsLoginValid(user.Login) {
 		return ErrInvalidLoginFormat
 	}
+
+	// Check if a log in attempt is allowed.
+	if err := u.guard.Check(ctx, user.Login, clientIP); err != nil {
+		return err
+	}
+
 	// Check if the user exists.
 	storedUser, err := u.repo.GetUserByLogin(ctx, user.Login)
-	if err != nil {
-		return ErrUserNotFound
+	exists := err == nil
+	if errors.Is(err, ErrUserNotFound) {
+		// Compare against a dummy hash to spend the same time as for an existing user.
+		storedUser = &models.User{Password: u.getDummyHash()}
+	} else if err != nil {
+		return err
 	}
 
 	// Check if the password is correct.
 	err = u.auth.CheckPasswordHash(user, storedUser)
-	if err != nil {
-		return ErrIncorrectPassword
+	if err != nil || !exists {
+		if err = u.guard.Failure(ctx, user.Login, clientIP); err != nil {
+			return err
+		}
+
+		return ErrInvalidCredentials
+	}
+
+	if err = u.guard.Success(ctx, user.Login); err != nil {
+		return err
 	}
 
 	user.UserID = storedUser.UserID
+	user.Role = storedUser.Role
 
 	return nil
 }
 
-// GetToken generates a JWT token for a given user.
-// If the token generation fails, an error is returned.
-// If the token generation succeeds, the token is returned.
-// The token is signed with HMAC-SHA256 algorithm.
-func (u *UserManager) GetToken(user *models.User) (string, error) {
-	token, err := u.auth.GenerateToken(user)
+// ChangePassword changes the password of a user.
+// The old password must be correct, otherwise ErrInvalidCredentials is returned,
+// failed attempts are throttled as log in attempts.
+// The new password must satisfy the credentials policy,
+// otherwise a *CredentialsPolicyError is returned.
+// All sessions and machine tokens of the user are revoked, so every issued token stops working.
+func (u *UserManager) ChangePassword(ctx context.Context, userID int64, req *models.PasswordChangeRequest,
+	clientIP string) error {
+	storedUser, err := u.verifyPassword(ctx, userID, req.OldPassword, clientIP)
+	if err != nil {
+		return err
+	}
+
+	// Only the password rules apply, the login may predate the credentials policy.
+	if err = u.policy.ValidatePassword(storedUser.Login, req.NewPassword); err != nil {
+		return err
+	}
+
+	user := &models.User{UserID: userID, Login: storedUser.Login, Password: req.NewPassword}
+
+	hashedPasswd, err := u.auth.GenerateHashFromPassword(user)
+	if err != nil {
+		return err
+	}
+
+	return u.repo.ChangePassword(ctx, userID, hashedPasswd)
+}
+
+// DeleteUser deletes the account of a user.
+// The password must be correct, otherwise ErrInvalidCredentials is returned.
+// The user is anonymised, orders and withdrawals are kept for accounting.
+// The remaining balance is handled according to the balance policy:
+// with BalancePolicyReject ErrAccountHasBalance is returned while the balance is positive.
+// All sessions of the user are revoked.
+func (u *UserManager) DeleteUser(ctx context.Context, userID int64, req *models.AccountDeletionRequest,
+	clientIP string) error {
+	if _, err := u.verifyPassword(ctx, userID, req.Password, clientIP); err != nil {
+		return err
+	}
+
+	return u.repo.DeleteUser(ctx, userID, u.balancePolicy == BalancePolicyForfeit)
+}
+
+// verifyPassword checks the password of a logged-in user.
+// Failed attempts are throttled the same way as log in attempts.
+func (u *UserManager) verifyPassword(ctx context.Context, userID int64, password, clientIP string) (*models.User, error) {
+	storedUser, err := u.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = u.guard.Check(ctx, storedUser.Login, clientIP); err != nil {
+		return nil, err
+	}
 
+	err = u.auth.CheckPasswordHash(&models.User{Password: password}, storedUser)
 	if err != nil {
-		return "", err
+		if err = u.guard.Failure(ctx, storedUser.Login, clientIP); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrInvalidCredentials
 	}
 
-	return token, nil
+	return storedUser, nil
+}
+
+func (u *UserManager) getDummyHash() string {
+	u.dummyOnce.Do(func() {
+		u.dummyHash, _ = u.auth.GenerateHashFromPassword(&models.User{Password: dummyPassword})
+	})
+
+	return u.dummyHash
 }
 
 // GetUserAccount returns details about user account.
@@ -91,20 +210,27 @@ func (u *UserManager) GetUserAccount(ctx context.Context, userID int64) (*models
 		return nil, err
 	}
 
-	// Convert integer sum to float sum
-	userAccount.Current /= 100
-	userAccount.Withdrawn /= 100
-
 	return userAccount, nil
 }
 
 // WithdrawFromAccount withdraws a given sum from a user account.
 // If the withdrawal succeeds, it returns nil.
 // If the withdrawal fails, it returns an error.
-// The withdrawal fails if the sum is greater than the current balance.
+// The withdrawal fails if the sum is greater than the current balance,
+// the balance is checked by the repository within the withdrawal transaction.
+// If the withdrawal repeats an earlier one with the same idempotency key,
+// ErrWithdrawalReplayed is returned and w holds the original withdrawal.
 func (u *UserManager) WithdrawFromAccount(ctx context.Context, w *models.Withdrawal) error {
-	// Convert float sum to integer sum
-	w.Sum *= 100
+	// Check if the sum is positive.
+	if w.Sum <= 0 {
+		return ErrInvalidWithdrawalSum
+	}
+
+	// Check if the idempotency key fits into the repository.
+	if len(w.IdempotencyKey) > maxIdempotencyKeyLen {
+		return ErrInvalidIdempotencyKey
+	}
+
 	// Check if the orderNumber is valid.
 	if !utils.IsNumber(w.OrderNumber) {
 		return ErrInvalidOrderNumberFormat
@@ -115,40 +241,52 @@ func (u *UserManager) WithdrawFromAccount(ctx context.Context, w *models.Withdra
 		return ErrInvalidOrderNumber
 	}
 
-	// Get user account.
-	userAccount, err := u.repo.GetUserAccount(ctx, w.UserID)
+	// Withdraw from account.
+	w.Status = models.WithdrawalStatusPending
+	w.ProcessedAt = time.Now()
+	err := u.repo.DoWithdrawal(ctx, w)
 	if err != nil {
 		return err
 	}
 
-	// Check if the sum is greater than the current balance.
-	if userAccount.Current < w.Sum {
-		return ErrInsufficientFunds
+	return nil
+}
+
+// GetWithdrawals returns a page of withdrawals of a user, oldest first.
+// If there are more withdrawals after the page, a cursor to continue with is returned.
+// If the query is invalid or the list of withdrawals is not found, it returns an error.
+func (u *UserManager) GetWithdrawals(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, *models.Cursor, error) {
+	err := validateListQuery(q, models.WithdrawalStatusPending, models.WithdrawalStatusCompleted,
+		models.WithdrawalStatusReversed)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Withdraw from account.
-	w.ProcessedAt = time.Now()
-	err = u.repo.DoWithdrawal(ctx, w)
+	// Retrieve one withdrawal over the limit to know if there is a next page.
+	fetch := *q
+	if fetch.Limit > 0 {
+		fetch.Limit++
+	}
+	withdrawals, err := u.repo.GetWithdrawalList(ctx, &fetch)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	return nil
+	withdrawals, next := paginate(withdrawals, q.Limit, func(w *models.Withdrawal) *models.Cursor {
+		return &models.Cursor{Time: w.ProcessedAt, Key: w.OrderNumber}
+	})
+
+	return withdrawals, next, nil
 }
 
-// GetWithdrawals returns a list of withdrawals.
-// If the list of withdrawals is found, it returns the list of withdrawals and nil.
-// If the list of withdrawals is not found, it returns nil and an error.
-func (u *UserManager) GetWithdrawals(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-	withdrawals, err := u.repo.GetWithdrawalList(ctx, userID)
+// GetLedger returns the full history of point movements of a user.
+// If the ledger is found, it returns the ledger entries and nil.
+// If the ledger is not found, it returns nil and an error.
+func (u *UserManager) GetLedger(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+	entries, err := u.repo.GetLedgerEntries(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert integer sum to float sum
-	for _, w := range withdrawals {
-		w.Sum /= 100
-	}
-
-	return withdrawals, nil
+	return entries, nil
 }
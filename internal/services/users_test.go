@@ -7,14 +7,23 @@ import (
 	"github.com/leonf08/gophermart.git/internal/services/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewUserManager(t *testing.T) {
+	policy := newTestPolicy(t)
+	guard := NewLoginGuard(mocks.NewLoginAttemptRepo(t), LoginGuardConfig{})
+
 	type args struct {
-		repo UserRepo
-		auth Authenticator
+		repo   UserRepo
+		auth   Authenticator
+		policy *CredentialsPolicy
+		guard  *LoginGuard
+		bp     BalancePolicy
 	}
 	tests := []struct {
 		name string
@@ -24,97 +33,40 @@ func TestNewUserManager(t *testing.T) {
 		{
 			name: "TestNewUserManager",
 			args: args{
-				repo: nil,
-				auth: nil,
+				repo:   nil,
+				auth:   nil,
+				policy: nil,
+				guard:  nil,
 			},
 			want: &UserManager{},
 		},
 		{
 			name: "TestNewUserManager",
 			args: args{
-				repo: mocks.NewUserRepo(t),
-				auth: mocks.NewAuthenticator(t),
+				repo:   mocks.NewUserRepo(t),
+				auth:   mocks.NewAuthenticator(t),
+				policy: policy,
+				guard:  guard,
+				bp:     BalancePolicyForfeit,
+			},
+			want: &UserManager{
+				repo:          mocks.NewUserRepo(t),
+				auth:          mocks.NewAuthenticator(t),
+				policy:        policy,
+				guard:         guard,
+				balancePolicy: BalancePolicyForfeit,
 			},
-			want: &UserManager{mocks.NewUserRepo(t), mocks.NewAuthenticator(t)},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewUserManager(tt.args.repo, tt.args.auth); !reflect.DeepEqual(got, tt.want) {
+			if got := NewUserManager(tt.args.repo, tt.args.auth, tt.args.policy, tt.args.guard, tt.args.bp); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewUserManager() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestUserManager_GetToken(t *testing.T) {
-	repo := mocks.NewUserRepo(t)
-	auth := mocks.NewAuthenticator(t)
-
-	type args struct {
-		user *models.User
-	}
-	type want struct {
-		token string
-		err   error
-	}
-	tests := []struct {
-		name string
-		args args
-		want want
-	}{
-		{
-			name: "TestUserManager_GetToken_no_error",
-			args: args{
-				user: &models.User{
-					Login:    "test",
-					Password: "test",
-				},
-			},
-			want: want{
-				token: "test",
-				err:   nil,
-			},
-		},
-		{
-			name: "TestUserManager_GetToken_error",
-			args: args{
-				user: &models.User{
-					Login:    "user",
-					Password: "test",
-				},
-			},
-			want: want{
-				token: "",
-				err:   ErrGenerateToken,
-			},
-		},
-	}
-
-	auth.
-		On("GenerateToken", mock.Anything).
-		Return(func(user *models.User) (string, error) {
-			if user.Login != "test" {
-				return "", ErrGenerateToken
-			}
-
-			return "test", nil
-		})
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			u := &UserManager{
-				repo: repo,
-				auth: auth,
-			}
-			got, err := u.GetToken(tt.args.user)
-
-			assert.Equal(t, tt.want.token, got)
-			assert.Equal(t, tt.want.err, err)
-		})
-	}
-}
-
 func TestUserManager_GetUserAccount(t *testing.T) {
 	repo := mocks.NewUserRepo(t)
 	auth := mocks.NewAuthenticator(t)
@@ -186,7 +138,7 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 	auth := mocks.NewAuthenticator(t)
 
 	type args struct {
-		userID int64
+		q *models.ListQuery
 	}
 	type want struct {
 		withdrawals []*models.Withdrawal
@@ -200,7 +152,7 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 		{
 			name: "TestUserManager_GetWithdrawals_no_error",
 			args: args{
-				userID: 1,
+				q: &models.ListQuery{UserID: 1},
 			},
 			want: want{
 				withdrawals: []*models.Withdrawal{
@@ -211,10 +163,34 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 				err: false,
 			},
 		},
+		{
+			name: "TestUserManager_GetWithdrawals_status_filter",
+			args: args{
+				q: &models.ListQuery{UserID: 1, Statuses: []string{models.WithdrawalStatusReversed}},
+			},
+			want: want{
+				withdrawals: []*models.Withdrawal{
+					{
+						UserID: 1,
+					},
+				},
+				err: false,
+			},
+		},
+		{
+			name: "TestUserManager_GetWithdrawals_invalid_status_filter",
+			args: args{
+				q: &models.ListQuery{UserID: 1, Statuses: []string{models.OrderStatusNew}},
+			},
+			want: want{
+				withdrawals: nil,
+				err:         true,
+			},
+		},
 		{
 			name: "TestUserManager_GetWithdrawals_error",
 			args: args{
-				userID: 2,
+				q: &models.ListQuery{UserID: 2},
 			},
 			want: want{
 				withdrawals: nil,
@@ -225,14 +201,14 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 
 	repo.
 		On("GetWithdrawalList", mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, userID int64) ([]*models.Withdrawal, error) {
-			if userID != 1 {
+		Return(func(ctx context.Context, q *models.ListQuery) ([]*models.Withdrawal, error) {
+			if q.UserID != 1 {
 				return nil, errors.New("error")
 			}
 
 			return []*models.Withdrawal{
 				{
-					UserID: userID,
+					UserID: q.UserID,
 				},
 			}, nil
 		})
@@ -243,9 +219,10 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 				repo: repo,
 				auth: auth,
 			}
-			got, err := u.GetWithdrawals(context.Background(), tt.args.userID)
+			got, next, err := u.GetWithdrawals(context.Background(), tt.args.q)
 
 			assert.Equal(t, tt.want.withdrawals, got)
+			assert.Nil(t, next)
 			assert.Equal(t, tt.want.err, err != nil)
 		})
 	}
@@ -254,6 +231,7 @@ func TestUserManager_GetWithdrawals(t *testing.T) {
 func TestUserManager_LoginUser(t *testing.T) {
 	repo := mocks.NewUserRepo(t)
 	auth := mocks.NewAuthenticator(t)
+	attempts := mocks.NewLoginAttemptRepo(t)
 
 	type args struct {
 		user *models.User
@@ -297,7 +275,7 @@ func TestUserManager_LoginUser(t *testing.T) {
 				},
 			},
 			want: want{
-				err: ErrUserNotFound,
+				err: ErrInvalidCredentials,
 			},
 		},
 		{
@@ -309,7 +287,19 @@ func TestUserManager_LoginUser(t *testing.T) {
 				},
 			},
 			want: want{
-				err: ErrIncorrectPassword,
+				err: ErrInvalidCredentials,
+			},
+		},
+		{
+			name: "TestUserManager_LoginUser_locked_out",
+			args: args{
+				user: &models.User{
+					Login:    "locked",
+					Password: "test",
+				},
+			},
+			want: want{
+				err: &LoginThrottledError{RetryAfter: time.Minute},
 			},
 		},
 	}
@@ -331,20 +321,55 @@ func TestUserManager_LoginUser(t *testing.T) {
 		On("CheckPasswordHash", mock.Anything, mock.Anything).
 		Return(func(user, storedUser *models.User) error {
 			if user.Password != "test" {
-				return ErrIncorrectPassword
+				return bcrypt.ErrMismatchedHashAndPassword
 			}
 
 			return nil
 		})
 
+	auth.
+		On("GenerateHashFromPassword", mock.Anything).
+		Return("dummy", nil).
+		Once()
+
+	lockedUntil := time.Now().Add(time.Minute)
+	attempts.
+		On("GetLoginAttempts", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, key string) (*models.LoginAttempts, error) {
+			if key == "login:locked" {
+				return &models.LoginAttempts{Key: key, Failures: 5, LastFailureAt: time.Now(), LockedUntil: &lockedUntil}, nil
+			}
+
+			return &models.LoginAttempts{Key: key}, nil
+		})
+	attempts.
+		On("RecordLoginFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, key string, at time.Time, window time.Duration) (*models.LoginAttempts, error) {
+			return &models.LoginAttempts{Key: key, Failures: 1, LastFailureAt: at}, nil
+		})
+	attempts.
+		On("ResetLoginAttempts", mock.Anything, "login:test").
+		Return(nil)
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			u := &UserManager{
 				repo: repo,
 				auth: auth,
+				guard: NewLoginGuard(attempts, LoginGuardConfig{
+					Window:           time.Minute,
+					LoginMaxFailures: 5,
+					IPMaxFailures:    50,
+					LockoutDuration:  time.Minute,
+				}),
 			}
-			err := u.LoginUser(context.Background(), tt.args.user)
+			err := u.LoginUser(context.Background(), tt.args.user, "")
 
+			var throttled *LoginThrottledError
+			if errors.As(err, &throttled) {
+				assert.InDelta(t, time.Minute, throttled.RetryAfter, float64(time.Second))
+				return
+			}
 			assert.Equal(t, tt.want.err, err)
 		})
 	}
@@ -370,7 +395,7 @@ func TestUserManager_RegisterUser(t *testing.T) {
 			args: args{
 				user: &models.User{
 					Login:    "test",
-					Password: "test",
+					Password: "correct horse",
 				},
 			},
 			want: want{
@@ -382,7 +407,7 @@ func TestUserManager_RegisterUser(t *testing.T) {
 			args: args{
 				user: &models.User{
 					Login:    "test",
-					Password: "",
+					Password: "battery staple",
 				},
 			},
 			want: want{
@@ -394,29 +419,45 @@ func TestUserManager_RegisterUser(t *testing.T) {
 			args: args{
 				user: &models.User{
 					Login:    "admin",
-					Password: "test",
+					Password: "correct horse",
 				},
 			},
 			want: want{
 				err: errors.New("error"),
 			},
 		},
+		{
+			name: "TestUserManager_RegisterUser_policy_error",
+			args: args{
+				user: &models.User{
+					Login:    "te",
+					Password: "te",
+				},
+			},
+			want: want{
+				err: &CredentialsPolicyError{Violations: []models.PolicyViolation{
+					{Field: "login", Rule: RuleMinLength, Message: "login must be at least 3 characters long"},
+					{Field: "password", Rule: RuleMinLength, Message: "password must be at least 8 characters long"},
+					{Field: "password", Rule: RuleSameAsLogin, Message: "password must differ from login"},
+				}},
+			},
+		},
 	}
 
 	repo.
 		On("CreateUser", mock.Anything, mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, login, hashedPasswd string) error {
+		Return(func(ctx context.Context, login, hashedPasswd string) (int64, error) {
 			if login == "admin" {
-				return errors.New("error")
+				return 0, errors.New("error")
 			}
 
-			return nil
+			return 1, nil
 		})
 
 	auth.
 		On("GenerateHashFromPassword", mock.Anything).
 		Return(func(user *models.User) (string, error) {
-			if user.Password == "" {
+			if user.Password == "battery staple" {
 				return "", ErrGenerateHashFromPassword
 			}
 
@@ -426,8 +467,9 @@ func TestUserManager_RegisterUser(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			u := &UserManager{
-				repo: repo,
-				auth: auth,
+				repo:   repo,
+				auth:   auth,
+				policy: newTestPolicy(t),
 			}
 
 			err := u.RegisterUser(context.Background(), tt.args.user)
@@ -505,16 +547,30 @@ func TestUserManager_WithdrawFromAccount(t *testing.T) {
 			},
 		},
 		{
-			name: "TestUserManager_WithdrawFromAccount_get_user_account_error",
+			name: "TestUserManager_WithdrawFromAccount_invalid_idempotency_key",
 			args: args{
 				w: &models.Withdrawal{
-					UserID:      3,
-					Sum:         1,
+					UserID:         1,
+					Sum:            1,
+					OrderNumber:    "2030",
+					IdempotencyKey: strings.Repeat("k", 256),
+				},
+			},
+			want: want{
+				err: ErrInvalidIdempotencyKey,
+			},
+		},
+		{
+			name: "TestUserManager_WithdrawFromAccount_invalid_sum",
+			args: args{
+				w: &models.Withdrawal{
+					UserID:      1,
+					Sum:         -1,
 					OrderNumber: "2030",
 				},
 			},
 			want: want{
-				err: errors.New("error"),
+				err: ErrInvalidWithdrawalSum,
 			},
 		},
 		{
@@ -532,22 +588,13 @@ func TestUserManager_WithdrawFromAccount(t *testing.T) {
 		},
 	}
 
-	repo.
-		On("GetUserAccount", mock.Anything, mock.Anything).
-		Return(func(ctx context.Context, userID int64) (*models.UserAccount, error) {
-			if userID == 3 {
-				return nil, errors.New("error")
-			}
-
-			return &models.UserAccount{
-				UserID:  userID,
-				Current: 1000,
-			}, nil
-		})
-
 	repo.
 		On("DoWithdrawal", mock.Anything, mock.Anything).
 		Return(func(ctx context.Context, withdrawal *models.Withdrawal) error {
+			if withdrawal.Sum > 1000 {
+				return ErrInsufficientFunds
+			}
+
 			if withdrawal.UserID == 2 {
 				return errors.New("error")
 			}
@@ -568,3 +615,217 @@ func TestUserManager_WithdrawFromAccount(t *testing.T) {
 		})
 	}
 }
+
+func TestUserManager_GetLedger(t *testing.T) {
+	repo := mocks.NewUserRepo(t)
+	auth := mocks.NewAuthenticator(t)
+
+	type args struct {
+		userID int64
+	}
+	type want struct {
+		entries []*models.LedgerEntry
+		err     bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want want
+	}{
+		{
+			name: "TestUserManager_GetLedger_no_error",
+			args: args{
+				userID: 1,
+			},
+			want: want{
+				entries: []*models.LedgerEntry{
+					{
+						UserID:  1,
+						Account: models.LedgerAccountCurrent,
+						Kind:    models.LedgerKindAccrual,
+						Amount:  50000,
+					},
+				},
+				err: false,
+			},
+		},
+		{
+			name: "TestUserManager_GetLedger_error",
+			args: args{
+				userID: 2,
+			},
+			want: want{
+				entries: nil,
+				err:     true,
+			},
+		},
+	}
+
+	repo.
+		On("GetLedgerEntries", mock.Anything, mock.Anything).
+		Return(func(ctx context.Context, userID int64) ([]*models.LedgerEntry, error) {
+			if userID == 1 {
+				return []*models.LedgerEntry{
+					{
+						UserID:  1,
+						Account: models.LedgerAccountCurrent,
+						Kind:    models.LedgerKindAccrual,
+						Amount:  50000,
+					},
+				}, nil
+			}
+
+			return nil, errors.New("error")
+		})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &UserManager{
+				repo: repo,
+				auth: auth,
+			}
+
+			got, err := u.GetLedger(context.Background(), tt.args.userID)
+			assert.Equal(t, tt.want.entries, got)
+			assert.Equal(t, tt.want.err, err != nil)
+		})
+	}
+}
+
+func TestUserManager_ChangePassword(t *testing.T) {
+	type want struct {
+		err     error
+		changed bool
+	}
+	tests := []struct {
+		name  string
+		login string
+		req   *models.PasswordChangeRequest
+		want  want
+	}{
+		{
+			name:  "TestUserManager_ChangePassword_no_error",
+			login: "test",
+			req:   &models.PasswordChangeRequest{OldPassword: "old password", NewPassword: "new password"},
+			want:  want{changed: true},
+		},
+		{
+			name:  "TestUserManager_ChangePassword_legacy_login",
+			login: "a b",
+			req:   &models.PasswordChangeRequest{OldPassword: "old password", NewPassword: "new password"},
+			want:  want{changed: true},
+		},
+		{
+			name:  "TestUserManager_ChangePassword_wrong_old_password",
+			login: "test",
+			req:   &models.PasswordChangeRequest{OldPassword: "wrong password", NewPassword: "new password"},
+			want:  want{err: ErrInvalidCredentials},
+		},
+		{
+			name:  "TestUserManager_ChangePassword_policy_error",
+			login: "test",
+			req:   &models.PasswordChangeRequest{OldPassword: "old password", NewPassword: "short"},
+			want:  want{err: ErrCredentialsPolicy},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUserRepo(t)
+			auth := mocks.NewAuthenticator(t)
+			attempts := mocks.NewLoginAttemptRepo(t)
+
+			repo.
+				On("GetUserByID", mock.Anything, int64(1)).
+				Return(&models.User{UserID: 1, Login: tt.login, Password: "hash"}, nil)
+			auth.
+				On("CheckPasswordHash", mock.Anything, mock.Anything).
+				Return(func(user, storedUser *models.User) error {
+					if user.Password != "old password" {
+						return bcrypt.ErrMismatchedHashAndPassword
+					}
+
+					return nil
+				})
+			attempts.
+				On("GetLoginAttempts", mock.Anything, mock.Anything).
+				Return(func(ctx context.Context, key string) (*models.LoginAttempts, error) {
+					return &models.LoginAttempts{Key: key}, nil
+				})
+
+			if tt.want.err == ErrInvalidCredentials {
+				attempts.
+					On("RecordLoginFailure", mock.Anything, "login:"+tt.login, mock.Anything, mock.Anything).
+					Return(&models.LoginAttempts{Key: "login:" + tt.login, Failures: 1}, nil)
+			}
+			if tt.want.changed {
+				auth.On("GenerateHashFromPassword", mock.Anything).Return("new hash", nil)
+				repo.On("ChangePassword", mock.Anything, int64(1), "new hash").Return(nil)
+			}
+
+			u := &UserManager{
+				repo:   repo,
+				auth:   auth,
+				policy: newTestPolicy(t),
+				guard:  NewLoginGuard(attempts, LoginGuardConfig{Window: time.Minute}),
+			}
+			err := u.ChangePassword(context.Background(), 1, tt.req, "")
+
+			assert.ErrorIs(t, err, tt.want.err)
+		})
+	}
+}
+
+func TestUserManager_DeleteUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BalancePolicy
+		forfeit bool
+		repoErr error
+		wantErr error
+	}{
+		{
+			name:    "TestUserManager_DeleteUser_reject_with_balance",
+			policy:  BalancePolicyReject,
+			forfeit: false,
+			repoErr: ErrAccountHasBalance,
+			wantErr: ErrAccountHasBalance,
+		},
+		{
+			name:    "TestUserManager_DeleteUser_forfeit",
+			policy:  BalancePolicyForfeit,
+			forfeit: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewUserRepo(t)
+			auth := mocks.NewAuthenticator(t)
+			attempts := mocks.NewLoginAttemptRepo(t)
+
+			repo.
+				On("GetUserByID", mock.Anything, int64(1)).
+				Return(&models.User{UserID: 1, Login: "test", Password: "hash"}, nil)
+			repo.
+				On("DeleteUser", mock.Anything, int64(1), tt.forfeit).
+				Return(tt.repoErr)
+			auth.
+				On("CheckPasswordHash", mock.Anything, mock.Anything).
+				Return(nil)
+			attempts.
+				On("GetLoginAttempts", mock.Anything, mock.Anything).
+				Return(func(ctx context.Context, key string) (*models.LoginAttempts, error) {
+					return &models.LoginAttempts{Key: key}, nil
+				})
+
+			u := &UserManager{
+				repo:          repo,
+				auth:          auth,
+				guard:         NewLoginGuard(attempts, LoginGuardConfig{Window: time.Minute}),
+				balancePolicy: tt.policy,
+			}
+			err := u.DeleteUser(context.Background(), 1, &models.AccountDeletionRequest{Password: "password"}, "")
+
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
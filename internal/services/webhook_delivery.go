@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Webhook request headers.
+const (
+	headerWebhookEvent     = "X-Gophermart-Event"
+	headerWebhookDelivery  = "X-Gophermart-Delivery"
+	headerWebhookSignature = "X-Gophermart-Signature"
+)
+
+// webhookErrorLen limits the error saved in the delivery log.
+const webhookErrorLen = 1024
+
+// WebhookDispatcherConfig holds settings of the webhook delivery.
+type WebhookDispatcherConfig struct {
+	// PollInterval is how often the outbox and the pending deliveries are checked.
+	PollInterval time.Duration
+	// RetryInterval is the delay before the first retry of a failed delivery.
+	// It doubles with every attempt up to MaxRetryInterval.
+	RetryInterval    time.Duration
+	MaxRetryInterval time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is failed.
+	MaxAttempts int
+	// Timeout limits a single request to a webhook endpoint.
+	Timeout time.Duration
+	// BatchSize is the number of events or deliveries handled at once.
+	BatchSize int
+}
+
+// WebhookDispatcher delivers the events of the webhook outbox to the webhook endpoints.
+// Every event is turned into a delivery per subscribed webhook, failed deliveries are retried
+// with exponential back-off, and the result of every delivery is kept in the delivery log.
+// Request bodies are signed with HMAC-SHA256 using the webhook secret.
+type WebhookDispatcher struct {
+	cfg    WebhookDispatcherConfig
+	repo   WebhookRepo
+	log    Logger
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher and starts its worker.
+// The worker is stopped when the given context is done.
+func NewWebhookDispatcher(ctx context.Context, cfg WebhookDispatcherConfig, repo WebhookRepo, log Logger) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		cfg:    cfg,
+		repo:   repo,
+		log:    log,
+		client: newWebhookClient(cfg.Timeout),
+	}
+
+	d.wg.Add(1)
+	go d.run(ctx)
+
+	return d
+}
+
+// newWebhookClient creates a client for webhook requests.
+// It connects only to allowed addresses, checked after the host is resolved,
+// and it does not follow redirects, so neither a rebound host nor a redirect
+// can turn a webhook into a request to an internal address.
+// Proxies are not used, the checked address must be the endpoint itself.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			addr, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+
+			if !webhookAddrAllowed(addr) {
+				return ErrWebhookAddrForbidden
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Wait blocks until the worker is stopped.
+func (d *WebhookDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func (d *WebhookDispatcher) run(ctx context.Context) {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := d.dispatch(ctx); err != nil && ctx.Err() == nil {
+			d.log.Error("webhooks - run - d.dispatch", "error", err)
+		}
+
+		if err := d.deliverDue(ctx); err != nil && ctx.Err() == nil {
+			d.log.Error("webhooks - run - d.deliverDue", "error", err)
+		}
+	}
+}
+
+// dispatch turns all new outbox events into deliveries.
+func (d *WebhookDispatcher) dispatch(ctx context.Context) error {
+	for {
+		n, err := d.repo.DispatchWebhookOutbox(ctx, time.Now(), d.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		if n < int64(d.cfg.BatchSize) {
+			return nil
+		}
+	}
+}
+
+// deliverDue sends the deliveries which are due.
+func (d *WebhookDispatcher) deliverDue(ctx context.Context) error {
+	now := time.Now()
+
+	// A claimed delivery is not due again until the requests of the whole batch time out.
+	lease := now.Add(time.Duration(d.cfg.BatchSize+1) * d.cfg.Timeout)
+	deliveries, err := d.repo.ClaimWebhookDeliveries(ctx, now, lease, d.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		d.deliver(ctx, delivery)
+	}
+
+	return nil
+}
+
+// deliver sends a delivery and saves the result.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	status, err := d.send(ctx, delivery)
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.LastError = ""
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+	case d.cfg.MaxAttempts > 0 && delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(expBackoff(d.cfg.RetryInterval, d.cfg.MaxRetryInterval, delivery.Attempts))
+	}
+
+	if err != nil {
+		delivery.LastError = err.Error()
+		if len(delivery.LastError) > webhookErrorLen {
+			delivery.LastError = delivery.LastError[:webhookErrorLen]
+		}
+
+		d.log.Error("webhooks - deliver - d.send", "delivery", delivery.DeliveryID, "attempts", delivery.Attempts,
+			"status", delivery.Status, "error", err)
+	}
+
+	if err = d.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		d.log.Error("webhooks - deliver - d.repo.UpdateWebhookDelivery", "delivery", delivery.DeliveryID, "error", err)
+	}
+}
+
+// send posts the signed event to the webhook endpoint.
+// It returns the response status, if there was a response,
+// and an error unless the endpoint responded with a 2xx status.
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(&models.WebhookEnvelope{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.EventAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerWebhookEvent, delivery.EventType)
+	req.Header.Set(headerWebhookDelivery, strconv.FormatInt(delivery.DeliveryID, 10))
+	req.Header.Set(headerWebhookSignature, SignWebhook(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%w %d", ErrWebhookDeliveryStatus, resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the signature header of a webhook request body: t=<unix time>,v1=<hex HMAC-SHA256>.
+// The HMAC is computed over "<unix time>.<body>" with the webhook secret, so receivers
+// can check both the authenticity and the freshness of a request.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	// webhookSecretLen is the number of random bytes in a webhook secret.
+	webhookSecretLen = 32
+	// webhookDeliveriesLimit is the number of latest deliveries shown in a delivery log.
+	webhookDeliveriesLimit = 100
+)
+
+// webhookEvents are the event types a webhook can subscribe to.
+var webhookEvents = []string{
+	models.WebhookEventOrderProcessed,
+	models.WebhookEventOrderInvalid,
+	models.WebhookEventWithdrawal,
+	models.WebhookEventWithdrawalReversed,
+}
+
+// webhookDeniedPrefixes are internal address ranges
+// not covered by the address classes checked in webhookAddrAllowed.
+var webhookDeniedPrefixes = []netip.Prefix{
+	// "This network", e.g. 0.1.2.3 may reach the local host.
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Carrier-grade NAT shared address space.
+	netip.MustParsePrefix("100.64.0.0/10"),
+}
+
+// hostResolver resolves host names, *net.Resolver implements it.
+type hostResolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// WebhookManager is a service for working with webhooks of users.
+type WebhookManager struct {
+	repo     WebhookRepo
+	resolver hostResolver
+}
+
+// NewWebhookManager creates a new webhook manager.
+func NewWebhookManager(repo WebhookRepo) *WebhookManager {
+	return &WebhookManager{
+		repo:     repo,
+		resolver: net.DefaultResolver,
+	}
+}
+
+// CreateWebhook registers a webhook of a user with a new signing secret.
+// The secret is returned only here, later listings do not include it.
+// If the url or the events are invalid, an error is returned.
+// If the host of the url resolves to an internal address, ErrWebhookAddrForbidden is returned.
+func (m *WebhookManager) CreateWebhook(ctx context.Context, userID int64, req *models.WebhookRequest) (*models.Webhook, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	if err = m.checkHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if len(req.Events) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+
+	var events models.EventTypes
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return nil, ErrInvalidWebhookEvents
+		}
+
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+
+	secret, err := randomString(webhookSecretLen)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &models.Webhook{
+		UserID:    userID,
+		URL:       u.String(),
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now(),
+	}
+
+	if err = m.repo.CreateWebhook(ctx, w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// GetWebhooks returns the webhooks of a user.
+func (m *WebhookManager) GetWebhooks(ctx context.Context, userID int64) ([]*models.Webhook, error) {
+	return m.repo.GetWebhooks(ctx, userID)
+}
+
+// DeleteWebhook deletes a webhook of a user.
+// If the user has no such webhook, ErrWebhookNotFound is returned.
+func (m *WebhookManager) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	return m.repo.DeleteWebhook(ctx, userID, webhookID)
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook of a user, newest first.
+// If the user has no such webhook, ErrWebhookNotFound is returned.
+func (m *WebhookManager) GetWebhookDeliveries(ctx context.Context, userID, webhookID int64) ([]*models.WebhookDelivery, error) {
+	return m.repo.GetWebhookDeliveries(ctx, userID, webhookID, webhookDeliveriesLimit)
+}
+
+// checkHost resolves the host of a webhook url and checks all its addresses.
+// The addresses are checked again when a delivery connects,
+// since the host may resolve to other addresses by then.
+func (m *WebhookManager) checkHost(ctx context.Context, host string) error {
+	addrs := make([]netip.Addr, 0, 1)
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = append(addrs, addr)
+	} else {
+		addrs, err = m.resolver.LookupNetIP(ctx, "ip", host)
+		if err != nil || len(addrs) == 0 {
+			return ErrInvalidWebhookURL
+		}
+	}
+
+	for _, addr := range addrs {
+		if !webhookAddrAllowed(addr) {
+			return ErrWebhookAddrForbidden
+		}
+	}
+
+	return nil
+}
+
+// webhookAddrAllowed reports whether webhooks may be sent to an address.
+// Loopback, private, link-local, multicast and unspecified addresses
+// and webhookDeniedPrefixes are internal to the service
+// and must not be reachable through the webhooks of users.
+// IPv4-mapped IPv6 addresses are checked as IPv4 addresses.
+func webhookAddrAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range webhookDeniedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/leonf08/gophermart.git/internal/models"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookManager_CreateWebhook(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *models.WebhookRequest
+		wantEvents models.EventTypes
+		wantErr    error
+	}{
+		{
+			name: "create webhook",
+			req: &models.WebhookRequest{
+				URL:    "https://shop.example.com/hooks",
+				Events: []string{models.WebhookEventOrderProcessed, models.WebhookEventWithdrawal, models.WebhookEventOrderProcessed},
+			},
+			wantEvents: models.EventTypes{models.WebhookEventOrderProcessed, models.WebhookEventWithdrawal},
+		},
+		{
+			name:    "relative url",
+			req:     &models.WebhookRequest{URL: "/hooks", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrInvalidWebhookURL,
+		},
+		{
+			name:    "unsupported scheme",
+			req:     &models.WebhookRequest{URL: "ftp://shop.example.com", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrInvalidWebhookURL,
+		},
+		{
+			name:    "loopback address",
+			req:     &models.WebhookRequest{URL: "http://127.0.0.1:8080/hooks", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrWebhookAddrForbidden,
+		},
+		{
+			name:    "link-local metadata address",
+			req:     &models.WebhookRequest{URL: "http://169.254.169.254/latest/meta-data", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrWebhookAddrForbidden,
+		},
+		{
+			name:    "ipv4-mapped loopback address",
+			req:     &models.WebhookRequest{URL: "http://[::ffff:127.0.0.1]/hooks", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrWebhookAddrForbidden,
+		},
+		{
+			name:    "host resolving to a private address",
+			req:     &models.WebhookRequest{URL: "https://billing.internal/hooks", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrWebhookAddrForbidden,
+		},
+		{
+			name:    "unknown host",
+			req:     &models.WebhookRequest{URL: "https://unknown.example.com/hooks", Events: []string{models.WebhookEventWithdrawal}},
+			wantErr: ErrInvalidWebhookURL,
+		},
+		{
+			name:    "no events",
+			req:     &models.WebhookRequest{URL: "https://shop.example.com/hooks"},
+			wantErr: ErrInvalidWebhookEvents,
+		},
+		{
+			name:    "unknown event",
+			req:     &models.WebhookRequest{URL: "https://shop.example.com/hooks", Events: []string{"order.created"}},
+			wantErr: ErrInvalidWebhookEvents,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := mocks.NewWebhookRepo(t)
+			if tt.wantErr == nil {
+				repo.On("CreateWebhook", mock.Anything, mock.Anything).Return(nil).Once()
+			}
+
+			m := NewWebhookManager(repo)
+			m.resolver = testResolver{
+				"shop.example.com": {netip.MustParseAddr("93.184.216.34")},
+				"billing.internal": {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.5")},
+			}
+			w, err := m.CreateWebhook(context.Background(), 1, tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), w.UserID)
+			assert.Equal(t, tt.wantEvents, w.Events)
+			assert.NotEmpty(t, w.Secret)
+		})
+	}
+}
+
+// testResolver resolves the host names it holds.
+type testResolver map[string][]netip.Addr
+
+func (r testResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return addrs, nil
+}
+
+func TestWebhookAddrAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "100.63.255.255", want: true},
+		{addr: "100.128.0.0", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.0.0.5"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "fd00::1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "0.1.2.3"},
+		{addr: "100.64.0.1"},
+		{addr: "100.127.255.255"},
+		{addr: "224.0.0.1"},
+		{addr: "239.255.255.250"},
+		{addr: "ff02::1"},
+		{addr: "ff05::2"},
+		{addr: "::ffff:93.184.216.34", want: true},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:10.0.0.5"},
+		{addr: "::ffff:169.254.169.254"},
+		{addr: "::ffff:0.1.2.3"},
+		{addr: "::ffff:100.64.0.1"},
+		{addr: "::ffff:224.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, webhookAddrAllowed(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestNewWebhookClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newWebhookClient(time.Second)
+
+	// The test server listens on a loopback address.
+	_, err := client.Post(server.URL, "application/json", strings.NewReader("{}"))
+	assert.ErrorIs(t, err, ErrWebhookAddrForbidden)
+
+	assert.Equal(t, http.ErrUseLastResponse, client.CheckRedirect(nil, nil))
+}
+
+func newTestDispatcher(repo WebhookRepo) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		cfg: WebhookDispatcherConfig{
+			RetryInterval:    time.Minute,
+			MaxRetryInterval: time.Hour,
+			MaxAttempts:      3,
+			Timeout:          time.Second,
+			BatchSize:        10,
+		},
+		repo:   repo,
+		log:    &nopLogger{},
+		client: &http.Client{Timeout: time.Second},
+	}
+}
+
+func TestWebhookDispatcher_deliver(t *testing.T) {
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		// The receiver recomputes the signature with the shared secret.
+		signature := r.Header.Get(headerWebhookSignature)
+		ts, err := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, SignWebhook("secret", ts, body), signature)
+		assert.Equal(t, models.WebhookEventOrderProcessed, r.Header.Get(headerWebhookEvent))
+
+		envelope := &models.WebhookEnvelope{}
+		require.NoError(t, json.Unmarshal(body, envelope))
+		assert.Equal(t, int64(7), envelope.ID)
+		assert.JSONEq(t, `{"number":"12345678903","status":"PROCESSED"}`, string(envelope.Data))
+
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		status       int
+		attempts     int
+		wantStatus   string
+		wantAttempts int
+		wantRetry    bool
+	}{
+		{
+			name:         "delivered",
+			status:       http.StatusNoContent,
+			wantStatus:   models.WebhookDeliveryDelivered,
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried after an error response",
+			status:       http.StatusServiceUnavailable,
+			wantStatus:   models.WebhookDeliveryPending,
+			wantAttempts: 1,
+			wantRetry:    true,
+		},
+		{
+			name:         "failed after the last attempt",
+			status:       http.StatusInternalServerError,
+			attempts:     2,
+			wantStatus:   models.WebhookDeliveryFailed,
+			wantAttempts: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+
+			repo := mocks.NewWebhookRepo(t)
+			repo.On("UpdateWebhookDelivery", mock.Anything, mock.Anything).Return(nil).Once()
+
+			delivery := &models.WebhookDelivery{
+				DeliveryID: 1,
+				EventID:    7,
+				EventType:  models.WebhookEventOrderProcessed,
+				Status:     models.WebhookDeliveryPending,
+				Attempts:   tt.attempts,
+				URL:        server.URL,
+				Secret:     "secret",
+				Payload:    models.RawJSON(`{"number":"12345678903","status":"PROCESSED"}`),
+			}
+
+			newTestDispatcher(repo).deliver(context.Background(), delivery)
+
+			assert.Equal(t, tt.wantStatus, delivery.Status)
+			assert.Equal(t, tt.wantAttempts, delivery.Attempts)
+			assert.Equal(t, tt.status, delivery.ResponseStatus)
+			assert.Equal(t, tt.wantRetry, delivery.NextAttemptAt.After(time.Now()))
+			if tt.wantStatus == models.WebhookDeliveryDelivered {
+				assert.NotNil(t, delivery.DeliveredAt)
+				assert.Empty(t, delivery.LastError)
+			} else {
+				assert.NotEmpty(t, delivery.LastError)
+			}
+		})
+	}
+}
+
+func TestWebhookDispatcher_dispatch(t *testing.T) {
+	repo := mocks.NewWebhookRepo(t)
+	repo.On("DispatchWebhookOutbox", mock.Anything, mock.Anything, 10).Return(int64(10), nil).Once()
+	repo.On("DispatchWebhookOutbox", mock.Anything, mock.Anything, 10).Return(int64(3), nil).Once()
+
+	require.NoError(t, newTestDispatcher(repo).dispatch(context.Background()))
+}
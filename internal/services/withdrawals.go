@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithdrawalSettlerConfig holds settings of the withdrawal settlement.
+type WithdrawalSettlerConfig struct {
+	// ReversalWindow is how long a withdrawal stays pending and can be reversed.
+	ReversalWindow time.Duration
+	// PollInterval is how often the pending withdrawals are checked.
+	PollInterval time.Duration
+}
+
+// WithdrawalSettler completes the pending withdrawals once their reversal window is over.
+type WithdrawalSettler struct {
+	cfg  WithdrawalSettlerConfig
+	repo WithdrawalRepo
+	log  Logger
+	wg   sync.WaitGroup
+}
+
+// NewWithdrawalSettler creates a new withdrawal settler and starts its worker.
+// The worker is stopped when the given context is done.
+func NewWithdrawalSettler(ctx context.Context, cfg WithdrawalSettlerConfig, repo WithdrawalRepo, log Logger) *WithdrawalSettler {
+	s := &WithdrawalSettler{
+		cfg:  cfg,
+		repo: repo,
+		log:  log,
+	}
+
+	s.wg.Add(1)
+	go s.run(ctx)
+
+	return s
+}
+
+// Wait blocks until the worker is stopped.
+func (s *WithdrawalSettler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *WithdrawalSettler) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.settle(ctx); err != nil && ctx.Err() == nil {
+			s.log.Error("withdrawals - run - s.settle", "error", err)
+		}
+	}
+}
+
+// settle completes the pending withdrawals processed before the reversal window.
+func (s *WithdrawalSettler) settle(ctx context.Context) error {
+	n, err := s.repo.CompleteWithdrawals(ctx, time.Now().Add(-s.cfg.ReversalWindow))
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		s.log.Info("withdrawals - settle", "completed", n)
+	}
+
+	return nil
+}
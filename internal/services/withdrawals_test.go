@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"github.com/leonf08/gophermart.git/internal/services/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestWithdrawalSettler_settle(t *testing.T) {
+	repo := mocks.NewWithdrawalRepo(t)
+	repo.
+		On("CompleteWithdrawals", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			// Only the withdrawals older than the reversal window are completed.
+			return time.Since(before) >= time.Hour && time.Since(before) < time.Hour+time.Minute
+		})).
+		Return(int64(2), nil).
+		Once()
+
+	s := &WithdrawalSettler{
+		cfg:  WithdrawalSettlerConfig{ReversalWindow: time.Hour, PollInterval: time.Minute},
+		repo: repo,
+		log:  &nopLogger{},
+	}
+
+	assert.NoError(t, s.settle(context.Background()))
+}
+
+func TestNewWithdrawalSettler(t *testing.T) {
+	repo := mocks.NewWithdrawalRepo(t)
+	settled := make(chan struct{}, 1)
+	repo.
+		On("CompleteWithdrawals", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			select {
+			case settled <- struct{}{}:
+			default:
+			}
+		}).
+		Return(int64(0), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewWithdrawalSettler(ctx, WithdrawalSettlerConfig{
+		ReversalWindow: time.Hour,
+		PollInterval:   10 * time.Millisecond,
+	}, repo, &nopLogger{})
+
+	select {
+	case <-settled:
+	case <-time.After(time.Second):
+		t.Fatal("pending withdrawals are not settled")
+	}
+
+	cancel()
+	s.Wait()
+}
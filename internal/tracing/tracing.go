@@ -0,0 +1,114 @@
+// Package tracing sets up OpenTelemetry tracing and starts the spans of the application.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+)
+
+// Span exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// instrumentationName names the tracer of the application.
+const instrumentationName = "github.com/leonf08/gophermart.git"
+
+var ErrUnknownExporter = errors.New("unknown span exporter")
+
+// Config holds settings of the tracing.
+type Config struct {
+	// ServiceName is the service.name of the spans.
+	ServiceName string
+	// Exporter is one of ExporterNone, ExporterStdout and ExporterOTLP.
+	// The OTLP exporter sends spans over HTTP and is set up by the standard
+	// OTEL_EXPORTER_OTLP_* environment variables, e.g. OTEL_EXPORTER_OTLP_ENDPOINT.
+	Exporter string
+	// SampleRatio is the share of traces recorded, parent-based.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// With ExporterNone no spans are recorded, but trace context is still propagated.
+// The returned function flushes the spans and stops the provider.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, ErrUnknownExporter
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span of the application tracer.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End records the error, if any, on the span and ends it.
+// Expected errors, e.g. rejected input, are business outcomes and are not recorded.
+func End(span trace.Span, err error, expected ...error) {
+	if err != nil && !isExpected(err, expected) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// isExpected reports whether the error matches any of the expected errors.
+func isExpected(err error, expected []error) bool {
+	for _, target := range expected {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OrderNumber is the span attribute of an order number.
+func OrderNumber(orderNum string) attribute.KeyValue {
+	return attribute.String("order.number", orderNum)
+}
@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestEnd(t *testing.T) {
+	errExpected := errors.New("expected")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus codes.Code
+		wantEvents int
+	}{
+		{
+			name:       "no error",
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "unexpected error",
+			err:        errors.New("connection refused"),
+			wantStatus: codes.Error,
+			wantEvents: 1,
+		},
+		{
+			name:       "expected error",
+			err:        fmt.Errorf("create order: %w", errExpected),
+			wantStatus: codes.Unset,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			_, span := provider.Tracer("test").Start(context.Background(), "span")
+			End(span, tt.err, errExpected)
+
+			if assert.Len(t, recorder.Ended(), 1) {
+				ended := recorder.Ended()[0]
+				assert.Equal(t, tt.wantStatus, ended.Status().Code)
+				assert.Len(t, ended.Events(), tt.wantEvents)
+			}
+		})
+	}
+}